@@ -1,7 +1,10 @@
 module github.com/jwoos/rainbowgif
 
-go 1.13
+go 1.22.2
 
 require (
+	github.com/HugoSmits86/nativewebp v1.3.0
 	github.com/lucasb-eyer/go-colorful v1.0.3
 )
+
+require golang.org/x/image v0.24.0 // indirect
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,226 +12,1589 @@ import (
 	"image/gif"
 	"image/jpeg"
 	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/jwoos/rainbowgif/rainbow"
 	"github.com/lucasb-eyer/go-colorful"
 )
 
-func prepareFrame(src *image.Paletted, dst *image.Paletted, overlayColor colorful.Color) {
-	dst.Pix = src.Pix
-	dst.Stride = src.Stride
+// set when building a release with -ldflags "-X main.version=v1.2.3"
+var version = "dev"
 
-	for pixelIndex, pixel := range src.Palette {
-		_, _, _, alpha := pixel.RGBA()
-		convertedPixel, ok := colorful.MakeColor(pixel)
+// how tall the strip written by -dump_gradient is
+const gradientStripHeight = 32
 
-		if alpha == 0 || !ok {
-			dst.Palette[pixelIndex] = pixel
-			continue
+/* maps the loop flags onto gif.GIF.LoopCount
+ * 0 loops forever, -1 plays once, and n repeats n times
+ */
+func outputLoopCount(infinite bool, gifLoops int) int {
+	if gifLoops >= 0 {
+		return gifLoops
+	}
+
+	if infinite {
+		return 0
+	}
+
+	return -1
+}
+
+/* converts frames per second into the typical GIF delay in 100ths of a second, the library spreads the rounding over the frames
+ * anything faster than 100 fps still gets the smallest possible delay of 1
+ */
+func fpsDelay(fps float64) int {
+	delay := int(math.Round(100 / fps))
+	if delay < 1 {
+		return 1
+	}
+
+	return delay
+}
+
+/* parses start:end into the frames to keep, start is inclusive and end exclusive
+ * either side can be left out to run from the first or to the last frame
+ */
+func frameRange(s string, frameCount int) (int, int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid frame range %q, expected start:end", s)
+	}
+
+	start := 0
+	end := frameCount
+	var err error
+
+	if len(parts[0]) != 0 {
+		start, err = strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid frame range %q, expected start:end", s)
+		}
+	}
+
+	if len(parts[1]) != 0 {
+		end, err = strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid frame range %q, expected start:end", s)
+		}
+	}
+
+	if start < 0 || end > frameCount || start >= end {
+		return 0, 0, fmt.Errorf("Frame range %q doesn't fit the %d frames of the input", s, frameCount)
+	}
+
+	return start, end, nil
+}
+
+// parses x,y,width,height into the rectangle to crop to
+func cropRect(s string) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("Invalid crop %q, expected x,y,width,height", s)
+	}
+
+	values := make([]int, len(parts))
+	for i, part := range parts {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("Invalid crop %q, expected x,y,width,height", s)
+		}
+
+		values[i] = value
+	}
+
+	if values[2] <= 0 || values[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("Crop %q needs a width and height of at least 1", s)
+	}
+
+	return image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3]), nil
+}
+
+/* roughly how many bytes the GIF takes before LZW compression
+ * the header, every frame's color table, and one byte per pixel
+ */
+func estimateSize(img *gif.GIF) int {
+	size := 13
+
+	global, _ := img.Config.ColorModel.(color.Palette)
+	size += 3 * len(global)
+
+	for _, frame := range img.Image {
+		// graphic control extension and image descriptor
+		size += 8 + 10
+
+		// frames using the global palette don't get a table of their own
+		shared := len(global) > 0 && len(frame.Palette) > 0 && &global[0] == &frame.Palette[0]
+		if !shared {
+			size += 3 * len(frame.Palette)
+		}
+
+		size += frame.Bounds().Dx() * frame.Bounds().Dy()
+	}
+
+	return size
+}
+
+// the settings tried in order to shrink the output, each more lossy than the one before
+var targetSteps = []struct {
+	maxColors int
+	every     int
+}{
+	{maxColors: 0, every: 1},
+	{maxColors: 128, every: 1},
+	{maxColors: 64, every: 1},
+	{maxColors: 32, every: 1},
+	{maxColors: 16, every: 1},
+	{maxColors: 16, every: 2},
+	{maxColors: 16, every: 3},
+	{maxColors: 16, every: 4},
+}
+
+/* processes src with fewer and fewer colors and then frames until the encoded GIF fits in targetBytes
+ * max_colors and every given in opts are never loosened, finish is run on every attempt before encoding
+ * returns the output that fit along with the options and the size it took
+ */
+func fitToSize(src *gif.GIF, opts rainbow.Options, targetBytes int, finish func(*gif.GIF)) (*gif.GIF, rainbow.Options, int, error) {
+	smallest := -1
+
+	for _, step := range targetSteps {
+		stepOpts := opts
+		if step.maxColors != 0 && (stepOpts.MaxColors == 0 || step.maxColors < stepOpts.MaxColors) {
+			stepOpts.MaxColors = step.maxColors
+		}
+		if step.every > stepOpts.Every {
+			stepOpts.Every = step.every
+		}
+
+		img, err := rainbow.Rainbowify(src, stepOpts)
+		if err != nil {
+			return nil, opts, 0, err
+		}
+		finish(img)
+
+		var buf bytes.Buffer
+		if err := rainbow.EncodeTo(&buf, img); err != nil {
+			return nil, opts, 0, err
+		}
+
+		if buf.Len() <= targetBytes {
+			return img, stepOpts, buf.Len(), nil
+		}
+
+		if smallest == -1 || buf.Len() < smallest {
+			smallest = buf.Len()
+		}
+	}
+
+	return nil, opts, 0, fmt.Errorf("Cannot reach the target of %d bytes, the smallest output was %d bytes", targetBytes, smallest)
+}
+
+// the frame at a fraction of the way through the animation, rounded to the nearest one
+func previewIndex(at float64, frameCount int) int {
+	return int(math.Round(at * float64(frameCount-1)))
+}
+
+/* decodes every input and plays them one after the other as a single GIF, see rainbow.Concat
+ * stills are a single frame of the animation
+ */
+func decodeConcat(inputs []string, strict bool, quantizer string) (*gif.GIF, error) {
+	clips := make([]*gif.GIF, len(inputs))
+	for i, input := range inputs {
+		file, err := os.Open(input)
+		if err != nil {
+			return nil, fmt.Errorf("opening %q: %w", input, err)
+		}
+
+		clips[i], _, err = rainbow.DecodeImage(file, quantizer)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding %q: %w", input, err)
+		}
+	}
+
+	img, err := rainbow.Concat(clips, strict, quantizer)
+	if err != nil {
+		return nil, fmt.Errorf("concatenating: %w", err)
+	}
+
+	return img, nil
+}
+
+/* writes the palette of img that matters most, the global one when there is one and otherwise the first frame's
+ * as a GIMP palette for a .gpl path or an Adobe Color Table for a .act path
+ */
+func writePalette(path string, img *gif.GIF) error {
+	palette := img.Image[0].Palette
+	if global, ok := img.Config.ColorModel.(color.Palette); ok && len(global) > 0 {
+		palette = global
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gpl":
+		err = rainbow.EncodeGPL(&buf, palette, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	case ".act":
+		err = rainbow.EncodeACT(&buf, palette)
+	default:
+		err = fmt.Errorf("Unsupported palette file %q, must be .gpl or .act", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writes a single still PNG
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// whether the input names several files with *, ?, or [...]
+func isGlob(input string) bool {
+	return strings.ContainsAny(input, "*?[")
+}
+
+/* where a file matched by a glob is written
+ * {name} in output is replaced with the file's name without its extension, otherwise output is a directory
+ */
+func batchOutput(output string, input string) string {
+	name := filepath.Base(input)
+
+	if strings.Contains(output, "{name}") {
+		return strings.Replace(output, "{name}", strings.TrimSuffix(name, filepath.Ext(name)), -1)
+	}
+
+	return filepath.Join(output, name)
+}
+
+/* turns the threads flag into a thread count, explicit values are used as is
+ * 0 uses GOMAXPROCS, which can be lowered below the CPU count by the environment, but never more than the CPUs
+ */
+func resolveThreads(threads int) int {
+	if threads != 0 {
+		return threads
+	}
+
+	threads = runtime.GOMAXPROCS(0)
+	if cpus := runtime.NumCPU(); threads > cpus {
+		threads = cpus
+	}
+
+	if threads < 1 {
+		return 1
+	}
+
+	return threads
+}
+
+// picks the output format from the file extension
+func outputFormat(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	switch ext {
+	case ".gif":
+		return "gif", nil
+	case ".png":
+		return "png", nil
+	case ".apng":
+		return "apng", nil
+	case ".jpg", ".jpeg":
+		return "jpeg", nil
+	case ".webp":
+		// turned down here rather than by the encoder, so nothing is processed or created for it
+		if !rainbow.WebPSupported {
+			return "", fmt.Errorf("%w: WebP output needs a build with -tags webp", rainbow.ErrUnsupportedFormat)
+		}
+		return "webp", nil
+	default:
+		return "", fmt.Errorf("%w for output: %q", rainbow.ErrUnsupportedFormat, ext)
+	}
+}
+
+// the user's comment followed by the version and gradient that produced the file
+func provenanceComment(text string, colors []colorful.Color) string {
+	hexes := make([]string, len(colors))
+	for i, c := range colors {
+		hexes[i] = c.Hex()
+	}
+
+	return fmt.Sprintf("%s\nrainbowgif %s, gradient %s", text, version, strings.Join(hexes, ","))
+}
+
+/* writes the image to path using the encoder matching its extension
+ * GIFs, WebPs, and .apng files keep the whole animation, an .apng is animated even with a single frame
+ * a .png is a still unless there's more than one frame, which run only lets through with -animate,
+ * so nobody asking for a PNG gets an animation by surprise
+ * JPEGs are stills of the first frame
+ * comment is only written into GIFs and can be empty
+ */
+func encodeOutput(path string, img *gif.GIF, comment string) error {
+	format, err := outputFormat(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "gif":
+		err = rainbow.EncodeWithComment(file, img, comment)
+	case "png":
+		if len(img.Image) > 1 {
+			err = rainbow.EncodeAPNG(file, img)
+		} else {
+			err = png.Encode(file, img.Image[0])
+		}
+	case "apng":
+		err = rainbow.EncodeAPNG(file, img)
+	case "jpeg":
+		err = jpeg.Encode(file, img.Image[0], nil)
+	case "webp":
+		err = rainbow.EncodeWebP(file, img)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	// a half written file is no use to anyone
+	if err != nil {
+		os.Remove(path)
+	}
+
+	return err
+}
+
+/* writes every frame of img as a numbered RGBA PNG into dir, creating it when needed
+ * the numbers are padded to at least 3 digits, more when there are more frames
+ */
+func writeSequence(dir string, img *gif.GIF) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	frames := rainbow.Frames(img)
+	width := len(strconv.Itoa(len(frames)))
+	if width < 3 {
+		width = 3
+	}
+
+	for i, frame := range frames {
+		name := fmt.Sprintf("frame_%0*d.png", width, i+1)
+		if err := writePNG(filepath.Join(dir, name), frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// describes the grid written next to a montage so the frames can be found again
+type montageMetadata struct {
+	Frames     int   `json:"frames"`
+	Columns    int   `json:"columns"`
+	Rows       int   `json:"rows"`
+	CellWidth  int   `json:"cell_width"`
+	CellHeight int   `json:"cell_height"`
+	Delay      []int `json:"delay"`
+}
+
+/* writes every frame of img into a single PNG grid at path
+ * along with a JSON file describing the grid, named after path with a .json extension
+ */
+func writeMontage(path string, img *gif.GIF, columns int) error {
+	montage, err := rainbow.Montage(img, columns)
+	if err != nil {
+		return err
+	}
+
+	if err := writePNG(path, montage); err != nil {
+		return err
+	}
+
+	bounds := montage.Bounds()
+	rows := (len(img.Image) + columns - 1) / columns
+	metadata, err := json.MarshalIndent(
+		montageMetadata{
+			Frames:     len(img.Image),
+			Columns:    columns,
+			Rows:       rows,
+			CellWidth:  bounds.Dx() / columns,
+			CellHeight: bounds.Dy() / rows,
+			Delay:      img.Delay,
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(strings.TrimSuffix(path, filepath.Ext(path))+".json", metadata, 0644)
+}
+
+// what processing a file did, written as JSON with -stats
+type Stats struct {
+	Input        string   `json:"input"`
+	Inputs       []string `json:"inputs,omitempty"`
+	Output       string   `json:"output"`
+	Width        int      `json:"width"`
+	Height       int      `json:"height"`
+	InputFrames  int      `json:"input_frames"`
+	Frames       int      `json:"frames"`
+	LoopCount    int      `json:"loop_count"`
+	Colors       []string `json:"colors"`
+	Blend        string   `json:"blend"`
+	Threads      int      `json:"threads"`
+	ProcessingMS int64    `json:"processing_ms"`
+	OutputBytes  int64    `json:"output_bytes"`
+}
+
+// counts the bytes written through it
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.count += int64(n)
+	return n, err
+}
+
+// a GIFWriter for -low_memory that raises short delays like finish does for a whole GIF, counting the frames that go through
+type lowMemoryWriter struct {
+	*rainbow.GIFWriter
+	minDelay int
+	frames   int
+	clamped  int
+}
+
+func (w *lowMemoryWriter) WriteFrame(frame *image.Paletted, delay int, disposal byte) error {
+	if delay < w.minDelay {
+		delay = w.minDelay
+		w.clamped++
+	}
+
+	w.frames++
+	return w.GIFWriter.WriteFrame(frame, delay, disposal)
+}
+
+// the size of the file at path, or of all the frames for a sequence directory
+func outputBytes(path string, sequence bool) (int64, error) {
+	paths := []string{path}
+	if sequence {
+		var err error
+		paths, err = filepath.Glob(filepath.Join(path, "frame_*.png"))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var total int64
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size()
+	}
+
+	return total, nil
+}
+
+// writes v as indented JSON to path, - writes it to stdout
+func writeStats(path string, stdout io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if path == "-" {
+		_, err = stdout.Write(b)
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("rainbowgif", flag.ContinueOnError)
+
+	var threads int
+	flags.IntVar(&threads, "threads", 0, "The number of go threads to use, 0 picks one per CPU available to the process")
+
+	var gradientColors string
+	flags.StringVar(&gradientColors, "gradient", "", "A list of colors in hex separated by comma to use as the gradient, - reads the list from stdin")
+
+	var gradientFile string
+	flags.StringVar(&gradientFile, "gradient_file", "", "A file with the list of colors in hex to use as the gradient, separated by commas or newlines")
+
+	var loopCount int
+	flags.IntVar(&loopCount, "loop_count", 1, "The number of times to loop through the GIF or the number of frames to show - this duplicates frames in the output, see gif_loops for playback looping")
+	var maxFrames int
+	flags.IntVar(&maxFrames, "max_frames", 10000, "Refuse to make more output frames than this, so a huge loop_count errors instead of running out of memory, 0 for no limit")
+
+	var loopMode string
+	flags.StringVar(&loopMode, "loop_mode", "continuous", "How the gradient spreads over the loops from loop_count: continuous sweeps once across all of them, per-loop sweeps through it in every loop")
+
+	var infinite bool
+	flags.BoolVar(&infinite, "infinite", true, "Whether viewers should loop the output GIF forever, without this or gif_loops an animated input keeps its own looping")
+
+	var gifLoops int
+	flags.IntVar(&gifLoops, "gif_loops", -1, "The number of times viewers should repeat the output GIF, 0 meaning forever - overrides infinite and does not add frames")
+
+	var static bool
+	flags.BoolVar(&static, "static", false, "Deprecated: still images (JPG/PNG) are now detected automatically")
+
+	var delay int
+	flags.IntVar(&delay, "delay", 0, "The delay between frames")
+
+	var quantizer string
+	flags.StringVar(&quantizer, "quantizer", "populosity", "quantizer algorithm to use")
+
+	var opacity float64
+	flags.Float64Var(&opacity, "opacity", 1, "How strongly the gradient is blended in, from 0 (untouched) to 1 (fully blended)")
+	var blendFactor float64
+	flags.Float64Var(&blendFactor, "blend_factor", 0, "How much of the gradient's own lightness the color blend takes on, from 0 (keeps the source's) to 1 (the gradient color as is), opacity then mixes the result with the source")
+
+	var blendMode string
+	flags.StringVar(&blendMode, "blend", "color", "blend mode to use: color, normal, multiply, screen, overlay, softlight, or hue")
+
+	var cycles int
+	flags.IntVar(&cycles, "cycles", 1, "The number of full sweeps through the gradient across the whole animation")
+
+	var sweepSeconds float64
+	flags.Float64Var(&sweepSeconds, "sweep_seconds", 0, "Make a full sweep through the gradient take this many seconds of playback instead of spreading cycles over the frames, 0 turns it off")
+
+	var reverse bool
+	flags.BoolVar(&reverse, "reverse", false, "Run the gradient backwards")
+	var reversePlayback bool
+	flags.BoolVar(&reversePlayback, "reverse_playback", false, "Play the source frames backwards, unlike reverse which runs the gradient backwards")
+
+	var phase float64
+	flags.Float64Var(&phase, "phase", 0, "Where in the gradient to start, from 0 up to but not including 1")
+
+	var interpolation string
+	flags.StringVar(&interpolation, "interp", "hcl", "color space to interpolate the gradient in: rgb, hsv, hcl, or lab")
+
+	var compare string
+	flags.StringVar(&compare, "compare", "", "A second gradient, written like gradient, to process the input with as well and show next to the first one in the same output for an A/B of the two")
+	var compareLayout string
+	flags.StringVar(&compareLayout, "compare_layout", "vertical", "How compare lays out the two: vertical puts the first gradient on top, horizontal puts it on the left")
+
+	var concat bool
+	flags.BoolVar(&concat, "concat", false, "Take several inputs before the output and play them one after the other with a single sweep of the gradient across all of them")
+
+	var concatStrict bool
+	flags.BoolVar(&concatStrict, "concat_strict", false, "Fail when the inputs to concat aren't all the same size instead of centering the smaller ones")
+
+	var hueStart float64
+	flags.Float64Var(&hueStart, "hue_start", 0, "Without a gradient, build one from this hue in degrees (0 to 360) to hue_end instead of the full rainbow")
+	var hueEnd float64
+	flags.Float64Var(&hueEnd, "hue_end", 360, "Where the hue_start gradient ends in degrees, a start past the end wraps around through red")
+
+	var preset string
+	flags.StringVar(&preset, "preset", "", "A named gradient to use instead of gradient: rainbow, pride, trans, bi, lesbian, or ace")
+
+	var randomGradient int
+	flags.IntVar(&randomGradient, "random_gradient", 0, "Generate a gradient of this many random colors with evenly spaced hues instead of gradient")
+
+	var seed int64
+	flags.Int64Var(&seed, "seed", 0, "The seed for random_gradient so a gradient can be generated again, 0 picks a new one every run")
+
+	var coalesce bool
+	flags.BoolVar(&coalesce, "coalesce", false, "Composite partial frames onto the full canvas before blending - fixes flickering colors but increases file size")
+	var orient string
+	flags.StringVar(&orient, "orient", "none", "Turn or mirror every frame before blending: none, 90, 180, 270 (clockwise), flip-h, or flip-v")
+	var crop string
+	flags.StringVar(&crop, "crop", "", "Only keep this part of every frame, given as x,y,width,height")
+	var width int
+	flags.IntVar(&width, "width", 0, "Resize the output to this many pixels wide, 0 follows the height to keep the aspect ratio")
+	var height int
+	flags.IntVar(&height, "height", 0, "Resize the output to this many pixels tall, 0 follows the width to keep the aspect ratio")
+
+	var spatial string
+	flags.StringVar(&spatial, "spatial", "none", "vary the gradient across each frame as well as over time: none, horizontal, vertical, diagonal, or radial")
+
+	var centerX float64
+	flags.Float64Var(&centerX, "center_x", 0.5, "Where the radial spatial gradient is centered horizontally, from 0 (left) to 1 (right)")
+
+	var centerY float64
+	flags.Float64Var(&centerY, "center_y", 0.5, "Where the radial spatial gradient is centered vertically, from 0 (top) to 1 (bottom)")
+
+	var animate bool
+	flags.BoolVar(&animate, "animate", false, "Write an animated PNG for .png outputs of animated inputs instead of a still, .apng outputs are always animated")
+	var statsPath string
+	flags.StringVar(&statsPath, "stats", "", "Write a JSON summary of the processing to this file, - for stdout, an array of them for several inputs")
+	var sequence bool
+	flags.BoolVar(&sequence, "sequence", false, "Write every frame as a numbered RGBA PNG (frame_001.png, ...) into the output directory instead of an animation")
+
+	var montage int
+	flags.IntVar(&montage, "montage", 0, "Lay every frame out in a PNG grid with this many columns instead of animating, along with a JSON file describing the grid")
+
+	var fps float64
+	flags.Float64Var(&fps, "fps", 0, "Overrides every frame's delay to play at this many frames per second")
+
+	var delayScale float64
+	flags.Float64Var(&delayScale, "delay_scale", 1, "Multiplies every frame's delay, 0.5 plays twice as fast and 2 half as fast")
+
+	var minDelay int
+	flags.IntVar(&minDelay, "min_delay", 0, "Raises every frame's delay to at least this many 100ths of a second, 2 plays consistently in browsers - 0 leaves delays alone")
+
+	var gradientImage string
+	flags.StringVar(&gradientImage, "gradient_image", "", "An image to pick the gradient's colors from instead of gradient")
+
+	var gradientImageStops int
+	flags.IntVar(&gradientImageStops, "gradient_image_stops", 5, "The most colors to pick from gradient_image")
+
+	var easing string
+	flags.StringVar(&easing, "easing", "linear", "how each sweep through the gradient speeds up and slows down, applied to every cycle by itself: linear, ease-in, ease-out, ease-in-out, or sine")
+
+	var intensityRamp string
+	flags.StringVar(&intensityRamp, "intensity_ramp", "none", "How the opacity changes over the animation: none, fade-in, fade-out, or pulse")
+
+	var bounce bool
+	flags.BoolVar(&bounce, "bounce", false, "Sweep through the gradient and back again in every cycle so the animation ends on the color it started with")
+
+	var seamless bool
+	flags.BoolVar(&seamless, "seamless", true, "Stop the last frame one step short of the first color so the loop doesn't show it twice in a row")
+
+	var mode string
+	flags.StringVar(&mode, "mode", "blend", "blend mixes the gradient into every frame, huerotate instead turns every color's hue a full circle over the animation")
+
+	var gamma float64
+	flags.Float64Var(&gamma, "gamma", 1, "Raises every channel of the gradient's colors to this power before blending, above 1 darkens and below 1 brightens")
+
+	var saturation float64
+	flags.Float64Var(&saturation, "saturation", 1, "Multiplies the saturation of every blended color, 0 gives grays")
+
+	var brightness float64
+	flags.Float64Var(&brightness, "brightness", 1, "Multiplies the lightness of every blended color, 0 gives black")
+
+	var channels string
+	flags.StringVar(&channels, "channels", "rgb", "The channels the gradient may change, any combination of r, g, and b, e.g. r to only tint the red channel")
+
+	var preserve bool
+	flags.BoolVar(&preserve, "preserve", false, "Snap colors that are already within a couple of steps of the gradient's color to it instead of blending them again, so running over its own output doesn't drift")
+
+	var bwPreserve bool
+	flags.BoolVar(&bwPreserve, "bw_preserve", false, "Leave colors close to pure black or white unblended, so logos keep clean outlines and backgrounds")
+
+	var bwTolerance int
+	flags.IntVar(&bwTolerance, "bw_tolerance", 8, "How many steps (0 to 127) every channel may be from black or white for bw_preserve to leave a color alone")
+
+	var keepDominant bool
+	flags.BoolVar(&keepDominant, "keep_dominant", false, "Leave the color covering the most pixels across all frames unblended, usually a solid background")
+
+	var invert bool
+	flags.BoolVar(&invert, "invert", false, "Invert the source colors before blending for a negative with the gradient over it")
+
+	var desaturateFirst bool
+	flags.BoolVar(&desaturateFirst, "desaturate_first", false, "Turn the source colors into grays before blending for a clean rainbow wash")
+
+	var linear bool
+	flags.BoolVar(&linear, "linear", false, "Blend in linear light instead of sRGB for brighter mixes, only for the normal, multiply, screen, overlay, and softlight blends")
+
+	var mask string
+	flags.StringVar(&mask, "mask", "", "A grayscale PNG the size of the frames, white gets the full effect, black none, and grays scale the opacity")
+
+	var respectAlpha bool
+	flags.BoolVar(&respectAlpha, "respect_alpha", false, "Scale the effect by every color's alpha so semi transparent areas are only partially recolored")
+
+	var every int
+	flags.IntVar(&every, "every", 1, "Only keep every nth source frame to shrink the output, the dropped frames' delays are added to the kept ones")
+	var dedupe bool
+	flags.BoolVar(&dedupe, "dedupe", false, "Merge consecutive output frames that look exactly the same into one, adding up their delays")
+
+	var interpolateFrames int
+	flags.IntVar(&interpolateFrames, "interpolate_frames", 0, "The number of frames to insert after every source frame for a smoother sweep, the total duration stays the same")
+
+	var singleFrame string
+	flags.StringVar(&singleFrame, "single_frame", "sweep", "What to do with GIFs that have a single frame: sweep through the gradient over frames copies of it, or still to recolor it once with the gradient's midpoint")
+
+	var singleFrameCount int
+	flags.IntVar(&singleFrameCount, "frames", 0, "The number of frames to sweep a still image or single frame GIF over, set their delay with fps, 0 uses loop_count")
+
+	var frameRangeFlag string
+	flags.StringVar(&frameRangeFlag, "frame_range", "", "Only process the frames from start up to but not including end, given as start:end, either side can be left out")
+
+	var comment string
+	flags.StringVar(&comment, "comment", "", "Text to write into a comment in the output GIF, followed by the version and gradient used")
+
+	var targetKB int
+	flags.IntVar(&targetKB, "target_kb", 0, "Reduce the colors and then the frames until the GIF fits in this many kilobytes, 0 doesn't limit the size")
+
+	var preview bool
+	flags.BoolVar(&preview, "preview", false, "Only write the frame at preview_at as a PNG for a quick look at the settings")
+
+	var previewAt float64
+	flags.Float64Var(&previewAt, "preview_at", 0.5, "Where in the animation the preview frame is, from 0 for the first frame to 1 for the last")
+
+	var dumpGradient string
+	flags.StringVar(&dumpGradient, "dump_gradient", "", "Also write the overlay color of every output frame as a PNG strip, one column per frame, to check the gradient")
+
+	var exportPalette string
+	flags.StringVar(&exportPalette, "export_palette", "", "Also write the output's palette to a .gpl (GIMP) or .act (Adobe) file, the global palette when there is one like with global_palette and the first frame's otherwise")
+
+	var lowMemory bool
+	flags.BoolVar(&lowMemory, "low_memory", false, "Encode GIF frames as soon as they're done instead of holding all of them, for long outputs - can't be combined with options that need every frame at once like max_colors or dedupe")
+
+	var dryRun bool
+	flags.BoolVar(&dryRun, "dry_run", false, "Decode and process the input but only print a summary instead of writing the output")
+
+	var verbose bool
+	flags.BoolVar(&verbose, "verbose", false, "Log timings and progress to stderr")
+
+	var maxColors int
+	flags.IntVar(&maxColors, "max_colors", 0, "Reduce the output to one palette of at most this many colors, from 2 to 256, 0 keeps every frame's own palette")
+
+	var globalPalette bool
+	flags.BoolVar(&globalPalette, "global_palette", false, "Share a single palette of at most 256 colors, or max_colors, between all frames for a smaller file")
+
+	var paletteFromFirst bool
+	flags.BoolVar(&paletteFromFirst, "palette_from_first", false, "Share the first frame's blended palette between all frames, mapping the others onto its closest colors, for a smaller file with less flicker")
+
+	var text string
+	flags.StringVar(&text, "text", "", "A caption to draw over every frame after blending")
+	var textPos string
+	flags.StringVar(&textPos, "text_pos", "bottom", "Where to put the caption: top, bottom, or center")
+	var textColor string
+	flags.StringVar(&textColor, "text_color", "ffffff", "The hex color of the caption")
+	var background string
+	flags.StringVar(&background, "background", "", "The hex color viewers should show behind the frames, mapped to the closest palette color")
+
+	var matte string
+	flags.StringVar(&matte, "matte", "", "The hex color to composite partly transparent pixels over after blending, so antialiased edges don't fringe")
+
+	var transparent bool
+	flags.BoolVar(&transparent, "transparent", false, "Point the background at the transparent color instead of background")
+
+	var quality int
+	flags.IntVar(&quality, "quality", 5, "How much effort goes into picking the colors for max_colors and global_palette, from 1 (fastest) to 10 (most accurate)")
+
+	var dither string
+	flags.StringVar(&dither, "dither", "none", "How to hide banding when reducing the colors or with spatial gradients: none, floyd-steinberg, or ordered")
+	var paletteOrder string
+	flags.StringVar(&paletteOrder, "palette_order", "none", "Sort every palette so similar colors sit together and the GIF compresses better: none, luminance, or hue")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	// the flags given on the command line, for options that only apply when something was asked for
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	hueRange := given["hue_start"] || given["hue_end"]
+
+	if len(gradientFile) != 0 && len(gradientColors) != 0 {
+		return errors.New("gradient_file and gradient are mutually exclusive, only one can be given")
+	}
+
+	if len(preset) != 0 && (len(gradientColors) != 0 || len(gradientFile) != 0) {
+		return errors.New("preset and gradient are mutually exclusive, only one can be given")
+	}
+
+	if len(gradientImage) != 0 && (len(gradientColors) != 0 || len(gradientFile) != 0 || len(preset) != 0) {
+		return errors.New("gradient_image and gradient are mutually exclusive, only one can be given")
+	}
+
+	if randomGradient != 0 && (len(gradientColors) != 0 || len(gradientFile) != 0 || len(preset) != 0 || len(gradientImage) != 0) {
+		return errors.New("random_gradient and gradient are mutually exclusive, only one can be given")
+	}
+
+	if hueRange && (len(gradientColors) != 0 || len(gradientFile) != 0 || len(preset) != 0 || len(gradientImage) != 0 || randomGradient != 0) {
+		return errors.New("hue_start and hue_end only build a gradient when none is given")
+	}
+
+	if gradientColors == "-" {
+		colorList, err := rainbow.ReadGradientColors(stdin)
+		if err != nil {
+			return fmt.Errorf("reading gradient from stdin: %w", err)
 		}
+		gradientColors = colorList
+	} else if len(gradientFile) != 0 {
+		file, err := os.Open(gradientFile)
+		if err != nil {
+			return fmt.Errorf("opening gradient file %q: %w", gradientFile, err)
+		}
+		colorList, err := rainbow.ReadGradientColors(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("reading gradient file %q: %w", gradientFile, err)
+		}
+		gradientColors = colorList
+	}
+
+	if minDelay < 0 {
+		return errors.New("Minimum delay must be at least 0")
+	}
 
-		convertedPixel = convertedPixel.Clamped()
+	if fps < 0 {
+		return errors.New("FPS must be greater than 0")
+	}
 
-		blendedPixel := blendColor(overlayColor, convertedPixel)
+	if fps > 0 {
+		if delay != 0 {
+			return errors.New("fps and delay are mutually exclusive, only one can be given")
+		}
 
-		blendedR, blendedG, blendedB := blendedPixel.RGB255()
-		dst.Palette[pixelIndex] = color.NRGBA{
-			blendedR,
-			blendedG,
-			blendedB,
-			255,
+		// browsers bump anything below 2 up to 10, which is much slower than asked for
+		if fpsDelay(fps) < 2 {
+			fmt.Fprintf(flags.Output(), "Warning: %v fps is a delay of %d, most browsers play delays below 2 much slower\n", fps, fpsDelay(fps))
 		}
 	}
-}
 
-func parseGradientColors(gradientColors string) ([]colorful.Color, error) {
-	var colors []colorful.Color
+	opts := rainbow.DefaultOptions()
+	opts.Threads = resolveThreads(threads)
+	opts.LoopCount = loopCount
+	opts.MaxFrames = maxFrames
+	opts.LoopMode = loopMode
+	opts.Opacity = opacity
+	opts.BlendFactor = blendFactor
+	opts.Blend = blendMode
+	opts.Mode = mode
+	opts.Saturation = saturation
+	opts.Brightness = brightness
+	opts.DesaturateFirst = desaturateFirst
+	opts.Invert = invert
+	opts.Preserve = preserve
+	opts.KeepDominant = keepDominant
+	opts.PreserveBlackWhite = bwPreserve
+	opts.BlackWhiteTolerance = bwTolerance
+	opts.Channels = channels
+	opts.Linear = linear
+	opts.IntensityRamp = intensityRamp
+	opts.Gamma = gamma
+	opts.RespectAlpha = respectAlpha
+	opts.Every = every
+	opts.Dedupe = dedupe
+	opts.MaxColors = maxColors
+	opts.GlobalPalette = globalPalette
+	opts.PaletteFromFirst = paletteFromFirst
+	opts.Dither = dither
+	opts.PaletteOrder = paletteOrder
+	opts.Quality = quality
+	opts.InterpolateFrames = interpolateFrames
+	opts.Interpolation = interpolation
+	opts.Easing = easing
+	opts.Cycles = cycles
+	opts.SweepSeconds = sweepSeconds
+	opts.Reverse = reverse
+	opts.ReversePlayback = reversePlayback
+	opts.Bounce = bounce
+	opts.Seamless = seamless
+	opts.Phase = phase
+	opts.Delay = delay
+	opts.DelayScale = delayScale
+	opts.FPS = fps
+	opts.Coalesce = coalesce
+	opts.Orient = orient
+	opts.Width = width
+	opts.Height = height
+	opts.Quantizer = quantizer
+	opts.Spatial = spatial
+	opts.CenterX = centerX
+	opts.CenterY = centerY
 
-	if len(gradientColors) != 0 {
-		colorHexes := strings.Split(gradientColors, ",")
-		colors = make([]colorful.Color, len(colorHexes))
-		for i, hex := range colorHexes {
-			color, err := colorful.Hex("#" + hex)
-			if err != nil {
-				return nil, errors.New(fmt.Sprintf("Invalid color: %s", hex))
-			}
-			colors[i] = color
+	if randomGradient != 0 {
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		if verbose {
+			fmt.Fprintf(flags.Output(), "Random gradient seed: %d\n", seed)
+		}
+
+		var err error
+		opts.Colors, err = rainbow.RandomGradient(randomGradient, seed)
+		if err != nil {
+			return err
+		}
+	} else if len(gradientImage) != 0 {
+		file, err := os.Open(gradientImage)
+		if err != nil {
+			return fmt.Errorf("opening gradient image %q: %w", gradientImage, err)
+		}
+		reference, _, err := image.Decode(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("decoding gradient image %q: %w", gradientImage, err)
+		}
+
+		opts.Colors, err = rainbow.GradientFromImage(reference, gradientImageStops)
+		if err != nil {
+			return fmt.Errorf("picking colors from %q: %w", gradientImage, err)
+		}
+	} else if hueRange {
+		var err error
+		opts.Colors, err = rainbow.HueGradient(hueStart, hueEnd)
+		if err != nil {
+			return err
+		}
+	} else if len(preset) != 0 {
+		var okay bool
+		opts.Colors, okay = rainbow.GradientPreset(preset)
+		if !okay {
+			return fmt.Errorf("Invalid preset: %s", preset)
 		}
 	} else {
-		// ROYGBV
-		colors = []colorful.Color{
-			{1, 0, 0},
-			{1, 127.0 / 255.0, 0},
-			{1, 1, 0},
-			{0, 1, 0},
-			{0, 0, 1},
-			{139.0 / 255.0, 0, 1},
+		stops, opacities, err := rainbow.ParseGradientStops(gradientColors)
+		if err != nil {
+			return fmt.Errorf("parsing gradient: %w", err)
+		}
+
+		opts.Colors = make([]colorful.Color, len(stops))
+		opts.Positions = make([]float64, len(stops))
+		for i, stop := range stops {
+			opts.Colors[i] = stop.Color
+			opts.Positions[i] = stop.Pos
 		}
+		opts.Opacities = opacities
 	}
 
-	return colors, nil
-}
+	// only the gradient differs, every other option is shared with the first take
+	var compareOpts rainbow.Options
+	if len(compare) != 0 {
+		stops, opacities, err := rainbow.ParseGradientStops(compare)
+		if err != nil {
+			return fmt.Errorf("parsing compare gradient: %w", err)
+		}
 
-func main() {
-	// register image formats
-	image.RegisterFormat("jpg", "\xFF\xD8", jpeg.Decode, jpeg.DecodeConfig)
-	image.RegisterFormat("png", "\x89\x50\x4E\x47\x0D\x0A\x1A\x0A", png.Decode, png.DecodeConfig)
+		compareOpts.Colors = make([]colorful.Color, len(stops))
+		compareOpts.Positions = make([]float64, len(stops))
+		for i, stop := range stops {
+			compareOpts.Colors[i] = stop.Color
+			compareOpts.Positions[i] = stop.Pos
+		}
+		compareOpts.Opacities = opacities
+	}
 
-	var threads uint
-	flag.UintVar(&threads, "threads", uint(runtime.NumCPU())/2, "The number of go threads to use")
+	if len(mask) != 0 {
+		file, err := os.Open(mask)
+		if err != nil {
+			return fmt.Errorf("opening mask %q: %w", mask, err)
+		}
+		opts.Mask, err = png.Decode(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("decoding mask %q: %w", mask, err)
+		}
+	}
 
-	var gradientColors string
-	flag.StringVar(&gradientColors, "gradient", "", "A list of colors in hex without # separated by comma to use as the gradient")
+	if len(crop) != 0 {
+		var err error
+		opts.Crop, err = cropRect(crop)
+		if err != nil {
+			return err
+		}
+	}
 
-	var loopCount uint
-	flag.UintVar(&loopCount, "loop_count", 1, "The number of times ot loop through thr GIF or the number of frames to show")
+	if len(text) != 0 {
+		colors, _, err := rainbow.ParseGradientColors(textColor)
+		if err != nil {
+			return fmt.Errorf("parsing text color: %w", err)
+		}
+		if len(colors) != 1 {
+			return errors.New("Text color must be a single color")
+		}
 
-	var static bool
-	flag.BoolVar(&static, "static", false, "Whether it's a static image (JPG/PNG) or not")
+		opts.Text = text
+		opts.TextPosition = textPos
+		opts.TextColor = colors[0]
+	}
 
-	var delay uint
-	flag.UintVar(&delay, "delay", 0, "The delay between frames")
+	if len(background) != 0 {
+		colors, _, err := rainbow.ParseGradientColors(background)
+		if err != nil {
+			return fmt.Errorf("parsing background: %w", err)
+		}
+		if len(colors) != 1 {
+			return errors.New("Background must be a single color")
+		}
 
-	var quantizer string
-	flag.StringVar(&quantizer, "quantizer", "populosity", "quantizer algorithm to use")
+		opts.Background = &colors[0]
+	}
+	opts.Transparent = transparent
 
-	flag.Parse()
+	if len(matte) != 0 {
+		colors, _, err := rainbow.ParseGradientColors(matte)
+		if err != nil {
+			return fmt.Errorf("parsing matte: %w", err)
+		}
+		if len(colors) != 1 {
+			return errors.New("Matte must be a single color")
+		}
 
-	if threads < 1 {
-		fmt.Println("Thread count must be at least 1")
-		os.Exit(1)
+		opts.Matte = &colors[0]
 	}
 
-	colors, err := parseGradientColors(gradientColors)
-	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(1)
+	if gifLoops < -1 {
+		return errors.New("GIF loops must be at least 0")
 	}
 
-	if loopCount < 1 {
-		fmt.Println("Loop count must be at least 1")
-		os.Exit(1)
-	}
+	// without either loop flag the output loops like the input did
+	loopFlagGiven := given["infinite"] || given["gif_loops"]
+
+	positionalArgs := flags.Args()
 
-	positionalArgs := flag.Args()
+	// every argument before the output is an input, the first one names them in the logs and stats
+	var concatInputs []string
+	if concat {
+		if len(positionalArgs) < 3 {
+			return errors.New("concat expects at least two inputs followed by the output")
+		}
+
+		concatInputs = positionalArgs[:len(positionalArgs)-1]
+		for _, input := range concatInputs {
+			if input == "-" || isGlob(input) {
+				return errors.New("concat needs the inputs as files, not stdin or patterns")
+			}
+		}
+
+		positionalArgs = []string{concatInputs[0], positionalArgs[len(positionalArgs)-1]}
+	}
 
 	if len(positionalArgs) != 2 {
-		fmt.Println("Expected two positional arguments: input and output")
-		os.Exit(1)
+		return errors.New("Expected two positional arguments: input and output")
 	}
 
+	// - reads the input from stdin and writes the output to stdout as a GIF
 	input := positionalArgs[0]
 	output := positionalArgs[1]
 
-	file, err := os.Open(input)
-	if err != nil {
-		fmt.Println("Error opening file: ", err)
-		os.Exit(1)
+	if input == "-" && gradientColors == "-" {
+		return errors.New("Only one of the input and the gradient can be read from stdin")
 	}
 
-	var img *gif.GIF
+	if montage < 0 {
+		return errors.New("Montage columns must be at least 1")
+	}
 
-	if static {
-		staticImg, format, err := image.Decode(file)
-		if err != nil {
-			fmt.Println("Error decoding static image: ", err)
-			os.Exit(1)
+	if sequence && (montage > 0 || preview) {
+		return errors.New("sequence, montage, and preview are mutually exclusive, only one can be given")
+	}
+
+	if sequence && output == "-" {
+		return errors.New("sequence needs a directory to write the frames to")
+	}
+
+	if len(dumpGradient) != 0 && isGlob(input) {
+		return errors.New("dump_gradient only works with a single input")
+	}
+
+	if len(exportPalette) != 0 {
+		if isGlob(input) {
+			return errors.New("export_palette only works with a single input")
+		}
+
+		if lowMemory {
+			return errors.New("export_palette can't be combined with low_memory, the frames aren't kept")
+		}
+
+		if ext := strings.ToLower(filepath.Ext(exportPalette)); ext != ".gpl" && ext != ".act" {
+			return fmt.Errorf("Unsupported palette file %q, must be .gpl or .act", exportPalette)
 		}
-		img, err = staticTransform(staticImg, format, quantizer, delay)
-	} else {
-		img, err = gif.DecodeAll(file)
 	}
 
-	if err != nil {
-		fmt.Println("Error decoding: ", err)
-		os.Exit(1)
+	if len(compare) != 0 && (lowMemory || targetKB > 0 || dedupe) {
+		return errors.New("compare can't be combined with low_memory, target_kb, or dedupe")
 	}
-	file.Close()
 
-	frameCount := uint(len(img.Image)) * loopCount
-	newFrames := make([]*image.Paletted, frameCount)
-	for i := range newFrames {
-		originalFrame := img.Image[i%len(img.Image)]
-		newPalette := make([]color.Color, len(originalFrame.Palette))
-		copy(newPalette, originalFrame.Palette)
-		newFrames[i] = image.NewPaletted(originalFrame.Bounds(), newPalette)
+	if compareLayout != "vertical" && compareLayout != "horizontal" {
+		return fmt.Errorf("Invalid compare layout %q, must be vertical or horizontal", compareLayout)
+	}
+
+	if lowMemory && (targetKB > 0 || dryRun) {
+		return errors.New("low_memory can't be combined with target_kb or dry_run, they need the whole output")
+	}
+
+	if statsPath == "-" && output == "-" {
+		return errors.New("stats can't be written to stdout when the output is")
+	}
+
+	if previewAt < 0 || previewAt > 1 {
+		return errors.New("Preview position must be between 0 and 1")
+	}
+
+	if singleFrame != "sweep" && singleFrame != "still" {
+		return fmt.Errorf("Invalid single frame policy %q, must be sweep or still", singleFrame)
+	}
+
+	if singleFrameCount < 0 {
+		return errors.New("Frames must be at least 0")
+	}
+
+	var commentText string
+	if len(comment) != 0 {
+		commentText = provenanceComment(comment, opts.Colors)
+	}
+
+	// stdout may hold the output, so logs always go to stderr
+	logf := func(format string, args ...interface{}) {
+		if verbose {
+			fmt.Fprintf(flags.Output(), format+"\n", args...)
+		}
+	}
+
+	if verbose {
+		opts.Progress = func(done int, total int) {
+			logf("Processed %d/%d frames", done, total)
+		}
+	}
+	opts.Warn = func(message string) {
+		fmt.Fprintf(flags.Output(), "Warning: %s\n", message)
+	}
+
+	// processes img into output frame by frame for -low_memory, so the output frames are never all held at once
+	streamFile := func(input string, output string, stdout io.Writer, img *gif.GIF, fileOpts rainbow.Options, loopCount int, stats *Stats) error {
+		dst := stdout
+		var file *os.File
+		if output != "-" {
+			var err error
+			file, err = os.Create(output)
+			if err != nil {
+				return fmt.Errorf("encoding %q: %w", output, err)
+			}
+			dst = file
+		}
+
+		counter := &countingWriter{w: dst}
+		writer := &lowMemoryWriter{GIFWriter: rainbow.NewGIFWriter(counter), minDelay: minDelay}
+		writer.LoopCount = loopCount
+		writer.Comment = commentText
+
+		processStart := time.Now()
+		err := rainbow.RainbowifyStream(context.Background(), img, fileOpts, writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+
+			// half a GIF is no use to anyone
+			if err != nil {
+				os.Remove(output)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("processing %q: %w", input, err)
+		}
+
+		processing := time.Since(processStart)
+		if writer.clamped > 0 {
+			logf("Raised %d delays to %d", writer.clamped, minDelay)
+		}
+		logf("Processed and encoded %d frames in %v", writer.frames, processing)
+
+		stats.Frames = writer.frames
+		stats.LoopCount = writer.LoopCount
+		stats.Colors = make([]string, len(fileOpts.Colors))
+		for i, c := range fileOpts.Colors {
+			stats.Colors[i] = c.Hex()
+		}
+		stats.Blend = fileOpts.Blend
+		stats.Threads = fileOpts.Threads
+		stats.ProcessingMS = processing.Milliseconds()
+		stats.OutputBytes = counter.count
+
+		return nil
 	}
 
-	gradient := newGradient(colors, true)
-	overlayColors := gradient.generate(frameCount)
+	processFile := func(input string, output string, stats *Stats) error {
+		var err error
+		format := "gif"
+		if sequence {
+			format = "sequence"
+		} else if output != "-" {
+			format, err = outputFormat(output)
+			if err != nil {
+				return err
+			}
+		}
+
+		if montage > 0 && format != "png" {
+			return errors.New("Montage output must be a .png file")
+		}
+
+		if preview && format != "png" {
+			return errors.New("Preview output must be a .png file")
+		}
+
+		decodeStart := time.Now()
+
+		var img *gif.GIF
+		var static bool
+		if len(concatInputs) != 0 {
+			img, err = decodeConcat(concatInputs, concatStrict, quantizer)
+			if err != nil {
+				return err
+			}
+		} else if input == "-" {
+			img, static, err = rainbow.DecodeImage(stdin, quantizer)
+		} else {
+			var file *os.File
+			file, err = os.Open(input)
+			if err != nil {
+				return fmt.Errorf("opening %q: %w", input, err)
+			}
+
+			img, static, err = rainbow.DecodeImage(file, quantizer)
+			file.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("decoding %q: %w", input, err)
+		}
+
+		logf("Decoded %d frames in %v", len(img.Image), time.Since(decodeStart))
+
+		stats.Input = input
+		stats.Inputs = concatInputs
+		stats.Output = output
+		stats.Width = img.Config.Width
+		stats.Height = img.Config.Height
+		// stills don't say, their one frame is the whole picture
+		if stats.Width == 0 || stats.Height == 0 {
+			bounds := img.Image[0].Bounds()
+			stats.Width = bounds.Dx()
+			stats.Height = bounds.Dy()
+		}
+		stats.InputFrames = len(img.Image)
+
+		if len(frameRangeFlag) != 0 {
+			start, end, err := frameRange(frameRangeFlag, len(img.Image))
+			if err != nil {
+				return err
+			}
+
+			img.Image = img.Image[start:end]
+			if len(img.Delay) > 0 {
+				img.Delay = img.Delay[start:end]
+			}
+			if len(img.Disposal) > 0 {
+				img.Disposal = img.Disposal[start:end]
+			}
+		}
+
+		// a still image written out as a still gets the gradient's midpoint color
+		fileOpts := opts
+		fileOpts.Still = static && format != "gif" && format != "sequence" && format != "apng" && montage == 0
+
+		// a .png is a single picture unless an animation is asked for and a JPEG always is, previews and montages pick their own frames
+		if (format == "jpeg" || format == "png" && !animate) && montage == 0 && !preview {
+			fileOpts.Still = true
+		}
+
+		// a GIF with a single frame would only get one color, so it's either swept over several copies or made a still
+		if !static && len(img.Image) == 1 && singleFrame == "still" {
+			fileOpts.Still = true
+			logf("Single frame GIF, using the still policy")
+		}
+
+		// still images and single frames become an animation of frames copies
+		if len(img.Image) == 1 && !fileOpts.Still && singleFrameCount > 0 {
+			fileOpts.LoopCount = singleFrameCount
+			logf("Sweeping the frame over %d frames", singleFrameCount)
+		}
+
+		// the previewed frame has to be a complete picture by itself
+		if preview {
+			fileOpts.Coalesce = true
+		}
+
+		if targetKB > 0 && format != "gif" {
+			return errors.New("target_kb only works with GIF output")
+		}
+
+		if len(comment) != 0 && format != "gif" {
+			return errors.New("comment only works with GIF output")
+		}
+
+		/* an animation keeps looping the way it did unless a loop flag says otherwise
+		 * a still or a single frame has no looping to keep, so it gets the flags' defaults
+		 */
+		loopCount := outputLoopCount(infinite, gifLoops)
+		if !loopFlagGiven && !static && stats.InputFrames > 1 {
+			loopCount = img.LoopCount
+		}
+
+		finish := func(out *gif.GIF) {
+			out.LoopCount = loopCount
+			if clamped := rainbow.ClampDelays(out.Delay, minDelay); clamped > 0 {
+				logf("Raised %d delays to %d", clamped, minDelay)
+			}
+		}
+
+		/* written from the colors the frames are about to get, so it's there to look at while a long one runs
+		 * fitting to a size writes it again for every try, the last one is what gets encoded
+		 */
+		var dumpErr error
+		if len(dumpGradient) != 0 {
+			fileOpts.Overlay = func(colors []colorful.Color) {
+				strip, err := rainbow.GradientStrip(colors, gradientStripHeight)
+				if err == nil {
+					err = writePNG(dumpGradient, strip)
+				}
+				dumpErr = err
+			}
+		}
+
+		// a preview only renders the frame it shows, with the overlay it gets in the whole animation
+		var previewFrame, previewFrames int
+		process := rainbow.Rainbowify
+		if preview {
+			process = func(src *gif.GIF, opts rainbow.Options) (*gif.GIF, error) {
+				return rainbow.RainbowifyFrame(context.Background(), src, opts, func(frameCount int) int {
+					previewFrame, previewFrames = previewIndex(previewAt, frameCount), frameCount
+					return previewFrame
+				})
+			}
+		}
+
+		logf("Processing with %d threads", fileOpts.Threads)
+		processStart := time.Now()
+
+		if lowMemory {
+			if format != "gif" {
+				return errors.New("low_memory only works with GIF output")
+			}
+
+			err := streamFile(input, output, stdout, img, fileOpts, loopCount, stats)
+			if err == nil && dumpErr != nil {
+				err = fmt.Errorf("dumping the gradient: %w", dumpErr)
+			}
+			return err
+		}
+
+		if targetKB > 0 {
+			var size int
+			img, fileOpts, size, err = fitToSize(img, fileOpts, targetKB*1024, finish)
+			if err != nil {
+				return fmt.Errorf("processing %q: %w", input, err)
+			}
+
+			fmt.Fprintf(flags.Output(), "Fit %s in %d bytes with max_colors %d and every %d\n", input, size, fileOpts.MaxColors, fileOpts.Every)
+		} else {
+			src := img
+			img, err = process(src, fileOpts)
+			if err != nil {
+				return fmt.Errorf("processing %q: %w", input, err)
+			}
 
-	framesPerThread := frameCount/threads + 1
-	ch := make(chan uint)
-	barrier := uint(0)
+			if len(compare) != 0 {
+				otherOpts := fileOpts
+				otherOpts.Colors = compareOpts.Colors
+				otherOpts.Positions = compareOpts.Positions
+				otherOpts.Opacities = compareOpts.Opacities
+				// the strip shows the gradient of the main output
+				otherOpts.Overlay = nil
 
-	for i := 0; i < int(threads); i++ {
-		go func(base int, normalizedFrameIndex int) {
-			for processed := 0; processed < int(framesPerThread); processed++ {
-				frameIndex := base*int(framesPerThread) + processed
+				other, err := process(src, otherOpts)
+				if err != nil {
+					return fmt.Errorf("processing %q with the compare gradient: %w", input, err)
+				}
 
-				if frameIndex >= int(frameCount) {
-					break
+				img, err = rainbow.Stack(img, other, compareLayout, quantizer)
+				if err != nil {
+					return fmt.Errorf("comparing %q: %w", input, err)
 				}
+			}
+
+			finish(img)
+		}
+
+		if dumpErr != nil {
+			return fmt.Errorf("dumping the gradient: %w", dumpErr)
+		}
+
+		processing := time.Since(processStart)
+		logf("Processed %d frames in %v", len(img.Image), processing)
 
-				if normalizedFrameIndex >= len(img.Image) {
-					normalizedFrameIndex = 0
+		stats.Frames = len(img.Image)
+		stats.LoopCount = img.LoopCount
+		stats.Colors = make([]string, len(fileOpts.Colors))
+		for i, c := range fileOpts.Colors {
+			stats.Colors[i] = c.Hex()
+		}
+		stats.Blend = fileOpts.Blend
+		stats.Threads = fileOpts.Threads
+		stats.ProcessingMS = processing.Milliseconds()
+
+		if dryRun {
+			smallest, largest := len(img.Image[0].Palette), 0
+			for _, frame := range img.Image {
+				if len(frame.Palette) < smallest {
+					smallest = len(frame.Palette)
 				}
+				if len(frame.Palette) > largest {
+					largest = len(frame.Palette)
+				}
+			}
+
+			fmt.Fprintf(flags.Output(), "Frames: %d\n", len(img.Image))
+			fmt.Fprintf(flags.Output(), "Palette sizes: %d to %d colors\n", smallest, largest)
+			fmt.Fprintf(flags.Output(), "Estimated size: %d bytes before compression\n", estimateSize(img))
+			return nil
+		}
+
+		if len(exportPalette) != 0 {
+			if err := writePalette(exportPalette, img); err != nil {
+				return fmt.Errorf("exporting the palette: %w", err)
+			}
+		}
+
+		if preview {
+			logf("Previewing frame %d of %d", previewFrame, previewFrames)
+
+			if err := writePNG(output, img.Image[0]); err != nil {
+				return fmt.Errorf("encoding %q: %w", output, err)
+			}
+
+			stats.OutputBytes, err = outputBytes(output, false)
+			return err
+		}
+
+		encodeStart := time.Now()
+
+		if montage > 0 {
+			err = writeMontage(output, img, montage)
+		} else if sequence {
+			err = writeSequence(output, img)
+		} else if output == "-" {
+			counter := &countingWriter{w: stdout}
+			err = rainbow.EncodeWithComment(counter, img, commentText)
+			stats.OutputBytes = counter.count
+		} else {
+			err = encodeOutput(output, img, commentText)
+		}
+		if err != nil {
+			return fmt.Errorf("encoding %q: %w", output, err)
+		}
 
-				// do actual work in here
-				prepareFrame(
-					img.Image[normalizedFrameIndex],
-					newFrames[frameIndex],
-					overlayColors[frameIndex],
-				)
-				normalizedFrameIndex++
+		if output != "-" {
+			stats.OutputBytes, err = outputBytes(output, sequence)
+			if err != nil {
+				return err
 			}
+		}
+
+		logf("Encoded in %v", time.Since(encodeStart))
+
+		return nil
+	}
+
+	if !isGlob(input) {
+		var stats Stats
+		if err := processFile(input, output, &stats); err != nil {
+			return err
+		}
+
+		if len(statsPath) != 0 {
+			return writeStats(statsPath, stdout, stats)
+		}
+		return nil
+	}
+
+	inputs, err := filepath.Glob(input)
+	if err != nil {
+		return fmt.Errorf("matching %q: %w", input, err)
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("No files match %q", input)
+	}
 
-			// thread is done
-			ch <- 1
-		}(i, i*int(framesPerThread)%len(img.Image))
+	if output == "-" {
+		return errors.New("Several inputs can't be written to stdout")
 	}
 
-	// wait for all threads to synchronize
-	for barrier != threads {
-		barrier += <-ch
+	// files are processed side by side, at most threads at a time
+	errs := make([]error, len(inputs))
+	outputs := make([]string, len(inputs))
+	allStats := make([]Stats, len(inputs))
+	slots := make(chan struct{}, opts.Threads)
+	var wg sync.WaitGroup
+
+	for i, matched := range inputs {
+		outputs[i] = batchOutput(output, matched)
+
+		wg.Add(1)
+		go func(i int, matched string) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if err := os.MkdirAll(filepath.Dir(outputs[i]), 0755); err != nil {
+				errs[i] = err
+				return
+			}
+
+			errs[i] = processFile(matched, outputs[i], &allStats[i])
+		}(i, matched)
 	}
 
-	newDelay := make([]int, len(newFrames))
-	// overwrite the delay if one is provided, otherwise use default
-	for i := range newDelay {
-		if delay == 0 {
-			newDelay[i] = img.Delay[i%len(img.Delay)]
+	wg.Wait()
+
+	var failed int
+	var written []Stats
+	for i, matched := range inputs {
+		if errs[i] != nil {
+			failed++
+			fmt.Fprintf(flags.Output(), "Failed %s: %v\n", matched, errs[i])
 		} else {
-			newDelay[i] = int(delay)
+			fmt.Fprintf(flags.Output(), "Wrote %s\n", outputs[i])
+			written = append(written, allStats[i])
 		}
 	}
 
-	newDisposal := make([]byte, len(newFrames))
-	if len(img.Disposal) > 0 {
-		for i := range newDelay {
-			newDisposal[i] = img.Disposal[i%len(img.Disposal)]
+	if len(statsPath) != 0 {
+		if err := writeStats(statsPath, stdout, written); err != nil {
+			return err
 		}
 	}
 
-	img.Image = newFrames
-	img.Delay = newDelay
-	img.Disposal = newDisposal
-
-	file, err = os.OpenFile(output, os.O_RDWR|os.O_CREATE, 0644)
-	if err != nil {
-		fmt.Println("Error opening file: ", err)
-		os.Exit(1)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(inputs))
 	}
 
-	img.Config.ColorModel = nil
-	img.BackgroundIndex = 0
+	return nil
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 
-	err = gif.EncodeAll(file, img)
 	if err != nil {
-		fmt.Println("Error encoding image: ", err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
-	file.Close()
 }
@@ -0,0 +1,1665 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jwoos/rainbowgif/rainbow"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// builds a small animated GIF where every frame has its own palette and pixels
+func newTestGIF(frameCount int, width int, height int) *gif.GIF {
+	img := &gif.GIF{
+		Image:    make([]*image.Paletted, frameCount),
+		Delay:    make([]int, frameCount),
+		Disposal: make([]byte, frameCount),
+	}
+
+	for i := range img.Image {
+		palette := color.Palette{
+			color.RGBA{R: uint8(20 * i), G: 0, B: 0, A: 255},
+			color.RGBA{R: 0, G: uint8(20 * i), B: 128, A: 255},
+			color.RGBA{R: 200, G: 200, B: uint8(20 * i), A: 255},
+			color.RGBA{R: 0, G: 0, B: 0, A: 0},
+		}
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8((i + j) % len(palette))
+		}
+
+		img.Image[i] = frame
+		img.Delay[i] = 10
+	}
+
+	return img
+}
+
+// frames full of random colors, which compress badly
+func newNoiseGIF(frameCount int, size int) *gif.GIF {
+	random := rand.New(rand.NewSource(1))
+	img := newTestGIF(frameCount, size, size)
+
+	for _, frame := range img.Image {
+		frame.Palette = make(color.Palette, 256)
+		for i := range frame.Palette {
+			frame.Palette[i] = color.RGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: 255}
+		}
+
+		for i := range frame.Pix {
+			frame.Pix[i] = uint8(random.Intn(256))
+		}
+	}
+
+	return img
+}
+
+func TestOutputLoopCount(t *testing.T) {
+	cases := []struct {
+		name     string
+		infinite bool
+		gifLoops int
+		expected int
+	}{
+		{name: "Infinite", infinite: true, gifLoops: -1, expected: 0},
+		{name: "Play once", infinite: false, gifLoops: -1, expected: -1},
+		{name: "Explicit loops", infinite: true, gifLoops: 3, expected: 3},
+		{name: "Explicit forever", infinite: false, gifLoops: 0, expected: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				img := newTestGIF(2, 2, 2)
+				img.LoopCount = outputLoopCount(c.infinite, c.gifLoops)
+
+				var buf bytes.Buffer
+				if err := gif.EncodeAll(&buf, img); err != nil {
+					innerT.Fatalf("Error encoding: %v", err)
+				}
+
+				decoded, err := gif.DecodeAll(&buf)
+				if err != nil {
+					innerT.Fatalf("Error decoding: %v", err)
+				}
+
+				if decoded.LoopCount != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, decoded.LoopCount)
+				}
+			},
+		)
+	}
+}
+
+func TestFPSDelay(t *testing.T) {
+	cases := []struct {
+		fps      float64
+		expected int
+	}{
+		{fps: 10, expected: 10},
+		{fps: 25, expected: 4},
+		{fps: 30, expected: 3},
+		{fps: 60, expected: 2},
+		{fps: 0.5, expected: 200},
+		{fps: 500, expected: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			fmt.Sprintf("%v fps", c.fps),
+			func(innerT *testing.T) {
+				if actual := fpsDelay(c.fps); actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestFrameRange(t *testing.T) {
+	cases := []struct {
+		value string
+		start int
+		end   int
+		fails bool
+	}{
+		{value: "2:5", start: 2, end: 5},
+		{value: ":4", start: 0, end: 4},
+		{value: "5:", start: 5, end: 10},
+		{value: ":", start: 0, end: 10},
+		{value: "0:10", start: 0, end: 10},
+		{value: "5", fails: true},
+		{value: "a:b", fails: true},
+		{value: "5:5", fails: true},
+		{value: "6:3", fails: true},
+		{value: "-1:3", fails: true},
+		{value: "0:11", fails: true},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.value,
+			func(innerT *testing.T) {
+				start, end, err := frameRange(c.value, 10)
+				if c.fails {
+					if err == nil {
+						innerT.Errorf("Expected an error but got %v:%v", start, end)
+					}
+					return
+				}
+
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if start != c.start || end != c.end {
+					innerT.Errorf("Expected %v but got %v", fmt.Sprintf("%d:%d", c.start, c.end), fmt.Sprintf("%d:%d", start, end))
+				}
+			},
+		)
+	}
+}
+
+func TestCropRect(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected image.Rectangle
+		fails    bool
+	}{
+		{value: "10,10,20,20", expected: image.Rect(10, 10, 30, 30)},
+		{value: "0, 5, 1, 2", expected: image.Rect(0, 5, 1, 7)},
+		{value: "1,2,3", fails: true},
+		{value: "a,b,c,d", fails: true},
+		{value: "0,0,0,5", fails: true},
+		{value: "0,0,5,-1", fails: true},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.value,
+			func(innerT *testing.T) {
+				actual, err := cropRect(c.value)
+				if c.fails {
+					if err == nil {
+						innerT.Errorf("Expected an error but got %v", actual)
+					}
+					return
+				}
+
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	img := newTestGIF(2, 4, 4)
+
+	// header, then per frame the extension, descriptor, 4 colors, and 16 pixels
+	expected := 13 + 2*(8+10+3*4+16)
+	if actual := estimateSize(img); actual != expected {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+
+	// a shared palette is only counted once
+	palette := img.Image[0].Palette
+	img.Image[1].Palette = palette
+	img.Config.ColorModel = palette
+
+	expected = 13 + 3*4 + 2*(8+10+16)
+	if actual := estimateSize(img); actual != expected {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+}
+
+func TestBatchOutput(t *testing.T) {
+	cases := []struct {
+		output   string
+		input    string
+		expected string
+	}{
+		{output: "out/{name}_rainbow.gif", input: "in/cat.gif", expected: filepath.Join("out", "cat_rainbow.gif")},
+		{output: "{name}.png", input: "in/cat.gif", expected: "cat.png"},
+		{output: "out", input: "in/cat.gif", expected: filepath.Join("out", "cat.gif")},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.output,
+			func(innerT *testing.T) {
+				if actual := batchOutput(c.output, c.input); actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestPreviewIndex(t *testing.T) {
+	cases := []struct {
+		at       float64
+		frames   int
+		expected int
+	}{
+		{at: 0.5, frames: 10, expected: 5},
+		{at: 0.5, frames: 9, expected: 4},
+		{at: 0, frames: 10, expected: 0},
+		{at: 1, frames: 10, expected: 9},
+		{at: 0.5, frames: 1, expected: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			fmt.Sprintf("%v of %d", c.at, c.frames),
+			func(innerT *testing.T) {
+				if actual := previewIndex(c.at, c.frames); actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestEncodeOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rainbowgif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := newTestGIF(3, 4, 4)
+
+	cases := []struct {
+		name   string
+		format string
+	}{
+		{name: "out.gif", format: "gif"},
+		{name: "out.png", format: "png"},
+		{name: "out.apng", format: "png"},
+		{name: "out.JPG", format: "jpeg"},
+		{name: "out.jpeg", format: "jpeg"},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				path := filepath.Join(dir, c.name)
+				if err := encodeOutput(path, img, ""); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				file, err := os.Open(path)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+				defer file.Close()
+
+				config, format, err := image.DecodeConfig(file)
+				if err != nil {
+					innerT.Fatalf("Error decoding: %v", err)
+				}
+
+				if format != c.format {
+					innerT.Errorf("Expected %v but got %v", c.format, format)
+				}
+
+				if config.Width != 4 || config.Height != 4 {
+					innerT.Errorf("Expected %v but got %v", "4x4", fmt.Sprintf("%dx%d", config.Width, config.Height))
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Unsupported extension",
+		func(innerT *testing.T) {
+			path := filepath.Join(dir, "out.bmp")
+			if err := encodeOutput(path, img, ""); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				innerT.Errorf("Expected no file to be written but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"WebP without the encoder",
+		func(innerT *testing.T) {
+			if rainbow.WebPSupported {
+				innerT.Skip("built with the WebP encoder")
+			}
+
+			path := filepath.Join(dir, "out.webp")
+			if err := encodeOutput(path, img, ""); !errors.Is(err, rainbow.ErrUnsupportedFormat) {
+				innerT.Errorf("Expected %v but got %v", rainbow.ErrUnsupportedFormat, err)
+			}
+
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				innerT.Errorf("Expected no file to be written but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Failed encoding",
+		func(innerT *testing.T) {
+			path := filepath.Join(dir, "empty.gif")
+			if err := encodeOutput(path, &gif.GIF{}, ""); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				innerT.Errorf("Expected no file to be left but got %v", err)
+			}
+		},
+	)
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rainbowgif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "in.gif")
+	output := filepath.Join(dir, "out.gif")
+	if err := encodeOutput(input, newTestGIF(4, 4, 4), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run(
+		"Success",
+		func(innerT *testing.T) {
+			if err := run([]string{"-threads", "2", input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if _, err := os.Stat(output); err != nil {
+				innerT.Errorf("Expected output to exist but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Stdin to stdout",
+		func(innerT *testing.T) {
+			var stdin bytes.Buffer
+			if err := gif.EncodeAll(&stdin, newTestGIF(4, 4, 4)); err != nil {
+				innerT.Fatal(err)
+			}
+
+			var stdout bytes.Buffer
+			if err := run([]string{"-threads", "1", "-", "-"}, &stdin, &stdout); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			decoded, err := gif.DecodeAll(&stdout)
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if len(decoded.Image) != 4 {
+				innerT.Errorf("Expected %v but got %v", 4, len(decoded.Image))
+			}
+		},
+	)
+
+	t.Run(
+		"Verbose keeps stdout clean",
+		func(innerT *testing.T) {
+			var stdin bytes.Buffer
+			if err := gif.EncodeAll(&stdin, newTestGIF(4, 4, 4)); err != nil {
+				innerT.Fatal(err)
+			}
+
+			var stdout bytes.Buffer
+			if err := run([]string{"-threads", "2", "-verbose", "-min_delay", "20", "-", "-"}, &stdin, &stdout); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if _, err := gif.DecodeAll(&stdout); err != nil {
+				innerT.Errorf("Error decoding: %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Invalid gradient",
+		func(innerT *testing.T) {
+			err := run([]string{"-threads", "1", "-gradient", "ff0000,zzz", input, output}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), `Invalid color "zzz"`) {
+				innerT.Errorf("Expected an invalid color error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Gradient image",
+		func(innerT *testing.T) {
+			reference := filepath.Join(dir, "reference.png")
+			if err := encodeOutput(reference, newTestGIF(1, 4, 4), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			if err := run([]string{"-threads", "1", "-gradient_image", reference, "-gradient_image_stops", "2", input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			err := run([]string{"-threads", "1", "-gradient_image", reference, "-preset", "pride", input, output}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+				innerT.Errorf("Expected a mutually exclusive error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Batch",
+		func(innerT *testing.T) {
+			batchDir := filepath.Join(dir, "batch")
+			if err := os.Mkdir(batchDir, 0755); err != nil {
+				innerT.Fatal(err)
+			}
+			for _, name := range []string{"one.gif", "two.gif"} {
+				if err := encodeOutput(filepath.Join(batchDir, name), newTestGIF(2, 4, 4), ""); err != nil {
+					innerT.Fatal(err)
+				}
+			}
+
+			pattern := filepath.Join(dir, "batched", "{name}_rainbow.gif")
+			if err := run([]string{"-threads", "2", filepath.Join(batchDir, "*.gif"), pattern}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for _, name := range []string{"one_rainbow.gif", "two_rainbow.gif"} {
+				if _, err := os.Stat(filepath.Join(dir, "batched", name)); err != nil {
+					innerT.Errorf("Expected output to exist but got %v", err)
+				}
+			}
+
+			// one broken file fails the batch but the other is still written
+			if err := ioutil.WriteFile(filepath.Join(batchDir, "broken.gif"), []byte("not a gif"), 0644); err != nil {
+				innerT.Fatal(err)
+			}
+
+			err := run([]string{"-threads", "2", filepath.Join(batchDir, "*.gif"), filepath.Join(dir, "batched_dir")}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "1 of 3 files failed") {
+				innerT.Errorf("Expected a failed file error but got %v", err)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "batched_dir", "one.gif")); err != nil {
+				innerT.Errorf("Expected output to exist but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Target size",
+		func(innerT *testing.T) {
+			noisy := filepath.Join(dir, "noisy.gif")
+			if err := encodeOutput(noisy, newNoiseGIF(8, 48), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			full := filepath.Join(dir, "full.gif")
+			if err := run([]string{"-threads", "2", noisy, full}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+			fullInfo, err := os.Stat(full)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			targetKB := int(fullInfo.Size()) / 2 / 1024
+			targeted := filepath.Join(dir, "targeted.gif")
+			if err := run([]string{"-threads", "2", "-target_kb", fmt.Sprint(targetKB), noisy, targeted}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			targetedInfo, err := os.Stat(targeted)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			if targetedInfo.Size() > int64(targetKB*1024) {
+				innerT.Errorf("Expected at most %v but got %v", targetKB*1024, targetedInfo.Size())
+			}
+
+			err = run([]string{"-threads", "2", "-target_kb", "1", noisy, targeted}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "Cannot reach the target") {
+				innerT.Errorf("Expected a target error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Preview",
+		func(innerT *testing.T) {
+			previewOutput := filepath.Join(dir, "preview.png")
+			if err := run([]string{"-threads", "1", "-preview", input, previewOutput}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			data, err := ioutil.ReadFile(previewOutput)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			// a plain PNG, not an animation
+			if bytes.Contains(data, []byte("acTL")) {
+				innerT.Errorf("Expected a still PNG but got an APNG")
+			}
+
+			config, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+			if config.Width != 4 || config.Height != 4 {
+				innerT.Errorf("Expected %v but got %v", "4x4", fmt.Sprintf("%dx%d", config.Width, config.Height))
+			}
+
+			// the same as that frame of the whole animation, which is only rendered here to compare against
+			if err := run([]string{"-threads", "1", "-preview", "-preview_at", "1", "-loop_count", "2", input, previewOutput}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+			// previews are coalesced
+			if err := run([]string{"-threads", "1", "-loop_count", "2", "-coalesce", input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			previewFile, err := os.Open(previewOutput)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			previewed, err := png.Decode(previewFile)
+			previewFile.Close()
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			b, err := ioutil.ReadFile(output)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			full, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			last := rainbow.Frames(full)[len(full.Image)-1]
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					expected := color.RGBAModel.Convert(last.At(x, y))
+					if actual := color.RGBAModel.Convert(previewed.At(x, y)); actual != expected {
+						innerT.Errorf("(%d, %d) - expected %v but got %v", x, y, expected, actual)
+					}
+				}
+			}
+
+			if err := run([]string{"-threads", "1", "-preview", input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Single frame",
+		func(innerT *testing.T) {
+			single := filepath.Join(dir, "single.gif")
+			if err := encodeOutput(single, newTestGIF(1, 4, 4), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			cases := []struct {
+				name     string
+				args     []string
+				expected int
+			}{
+				{name: "Sweep", args: []string{"-single_frame", "sweep", "-frames", "6"}, expected: 6},
+				{name: "Sweep uses loop_count", args: []string{"-loop_count", "3"}, expected: 3},
+				{name: "Still", args: []string{"-single_frame", "still", "-frames", "6"}, expected: 1},
+			}
+
+			for _, c := range cases {
+				swept := filepath.Join(dir, "single_out.gif")
+				args := append([]string{"-threads", "1"}, c.args...)
+				if err := run(append(args, single, swept), nil, nil); err != nil {
+					innerT.Fatalf("%s - unexpected error %v", c.name, err)
+				}
+
+				b, err := ioutil.ReadFile(swept)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				out, err := gif.DecodeAll(bytes.NewReader(b))
+				if err != nil {
+					innerT.Fatalf("Error decoding: %v", err)
+				}
+				if len(out.Image) != c.expected {
+					innerT.Errorf("%s - expected %v but got %v", c.name, c.expected, len(out.Image))
+				}
+
+				// every swept frame gets its own color
+				if len(out.Image) > 1 && out.Image[0].Palette[0] == out.Image[1].Palette[0] {
+					innerT.Errorf("%s - expected different colors but got %v twice", c.name, out.Image[0].Palette[0])
+				}
+			}
+
+			err := run([]string{"-threads", "1", "-single_frame", "nope", single, output}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "Invalid single frame policy") {
+				innerT.Errorf("Expected a policy error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Frames for a still image",
+		func(innerT *testing.T) {
+			still := filepath.Join(dir, "still.png")
+			if err := encodeOutput(still, newTestGIF(1, 4, 4), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			animated := filepath.Join(dir, "still_out.gif")
+			if err := run([]string{"-threads", "1", "-frames", "12", "-fps", "12", still, animated}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(animated)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			out, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if len(out.Image) != 12 {
+				innerT.Errorf("Expected %v but got %v", 12, len(out.Image))
+			}
+
+			// 12 frames at 12 fps last a second, 8.33 per frame is spread as 8 and 9
+			var total int
+			for _, delay := range out.Delay {
+				if delay != 8 && delay != 9 {
+					innerT.Errorf("Expected %v or %v but got %v", 8, 9, delay)
+				}
+				total += delay
+			}
+
+			if total != 100 {
+				innerT.Errorf("Expected %v but got %v", 100, total)
+			}
+		},
+	)
+
+	t.Run(
+		"Orient",
+		func(innerT *testing.T) {
+			wide := filepath.Join(dir, "wide.gif")
+			if err := encodeOutput(wide, newTestGIF(2, 4, 2), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			turned := filepath.Join(dir, "turned.gif")
+			if err := run([]string{"-threads", "1", "-orient", "90", wide, turned}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(turned)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			out, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if out.Config.Width != 2 || out.Config.Height != 4 {
+				innerT.Errorf("Expected %v but got %vx%v", "2x4", out.Config.Width, out.Config.Height)
+			}
+
+			for i, frame := range out.Image {
+				if frame.Bounds() != image.Rect(0, 0, 2, 4) {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, image.Rect(0, 0, 2, 4), frame.Bounds())
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Resize",
+		func(innerT *testing.T) {
+			large := filepath.Join(dir, "large.gif")
+			if err := encodeOutput(large, newTestGIF(2, 100, 40), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			small := filepath.Join(dir, "small.gif")
+			if err := run([]string{"-threads", "1", "-width", "50", large, small}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(small)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			out, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if out.Config.Width != 50 || out.Config.Height != 20 {
+				innerT.Errorf("Expected %v but got %vx%v", "50x20", out.Config.Width, out.Config.Height)
+			}
+		},
+	)
+
+	t.Run(
+		"Crop",
+		func(innerT *testing.T) {
+			square := filepath.Join(dir, "square.gif")
+			if err := encodeOutput(square, newTestGIF(2, 100, 100), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			cropped := filepath.Join(dir, "cropped.gif")
+			if err := run([]string{"-threads", "1", "-crop", "10,10,20,20", square, cropped}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(cropped)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			out, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if out.Config.Width != 20 || out.Config.Height != 20 {
+				innerT.Errorf("Expected %v but got %vx%v", "20x20", out.Config.Width, out.Config.Height)
+			}
+
+			err = run([]string{"-threads", "1", "-crop", "90,90,20,20", square, cropped}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "inside the canvas") {
+				innerT.Errorf("Expected a crop error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Text",
+		func(innerT *testing.T) {
+			captioned := filepath.Join(dir, "captioned.gif")
+			if err := run([]string{"-threads", "1", "-text", "hello", "-text_pos", "top", "-text_color", "00ff00", input, captioned}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			err := run([]string{"-threads", "1", "-text", "hello", "-text_color", "00ff00,0000ff", input, captioned}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "single color") {
+				innerT.Errorf("Expected a single color error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Stats",
+		func(innerT *testing.T) {
+			statsInput := filepath.Join(dir, "stats.gif")
+			if err := encodeOutput(statsInput, newTestGIF(3, 4, 2), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			statsOutput := filepath.Join(dir, "stats_out.gif")
+			statsPath := filepath.Join(dir, "stats.json")
+			if err := run([]string{"-threads", "1", "-stats", statsPath, "-gradient", "ff0000,0000ff", statsInput, statsOutput}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(statsPath)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			var stats Stats
+			if err := json.Unmarshal(b, &stats); err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			info, err := os.Stat(statsOutput)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			expected := Stats{
+				Input:        statsInput,
+				Output:       statsOutput,
+				Width:        4,
+				Height:       2,
+				InputFrames:  3,
+				Frames:       3,
+				Colors:       []string{"#ff0000", "#0000ff"},
+				Blend:        "color",
+				Threads:      1,
+				ProcessingMS: stats.ProcessingMS,
+				OutputBytes:  info.Size(),
+			}
+			if !reflect.DeepEqual(stats, expected) {
+				innerT.Errorf("Expected %+v but got %+v", expected, stats)
+			}
+
+			var stdout bytes.Buffer
+			if err := run([]string{"-threads", "1", "-stats", "-", statsInput, statsOutput}, nil, &stdout); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if err := json.Unmarshal(stdout.Bytes(), &stats); err != nil || stats.Frames != 3 {
+				innerT.Errorf("Expected %v frames but got %v (%v)", 3, stats.Frames, err)
+			}
+
+			err = run([]string{"-threads", "1", "-stats", "-", statsInput, "-"}, nil, &stdout)
+			if err == nil || !strings.Contains(err.Error(), "stats can't be written to stdout") {
+				innerT.Errorf("Expected a stdout error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"PNG and APNG",
+		func(innerT *testing.T) {
+			cases := []struct {
+				name     string
+				args     []string
+				animated bool
+			}{
+				{name: "still.png", animated: false},
+				{name: "animated.png", args: []string{"-animate"}, animated: true},
+				{name: "animated.apng", animated: true},
+			}
+
+			for _, c := range cases {
+				path := filepath.Join(dir, c.name)
+				args := append([]string{"-threads", "1"}, c.args...)
+				if err := run(append(args, input, path), nil, nil); err != nil {
+					innerT.Fatalf("%s - unexpected error %v", c.name, err)
+				}
+
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				if animated := bytes.Contains(data, []byte("acTL")); animated != c.animated {
+					innerT.Errorf("%s - expected animated to be %v but got %v", c.name, c.animated, animated)
+				}
+
+				if _, err := png.DecodeConfig(bytes.NewReader(data)); err != nil {
+					innerT.Errorf("%s - error decoding: %v", c.name, err)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"JPEG",
+		func(innerT *testing.T) {
+			// a solid color, so what JPEG loses stays small
+			solid := &gif.GIF{Config: image.Config{Width: 8, Height: 8}}
+			for i := 0; i < 3; i++ {
+				solid.Image = append(solid.Image, image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255}}))
+				solid.Delay = append(solid.Delay, 10)
+			}
+			solidInput := filepath.Join(dir, "solid.gif")
+			if err := encodeOutput(solidInput, solid, ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			// a still like a .png, so only the one frame is rendered however many loops there are
+			jpegPath := filepath.Join(dir, "still.jpg")
+			statsPath := filepath.Join(dir, "jpeg.json")
+			if err := run([]string{"-threads", "1", "-loop_count", "3", "-stats", statsPath, solidInput, jpegPath}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(statsPath)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			var stats Stats
+			if err := json.Unmarshal(b, &stats); err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if stats.Frames != 1 {
+				innerT.Errorf("Expected %v but got %v", 1, stats.Frames)
+			}
+
+			pngPath := filepath.Join(dir, "still.png")
+			if err := run([]string{"-threads", "1", solidInput, pngPath}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			decode := func(path string) image.Image {
+				file, err := os.Open(path)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+				defer file.Close()
+
+				img, _, err := image.Decode(file)
+				if err != nil {
+					innerT.Fatalf("Error decoding %s: %v", path, err)
+				}
+
+				return img
+			}
+
+			// the same midpoint color as the .png, give or take what JPEG loses
+			still, compressed := decode(pngPath), decode(jpegPath)
+			bounds := still.Bounds()
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					r1, g1, b1, _ := still.At(x, y).RGBA()
+					r2, g2, b2, _ := compressed.At(x, y).RGBA()
+					for _, diff := range []int{int(r1>>8) - int(r2>>8), int(g1>>8) - int(g2>>8), int(b1>>8) - int(b2>>8)} {
+						if diff < -8 || diff > 8 {
+							innerT.Errorf("(%d, %d) - expected about %v but got %v", x, y, still.At(x, y), compressed.At(x, y))
+						}
+					}
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Max frames",
+		func(innerT *testing.T) {
+			err := run([]string{"-threads", "1", "-loop_count", "1000000000", input, output}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "Too many output frames") {
+				innerT.Errorf("Expected a too many frames error but got %v", err)
+			}
+
+			if err := run([]string{"-threads", "1", "-loop_count", "3", "-max_frames", "12", input, output}, nil, nil); err != nil {
+				innerT.Errorf("Unexpected error %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Low memory",
+		func(innerT *testing.T) {
+			args := []string{"-threads", "2", "-loop_count", "5", "-min_delay", "20", "-infinite", "-comment", "hi"}
+
+			if err := run(append(args, input, output), nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+			expected, err := ioutil.ReadFile(output)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			streamedOutput := filepath.Join(dir, "streamed.gif")
+			if err := run(append(args, "-low_memory", input, streamedOutput), nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+			streamed, err := ioutil.ReadFile(streamedOutput)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			if !bytes.Equal(streamed, expected) {
+				innerT.Errorf("Expected the low memory output to match the normal one")
+			}
+
+			var stdout bytes.Buffer
+			if err := run(append(args, "-low_memory", input, "-"), nil, &stdout); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if !bytes.Equal(stdout.Bytes(), expected) {
+				innerT.Errorf("Expected the low memory output on stdout to match the normal one")
+			}
+
+			if err := run([]string{"-low_memory", "-dedupe", input, streamedOutput}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error for an option that needs every frame")
+			}
+			if _, err := os.Stat(streamedOutput); !os.IsNotExist(err) {
+				innerT.Errorf("Expected the failed output to be removed but got %v", err)
+			}
+
+			if err := run([]string{"-low_memory", input, filepath.Join(dir, "streamed.png")}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error for PNG output")
+			}
+		},
+	)
+
+	t.Run(
+		"Compare",
+		func(innerT *testing.T) {
+			cases := []struct {
+				layout string
+				width  int
+				height int
+			}{
+				{layout: "vertical", width: 4, height: 8},
+				{layout: "horizontal", width: 8, height: 4},
+			}
+
+			for _, c := range cases {
+				if err := run([]string{"-threads", "1", "-compare", "#0000ff,#00ff00", "-compare_layout", c.layout, input, output}, nil, nil); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				b, err := ioutil.ReadFile(output)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+				img, err := gif.DecodeAll(bytes.NewReader(b))
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				if img.Config.Width != c.width || img.Config.Height != c.height {
+					innerT.Errorf("%s - expected %vx%v but got %vx%v", c.layout, c.width, c.height, img.Config.Width, img.Config.Height)
+				}
+
+				if len(img.Image) != 4 {
+					innerT.Errorf("%s - expected %v but got %v", c.layout, 4, len(img.Image))
+				}
+			}
+
+			if err := run([]string{"-compare", "#0000ff", "-low_memory", input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error for compare with low_memory")
+			}
+
+			if err := run([]string{"-compare", "#0000ff", "-compare_layout", "diagonal", input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error for an invalid layout")
+			}
+		},
+	)
+
+	t.Run(
+		"Source loop count",
+		func(innerT *testing.T) {
+			cases := []struct {
+				name     string
+				source   int
+				args     []string
+				expected int
+			}{
+				{name: "Forever", source: 0, expected: 0},
+				{name: "Play once", source: -1, expected: -1},
+				{name: "Repeat", source: 3, expected: 3},
+				{name: "Infinite flag", source: -1, args: []string{"-infinite"}, expected: 0},
+				{name: "Not infinite", source: 0, args: []string{"-infinite=false"}, expected: -1},
+				{name: "GIF loops flag", source: 0, args: []string{"-gif_loops", "2"}, expected: 2},
+			}
+
+			for _, c := range cases {
+				src := newTestGIF(3, 4, 4)
+				src.LoopCount = c.source
+
+				loopInput := filepath.Join(dir, "loop.gif")
+				if err := encodeOutput(loopInput, src, ""); err != nil {
+					innerT.Fatal(err)
+				}
+
+				if err := run(append(append([]string{"-threads", "1"}, c.args...), loopInput, output), nil, nil); err != nil {
+					innerT.Fatalf("%s - unexpected error %v", c.name, err)
+				}
+
+				b, err := ioutil.ReadFile(output)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+				img, err := gif.DecodeAll(bytes.NewReader(b))
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				if img.LoopCount != c.expected {
+					innerT.Errorf("%s - expected %v but got %v", c.name, c.expected, img.LoopCount)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Hue range",
+		func(innerT *testing.T) {
+			statsPath := filepath.Join(dir, "hue_stats.json")
+			if err := run([]string{"-threads", "1", "-hue_start", "0", "-hue_end", "120", "-stats", statsPath, input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(statsPath)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			var stats Stats
+			if err := json.Unmarshal(b, &stats); err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if stats.Colors[0] != "#ff0000" || stats.Colors[len(stats.Colors)-1] != "#00ff00" {
+				innerT.Errorf("Expected %v to %v but got %v", "#ff0000", "#00ff00", stats.Colors)
+			}
+
+			// red to green never has any blue in it
+			for _, hex := range stats.Colors {
+				c, err := colorful.Hex(hex)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				if hue, _, _ := c.Hsv(); hue > 120 || c.B != 0 {
+					innerT.Errorf("Expected a color from red to green but got %v", hex)
+				}
+			}
+
+			if err := run([]string{"-hue_start", "200", "-preset", "bi", input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error for a hue range with a gradient")
+			}
+
+			if err := run([]string{"-hue_start", "400", input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error for a hue past 360")
+			}
+		},
+	)
+
+	t.Run(
+		"Dump gradient",
+		func(innerT *testing.T) {
+			stripPath := filepath.Join(dir, "strip.png")
+
+			// a column per output frame, input has 4
+			cases := []struct {
+				args     []string
+				expected int
+			}{
+				{args: []string{"-loop_count", "2"}, expected: 8},
+				{args: []string{"-every", "2"}, expected: 2},
+				{args: []string{"-interpolate_frames", "1"}, expected: 8},
+				{args: []string{"-sweep_seconds", "1"}, expected: 4},
+			}
+
+			for _, c := range cases {
+				args := append(append([]string{"-threads", "1", "-dump_gradient", stripPath}, c.args...), input, output)
+				if err := run(args, nil, nil); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				file, err := os.Open(stripPath)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				strip, err := png.Decode(file)
+				file.Close()
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				if width := strip.Bounds().Dx(); width != c.expected {
+					innerT.Errorf("%v - expected %v but got %v", c.args, c.expected, width)
+				}
+			}
+
+			if err := run([]string{"-dump_gradient", stripPath, filepath.Join(dir, "*.gif"), dir}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error")
+			}
+		},
+	)
+
+	t.Run(
+		"Concat",
+		func(innerT *testing.T) {
+			second := filepath.Join(dir, "second.gif")
+			if err := encodeOutput(second, newTestGIF(2, 2, 2), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			if err := run([]string{"-threads", "1", "-concat", input, second, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(output)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			img, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			if len(img.Image) != 6 {
+				innerT.Errorf("Expected %v but got %v", 6, len(img.Image))
+			}
+
+			// the smaller one is centered on the larger one's canvas
+			if img.Config.Width != 4 || img.Config.Height != 4 {
+				innerT.Errorf("Expected %v by %v but got %v by %v", 4, 4, img.Config.Width, img.Config.Height)
+			}
+
+			// a directory output, named after the first input in the stats
+			concatDir := filepath.Join(dir, "concat")
+			statsPath := filepath.Join(dir, "concat.json")
+			if err := run([]string{"-threads", "1", "-concat", "-sequence", "-stats", statsPath, input, second, concatDir}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			names, err := filepath.Glob(filepath.Join(concatDir, "frame_*.png"))
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			if len(names) != 6 {
+				innerT.Errorf("Expected %v but got %v", 6, len(names))
+			}
+
+			b, err = ioutil.ReadFile(statsPath)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			var stats Stats
+			if err := json.Unmarshal(b, &stats); err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if stats.Input != input || !reflect.DeepEqual(stats.Inputs, []string{input, second}) {
+				innerT.Errorf("Expected %v and %v but got %v and %v", input, []string{input, second}, stats.Input, stats.Inputs)
+			}
+
+			invalid := [][]string{
+				{"-concat", input, output},
+				{"-concat", "-concat_strict", input, second, output},
+				{"-concat", "-", second, output},
+			}
+			for _, args := range invalid {
+				if err := run(args, nil, nil); err == nil {
+					innerT.Errorf("%v - expected an error", args)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Export palette",
+		func(innerT *testing.T) {
+			actPath := filepath.Join(dir, "palette.act")
+			if err := run([]string{"-threads", "1", "-global_palette", "-export_palette", actPath, input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			table, err := ioutil.ReadFile(actPath)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			if len(table) != 768 {
+				innerT.Fatalf("Expected %v but got %v", 768, len(table))
+			}
+
+			b, err := ioutil.ReadFile(output)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			img, err := gif.DecodeAll(bytes.NewReader(b))
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			global, ok := img.Config.ColorModel.(color.Palette)
+			if !ok {
+				innerT.Fatalf("Expected a global palette")
+			}
+
+			for i, c := range global {
+				rgb := color.NRGBAModel.Convert(c).(color.NRGBA)
+				if rgb.A == 0 {
+					rgb = color.NRGBA{}
+				}
+
+				if actual := table[i*3 : i*3+3]; actual[0] != rgb.R || actual[1] != rgb.G || actual[2] != rgb.B {
+					innerT.Errorf("Color %d - expected %v but got %v", i, rgb, actual)
+				}
+			}
+
+			gplPath := filepath.Join(dir, "palette.gpl")
+			if err := run([]string{"-threads", "1", "-export_palette", gplPath, input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			gpl, err := ioutil.ReadFile(gplPath)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			if !strings.HasPrefix(string(gpl), "GIMP Palette\nName: palette\n") {
+				innerT.Errorf("Expected a GIMP palette but got %q", gpl)
+			}
+
+			if err := run([]string{"-export_palette", filepath.Join(dir, "palette.txt"), input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error")
+			}
+		},
+	)
+
+	t.Run(
+		"Sequence",
+		func(innerT *testing.T) {
+			sequenceInput := filepath.Join(dir, "sequence.gif")
+			if err := encodeOutput(sequenceInput, newTestGIF(3, 4, 2), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			sequenceDir := filepath.Join(dir, "sequence")
+			if err := run([]string{"-threads", "1", "-sequence", sequenceInput, sequenceDir}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for _, name := range []string{"frame_001.png", "frame_002.png", "frame_003.png"} {
+				file, err := os.Open(filepath.Join(sequenceDir, name))
+				if err != nil {
+					innerT.Fatalf("Expected %s to be written but got %v", name, err)
+				}
+
+				frame, err := png.Decode(file)
+				file.Close()
+				if err != nil {
+					innerT.Fatalf("Error decoding %s: %v", name, err)
+				}
+
+				if frame.Bounds() != image.Rect(0, 0, 4, 2) {
+					innerT.Errorf("Expected %v but got %v", image.Rect(0, 0, 4, 2), frame.Bounds())
+				}
+
+				if _, ok := frame.(*image.Paletted); ok {
+					innerT.Errorf("Expected a full color PNG but got a paletted one")
+				}
+			}
+
+			if _, err := os.Stat(filepath.Join(sequenceDir, "frame_004.png")); !os.IsNotExist(err) {
+				innerT.Errorf("Expected no fourth frame but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Comment",
+		func(innerT *testing.T) {
+			commented := filepath.Join(dir, "commented.gif")
+			if err := run([]string{"-threads", "1", "-comment", "made for the party", "-gradient", "ff0000,0000ff", input, commented}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			b, err := ioutil.ReadFile(commented)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			expected := "made for the party\nrainbowgif dev, gradient #ff0000,#0000ff"
+			if !bytes.Contains(b, []byte(expected)) {
+				innerT.Errorf("Expected %q in the output", expected)
+			}
+
+			err = run([]string{"-threads", "1", "-comment", "nope", input, filepath.Join(dir, "commented.png")}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "only works with GIF output") {
+				innerT.Errorf("Expected a GIF output error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Dry run",
+		func(innerT *testing.T) {
+			dryOutput := filepath.Join(dir, "dry.gif")
+			if err := run([]string{"-threads", "1", "-dry_run", input, dryOutput}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if _, err := os.Stat(dryOutput); !os.IsNotExist(err) {
+				innerT.Errorf("Expected no file to be written but got %v", err)
+			}
+
+			err := run([]string{"-threads", "1", "-dry_run", "-gradient", "ff0000,zzz", input, dryOutput}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), `Invalid color "zzz"`) {
+				innerT.Errorf("Expected an invalid color error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Background",
+		func(innerT *testing.T) {
+			if err := run([]string{"-threads", "1", "-background", "ffffff", input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			err := run([]string{"-threads", "1", "-background", "ffffff", "-transparent", input, output}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+				innerT.Errorf("Expected a mutually exclusive error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Mask",
+		func(innerT *testing.T) {
+			mask := filepath.Join(dir, "mask.png")
+			file, err := os.Create(mask)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			err = png.Encode(file, image.NewGray(image.Rect(0, 0, 4, 4)))
+			file.Close()
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			if err := run([]string{"-threads", "1", "-mask", mask, input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			err = run([]string{"-threads", "1", "-mask", filepath.Join(dir, "missing.png"), input, output}, nil, nil)
+			if !errors.Is(err, os.ErrNotExist) {
+				innerT.Errorf("Expected %v but got %v", os.ErrNotExist, err)
+			}
+		},
+	)
+
+	t.Run(
+		"Missing file",
+		func(innerT *testing.T) {
+			err := run([]string{"-threads", "1", filepath.Join(dir, "missing.gif"), output}, nil, nil)
+			if !errors.Is(err, os.ErrNotExist) {
+				innerT.Errorf("Expected %v but got %v", os.ErrNotExist, err)
+			}
+		},
+	)
+
+	t.Run(
+		"Bad thread count",
+		func(innerT *testing.T) {
+			err := run([]string{"-threads", "-1", input, output}, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), "Thread count must be at least 1") {
+				innerT.Errorf("Expected a thread count error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"FPS overrides delays",
+		func(innerT *testing.T) {
+			if err := run([]string{"-threads", "1", "-fps", "25", input, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			file, err := os.Open(output)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			defer file.Close()
+
+			decoded, err := gif.DecodeAll(file)
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			for _, delay := range decoded.Delay {
+				if delay != 4 {
+					innerT.Errorf("Expected %v but got %v", 4, delay)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Frame range",
+		func(innerT *testing.T) {
+			long := filepath.Join(dir, "ten.gif")
+			if err := encodeOutput(long, newTestGIF(10, 4, 4), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			if err := run([]string{"-threads", "1", "-frame_range", "2:5", long, output}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			file, err := os.Open(output)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			defer file.Close()
+
+			decoded, err := gif.DecodeAll(file)
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if len(decoded.Image) != 3 {
+				innerT.Errorf("Expected %v but got %v", 3, len(decoded.Image))
+			}
+
+			if err := run([]string{"-threads", "1", "-frame_range", "8:12", long, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Montage",
+		func(innerT *testing.T) {
+			montageInput := filepath.Join(dir, "five.gif")
+			if err := encodeOutput(montageInput, newTestGIF(5, 4, 2), ""); err != nil {
+				innerT.Fatal(err)
+			}
+
+			montageOutput := filepath.Join(dir, "montage.png")
+			if err := run([]string{"-threads", "1", "-montage", "3", montageInput, montageOutput}, nil, nil); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			file, err := os.Open(montageOutput)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			defer file.Close()
+
+			config, err := png.DecodeConfig(file)
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if config.Width != 3*4 || config.Height != 2*2 {
+				innerT.Errorf("Expected %v but got %v", "12x4", fmt.Sprintf("%dx%d", config.Width, config.Height))
+			}
+
+			sidecar, err := ioutil.ReadFile(filepath.Join(dir, "montage.json"))
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			var metadata montageMetadata
+			if err := json.Unmarshal(sidecar, &metadata); err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			expected := montageMetadata{Frames: 5, Columns: 3, Rows: 2, CellWidth: 4, CellHeight: 2}
+			metadata.Delay = nil
+			if !reflect.DeepEqual(metadata, expected) {
+				innerT.Errorf("Expected %v but got %v", expected, metadata)
+			}
+		},
+	)
+
+	t.Run(
+		"Montage needs a PNG",
+		func(innerT *testing.T) {
+			if err := run([]string{"-threads", "1", "-montage", "3", input, output}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Missing arguments",
+		func(innerT *testing.T) {
+			if err := run([]string{input}, nil, nil); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
+
+func TestResolveThreads(t *testing.T) {
+	t.Run(
+		"Explicit values are kept",
+		func(innerT *testing.T) {
+			if actual := resolveThreads(3); actual != 3 {
+				innerT.Errorf("Expected %v but got %v", 3, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"0 is automatic",
+		func(innerT *testing.T) {
+			actual := resolveThreads(0)
+			if actual < 1 || actual > runtime.NumCPU() || actual > runtime.GOMAXPROCS(0) {
+				innerT.Errorf("Expected between %v and %v but got %v", 1, runtime.NumCPU(), actual)
+			}
+		},
+	)
+}
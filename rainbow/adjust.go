@@ -0,0 +1,198 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* Functions that tweak colors around a blend
+ * each wraps a blendFunc so the palette cache and workers don't need to know about them
+ */
+
+/* blends in linear light instead of gamma encoded sRGB, then encodes the result again
+ * mixing in sRGB makes the midpoint of two bright colors too dark, most visibly with screen and soft light
+ * only meant for the per channel modes, color and hue already work in HCL
+ */
+func linearBlend(blend blendFunc) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		topR, topG, topB := top.Clamped().LinearRgb()
+		bottomR, bottomG, bottomB := bottom.Clamped().LinearRgb()
+
+		blended := blend(
+			colorful.Color{R: topR, G: topG, B: topB},
+			colorful.Color{R: bottomR, G: bottomG, B: bottomB},
+		)
+
+		return colorful.LinearRgb(blended.R, blended.G, blended.B).Clamped()
+	}
+}
+
+/* raises every channel of the overlay to the power of gamma before blending
+ * above 1 darkens the gradient and below 1 brightens it, 1 leaves it as is
+ */
+func gammaBlend(blend blendFunc, gamma float64) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		return blend(gammaColor(top, gamma), bottom)
+	}
+}
+
+func gammaColor(c colorful.Color, gamma float64) colorful.Color {
+	c = c.Clamped()
+
+	return colorful.Color{
+		R: math.Pow(c.R, gamma),
+		G: math.Pow(c.G, gamma),
+		B: math.Pow(c.B, gamma),
+	}
+}
+
+/* scales the saturation and lightness of every blended color in HSL
+ * 1 leaves them as is, 0 saturation gives grays and 0 brightness black
+ */
+func adjustBlend(blend blendFunc, saturation float64, brightness float64) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		return adjustColor(blend(top, bottom), saturation, brightness)
+	}
+}
+
+func adjustColor(c colorful.Color, saturation float64, brightness float64) colorful.Color {
+	hue, s, l := c.Clamped().Hsl()
+
+	s = math.Min(math.Max(s*saturation, 0), 1)
+	l = math.Min(math.Max(l*brightness, 0), 1)
+
+	return colorful.Hsl(hue, s, l).Clamped()
+}
+
+/* replaces every source color with a gray of the same luminance, so the gradient washes over
+ * a black and white picture instead of mixing with the original colors
+ * pixels are shared with the source, only the palettes are new
+ */
+func desaturateFrames(frames []*image.Paletted) []*image.Paletted {
+	return recolorFrames(frames, desaturatePixel)
+}
+
+// replaces every source color with its negative, so the gradient goes over a negative of the picture
+func invertFrames(frames []*image.Paletted) []*image.Paletted {
+	return recolorFrames(frames, invertPixel)
+}
+
+// composites every partly transparent color over matte, so antialiased edges fade into it instead of fringing once GIF drops their alpha
+func matteFrames(frames []*image.Paletted, matte colorful.Color) []*image.Paletted {
+	return recolorFrames(frames, func(pixel color.Color) color.Color {
+		return mattePixel(pixel, matte)
+	})
+}
+
+// runs recolor over every palette color, sharing the pixels with the source
+func recolorFrames(frames []*image.Paletted, recolor func(pixel color.Color) color.Color) []*image.Paletted {
+	// frames sharing a palette keep sharing it so the palette cache still works for them, keyed the same way it is
+	palettes := make(map[paletteKey]color.Palette)
+	recolored := make([]*image.Paletted, len(frames))
+
+	for i, frame := range frames {
+		key := newPaletteKey(frame.Palette, colorful.Color{}, 0)
+		palette, ok := palettes[key]
+		if !ok {
+			palette = make(color.Palette, len(frame.Palette))
+			for j, c := range frame.Palette {
+				palette[j] = recolor(c)
+			}
+
+			palettes[key] = palette
+		}
+
+		recolored[i] = &image.Paletted{
+			Pix:     frame.Pix,
+			Stride:  frame.Stride,
+			Rect:    frame.Rect,
+			Palette: palette,
+		}
+	}
+
+	return recolored
+}
+
+// 255 minus every channel in sRGB, keeping the alpha, transparent colors are returned as is
+func invertPixel(pixel color.Color) color.Color {
+	c := color.NRGBAModel.Convert(pixel).(color.NRGBA)
+	if c.A == 0 {
+		return pixel
+	}
+
+	return color.NRGBA{R: 255 - c.R, G: 255 - c.G, B: 255 - c.B, A: c.A}
+}
+
+// a gray with the same luminance (L in Lab), keeping the alpha
+func desaturatePixel(pixel color.Color) color.Color {
+	convertedPixel, alpha, ok := straightColor(pixel)
+
+	if !ok {
+		return pixel
+	}
+
+	luminance, _, _ := convertedPixel.Clamped().Lab()
+	gray, _, _ := colorful.Lab(luminance, 0, 0).Clamped().RGB255()
+
+	return color.NRGBA{gray, gray, gray, alpha}
+}
+
+// how far apart in 8 bit steps each channel can be for preserveBlend to snap
+const preserveTolerance = 2
+
+/* returns the overlay as is when the bottom color is already within preserveTolerance of it
+ * blending and rounding to 8 bits again nudges such colors a little every time, so running over its own output drifts
+ */
+func preserveBlend(blend blendFunc) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		topR, topG, topB := top.Clamped().RGB255()
+		bottomR, bottomG, bottomB := bottom.Clamped().RGB255()
+
+		if channelClose(topR, bottomR) && channelClose(topG, bottomG) && channelClose(topB, bottomB) {
+			return colorful.Color{R: float64(topR) / 255, G: float64(topG) / 255, B: float64(topB) / 255}
+		}
+
+		return blend(top, bottom)
+	}
+}
+
+/* returns the bottom color as is when every channel is within tolerance of 0 or every channel is within tolerance of 255
+ * tinting the black outlines and white backgrounds of logos muddies them, everything else is blended as usual
+ */
+func blackWhiteBlend(blend blendFunc, tolerance uint8) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		r, g, b := bottom.Clamped().RGB255()
+
+		if (r <= tolerance && g <= tolerance && b <= tolerance) || (r >= 255-tolerance && g >= 255-tolerance && b >= 255-tolerance) {
+			return bottom
+		}
+
+		return blend(top, bottom)
+	}
+}
+
+func channelClose(a uint8, b uint8) bool {
+	if a > b {
+		return a-b <= preserveTolerance
+	}
+
+	return b-a <= preserveTolerance
+}
+
+// the color over matte in sRGB the way viewers draw it, fully transparent and fully opaque colors are returned as is
+func mattePixel(pixel color.Color, matte colorful.Color) color.Color {
+	_, _, _, alpha := pixel.RGBA()
+	convertedPixel, _, ok := straightColor(pixel)
+
+	if !ok || alpha == 0xffff {
+		return pixel
+	}
+
+	composited := convertedPixel.Clamped().BlendRgb(matte.Clamped(), 1-float64(alpha)/0xffff)
+	r, g, b := composited.RGB255()
+
+	return color.NRGBA{r, g, b, 255}
+}
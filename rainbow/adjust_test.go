@@ -0,0 +1,396 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestAdjustBlend(t *testing.T) {
+	top := colorful.Color{R: 0.2, G: 0.4, B: 0.9}
+	bottom := colorful.Color{R: 0.6, G: 0.5, B: 0.3}
+	blended := blendColor(top, bottom)
+
+	t.Run(
+		"1 is unchanged",
+		func(innerT *testing.T) {
+			actual := adjustBlend(blendColor, 1, 1)(top, bottom)
+			if !actual.AlmostEqualRgb(blended) {
+				innerT.Errorf("Expected %v but got %v", blended, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"No saturation is gray",
+		func(innerT *testing.T) {
+			actual := adjustBlend(blendColor, 0, 1)(top, bottom)
+			if actual.R != actual.G || actual.G != actual.B {
+				innerT.Errorf("Expected a gray but got %v", actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Double saturation keeps the hue",
+		func(innerT *testing.T) {
+			actual := adjustBlend(blendColor, 2, 1)(top, bottom)
+
+			expectedHue, expectedSaturation, _ := blended.Hsl()
+			actualHue, actualSaturation, _ := actual.Hsl()
+
+			if math.Abs(actualHue-expectedHue) > 0.5 {
+				innerT.Errorf("Expected %v but got %v", expectedHue, actualHue)
+			}
+
+			if actualSaturation <= expectedSaturation {
+				innerT.Errorf("Expected more than %v but got %v", expectedSaturation, actualSaturation)
+			}
+		},
+	)
+
+	t.Run(
+		"Brightness scales lightness",
+		func(innerT *testing.T) {
+			_, _, expected := blended.Hsl()
+			_, _, actual := adjustBlend(blendColor, 1, 0.5)(top, bottom).Hsl()
+
+			if math.Abs(actual-expected*0.5) > 0.01 {
+				innerT.Errorf("Expected %v but got %v", expected*0.5, actual)
+			}
+		},
+	)
+}
+
+func TestRainbowifySaturation(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Saturation = 0
+
+	out, err := Rainbowify(newTestGIF(3, 2, 2), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for i, frame := range out.Image {
+		for _, c := range frame.Palette {
+			r, g, b, a := c.RGBA()
+			if a != 0 && (r != g || g != b) {
+				t.Errorf("Frame %d - expected a gray but got %v", i, c)
+			}
+		}
+	}
+}
+
+func TestDesaturateFirst(t *testing.T) {
+	// different hues with the same luminance
+	first, second := colorful.Hcl(30, 0.3, 0.6).Clamped(), colorful.Hcl(200, 0.3, 0.6).Clamped()
+	palette := color.Palette{
+		color.NRGBAModel.Convert(first),
+		color.NRGBAModel.Convert(second),
+	}
+	frame := image.NewPaletted(image.Rect(0, 0, 2, 1), palette)
+	frame.Pix[1] = 1
+
+	opts := DefaultOptions()
+	opts.DesaturateFirst = true
+	opts.Opacity = 0.5
+
+	out, err := Rainbowify(&gif.GIF{Image: []*image.Paletted{frame, frame}}, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Equal luminance gives equal colors",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				if frame.Palette[0] != frame.Palette[1] {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, frame.Palette[0], frame.Palette[1])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Source is untouched",
+		func(innerT *testing.T) {
+			if frame.Palette[0] == frame.Palette[1] {
+				innerT.Errorf("Expected %v and %v to differ", frame.Palette[0], frame.Palette[1])
+			}
+		},
+	)
+}
+
+func TestRecolorFramesShortened(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		color.RGBA{A: 255},
+		color.RGBA{R: 255, A: 255},
+	}
+
+	// the first frame's palette is a shorter slice of the second's, starting at the same entry
+	short := image.NewPaletted(image.Rect(0, 0, 2, 1), palette[:2])
+	full := image.NewPaletted(image.Rect(0, 0, 2, 1), palette)
+
+	frames := []*image.Paletted{short, full}
+	recolored := recolorFrames(frames, func(pixel color.Color) color.Color { return pixel })
+	for i, frame := range frames {
+		if !reflect.DeepEqual(recolored[i].Palette, frame.Palette) {
+			t.Errorf("Frame %d - expected %v but got %v", i, frame.Palette, recolored[i].Palette)
+		}
+	}
+}
+
+func TestLinearBlend(t *testing.T) {
+	red := colorful.Color{R: 1, G: 0, B: 0}
+	green := colorful.Color{R: 0, G: 1, B: 0}
+
+	// half of the top over the bottom
+	mix := func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		result, _ := blendNormal(top, 0.5, bottom, 1)
+		return result
+	}
+
+	t.Run(
+		"Midpoint is brighter",
+		func(innerT *testing.T) {
+			gamma := mix(red, green)
+			linear := linearBlend(mix)(red, green)
+
+			gammaLuminance, _, _ := gamma.Lab()
+			linearLuminance, _, _ := linear.Lab()
+			if linearLuminance <= gammaLuminance {
+				innerT.Errorf("Expected more than %v but got %v", gammaLuminance, linearLuminance)
+			}
+		},
+	)
+
+	t.Run(
+		"Ends are unchanged",
+		func(innerT *testing.T) {
+			actual := linearBlend(blendOpaque)(red, green)
+			if !actual.AlmostEqualRgb(red) {
+				innerT.Errorf("Expected %v but got %v", red, actual)
+			}
+		},
+	)
+}
+
+func TestGammaBlend(t *testing.T) {
+	gray := colorful.Color{R: 0.5, G: 0.5, B: 0.5}
+	white := colorful.Color{R: 1, G: 1, B: 1}
+
+	cases := []struct {
+		name     string
+		gamma    float64
+		expected float64
+	}{
+		{name: "No op", gamma: 1, expected: 0.5},
+		{name: "Darker", gamma: 2.2, expected: 0.2176},
+		{name: "Brighter", gamma: 0.5, expected: 0.7071},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				actual := gammaBlend(blendOpaque, c.gamma)(gray, white)
+				for _, channel := range []float64{actual.R, actual.G, actual.B} {
+					if math.Abs(channel-c.expected) > 0.001 {
+						innerT.Errorf("Expected %v but got %v", c.expected, actual)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestPreserveBlend(t *testing.T) {
+	top := colorful.Color{R: 0.2, G: 0.4, B: 0.9}
+
+	t.Run(
+		"Close colors snap to the overlay",
+		func(innerT *testing.T) {
+			bottom := colorful.Color{R: 0.2 + 1.0/255, G: 0.4, B: 0.9 - 2.0/255}
+			expectedR, expectedG, expectedB := top.RGB255()
+			actualR, actualG, actualB := preserveBlend(blendColor)(top, bottom).RGB255()
+			if actualR != expectedR || actualG != expectedG || actualB != expectedB {
+				innerT.Errorf("Expected %v but got %v", []uint8{expectedR, expectedG, expectedB}, []uint8{actualR, actualG, actualB})
+			}
+		},
+	)
+
+	t.Run(
+		"Other colors are blended",
+		func(innerT *testing.T) {
+			bottom := colorful.Color{R: 0.6, G: 0.5, B: 0.3}
+			expected := blendColor(top, bottom)
+			actual := preserveBlend(blendColor)(top, bottom)
+			if actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+		},
+	)
+}
+
+func TestInvert(t *testing.T) {
+	black := color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+	t.Run(
+		"Black becomes white",
+		func(innerT *testing.T) {
+			if actual := invertPixel(black); actual != white {
+				innerT.Errorf("Expected %v but got %v", white, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Transparent is kept",
+		func(innerT *testing.T) {
+			transparent := color.NRGBA{R: 10, G: 20, B: 30, A: 0}
+			if actual := invertPixel(transparent); actual != transparent {
+				innerT.Errorf("Expected %v but got %v", transparent, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Blends over the inverted color",
+		func(innerT *testing.T) {
+			overlay := colorful.Color{R: 0.2, G: 0.4, B: 0.9}
+			frame := image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{black})
+
+			opts := DefaultOptions()
+			opts.Colors = []colorful.Color{overlay, overlay}
+			opts.Invert = true
+			opts.Opacity = 0.5
+
+			out, err := Rainbowify(&gif.GIF{Image: []*image.Paletted{frame}}, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := color.NRGBAModel.Convert(blendPixel(white, overlay, blendColor, 0.5))
+			if actual := color.NRGBAModel.Convert(out.Image[0].Palette[0]); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+		},
+	)
+}
+
+func TestRainbowifyPreserveBlackWhite(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 0, G: 0, B: 0, A: 255},
+		color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255},
+		// within the default tolerance of black and white
+		color.RGBA{R: 5, G: 3, B: 8, A: 255},
+		color.RGBA{R: 250, G: 252, B: 247, A: 255},
+	}
+	src := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 2), palette)},
+		Delay: []int{10},
+	}
+
+	opts := DefaultOptions()
+	// multiply would turn white red and leave black alone, so white shows the check as well
+	opts.Blend = "multiply"
+	opts.Colors = []colorful.Color{{R: 1, G: 0, B: 0}}
+	opts.PreserveBlackWhite = true
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	blended := out.Image[0].Palette
+	for _, i := range []int{0, 1, 3, 4} {
+		if expected, actual := color.RGBAModel.Convert(palette[i]), color.RGBAModel.Convert(blended[i]); actual != expected {
+			t.Errorf("Expected %v but got %v", expected, actual)
+		}
+	}
+
+	if gray := color.RGBAModel.Convert(blended[2]).(color.RGBA); gray.G != 0 || gray.B != 0 {
+		t.Errorf("Expected %v to be blended", gray)
+	}
+
+	opts.BlackWhiteTolerance = 0
+	out, err = Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if nearWhite := color.RGBAModel.Convert(out.Image[0].Palette[4]).(color.RGBA); nearWhite.G != 0 {
+		t.Errorf("Expected %v to be blended without a tolerance", nearWhite)
+	}
+}
+
+func TestMatte(t *testing.T) {
+	white := colorful.Color{R: 1, G: 1, B: 1}
+
+	cases := []struct {
+		name     string
+		pixel    color.Color
+		expected color.Color
+	}{
+		{name: "Half red over white is pink", pixel: color.NRGBA{R: 255, G: 0, B: 0, A: 128}, expected: color.NRGBA{R: 255, G: 127, B: 127, A: 255}},
+		{name: "Opaque is kept", pixel: color.NRGBA{R: 255, G: 0, B: 0, A: 255}, expected: color.NRGBA{R: 255, G: 0, B: 0, A: 255}},
+		{name: "Transparent is kept", pixel: color.NRGBA{R: 10, G: 20, B: 30, A: 0}, expected: color.NRGBA{R: 10, G: 20, B: 30, A: 0}},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				if actual := mattePixel(c.pixel, white); actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Applied after blending",
+		func(innerT *testing.T) {
+			palette := color.Palette{
+				color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 255},
+				color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 128},
+				color.NRGBA{R: 0, G: 0, B: 0, A: 0},
+			}
+			src := &gif.GIF{
+				Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 2, 2), palette)},
+				Delay: []int{10},
+			}
+
+			opts := DefaultOptions()
+			opts.Matte = &white
+
+			out, err := Rainbowify(src, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			blended := out.Image[0].Palette
+			if _, _, _, alpha := blended[1].RGBA(); alpha != 0xffff {
+				innerT.Errorf("Expected %v but got %v", 0xffff, alpha)
+			}
+
+			// the blended color is what goes over the matte, not the source's gray
+			expected := mattePixel(blendPixel(palette[1], opts.Colors[0], blendColor, 1), white)
+			if actual := color.NRGBAModel.Convert(blended[1]); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+
+			if _, _, _, alpha := blended[2].RGBA(); alpha != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, alpha)
+			}
+		},
+	)
+}
@@ -0,0 +1,42 @@
+package rainbow
+
+import (
+	"image/color"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* splits a color into its straight (not premultiplied) sRGB color and its alpha in 8 bits
+ * colorful.MakeColor divides the premultiplied channels by the alpha in integers, which rounds the color down,
+ * by a whole 8 bit step for some colors with a low alpha, so NRGBA colors are read as they are and the rest is divided in floating point
+ * false for fully transparent colors, which have no color to speak of
+ */
+func straightColor(pixel color.Color) (colorful.Color, uint8, bool) {
+	switch c := pixel.(type) {
+	case color.NRGBA:
+		if c.A == 0 {
+			return colorful.Color{}, 0, false
+		}
+
+		return colorful.Color{R: float64(c.R) / 0xff, G: float64(c.G) / 0xff, B: float64(c.B) / 0xff}, c.A, true
+	case color.NRGBA64:
+		if c.A == 0 {
+			return colorful.Color{}, 0, false
+		}
+
+		return colorful.Color{R: float64(c.R) / 0xffff, G: float64(c.G) / 0xffff, B: float64(c.B) / 0xffff}, alpha8(uint32(c.A)), true
+	}
+
+	r, g, b, a := pixel.RGBA()
+	if a == 0 {
+		return colorful.Color{}, 0, false
+	}
+
+	alpha := float64(a)
+	return colorful.Color{R: float64(r) / alpha, G: float64(g) / alpha, B: float64(b) / alpha}, alpha8(a), true
+}
+
+// rounds a 16 bit alpha to 8 bits, shifting would turn 0xff00 into 255 and make a partly transparent color opaque
+func alpha8(alpha uint32) uint8 {
+	return uint8((alpha*0xff + 0x7fff) / 0xffff)
+}
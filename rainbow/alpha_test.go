@@ -0,0 +1,118 @@
+package rainbow
+
+import (
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestStraightColor(t *testing.T) {
+	t.Run(
+		"Every NRGBA color comes back as is",
+		func(innerT *testing.T) {
+			for alpha := 1; alpha < 256; alpha++ {
+				for value := 0; value < 256; value++ {
+					pixel := color.NRGBA{R: uint8(value), G: uint8(255 - value), B: uint8(value / 2), A: uint8(alpha)}
+
+					converted, actualAlpha, ok := straightColor(pixel)
+					if !ok {
+						innerT.Fatalf("Expected %v to have a color", pixel)
+					}
+
+					r, g, b := converted.RGB255()
+					if actual := (color.NRGBA{R: r, G: g, B: b, A: actualAlpha}); actual != pixel {
+						innerT.Fatalf("Expected %v but got %v", pixel, actual)
+					}
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Premultiplied colors are divided out",
+		func(innerT *testing.T) {
+			// 0x4000 of red under an alpha of 0x8000 is half of the way to full red
+			converted, alpha, ok := straightColor(color.RGBA64{R: 0x4000, A: 0x8000})
+			if !ok {
+				innerT.Fatalf("Expected a color")
+			}
+
+			if math.Abs(converted.R-0.5) > 1e-9 || converted.G != 0 || converted.B != 0 {
+				innerT.Errorf("Expected %v but got %v", colorful.Color{R: 0.5}, converted)
+			}
+
+			if alpha != 128 {
+				innerT.Errorf("Expected %v but got %v", 128, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Alpha is rounded",
+		func(innerT *testing.T) {
+			if _, alpha, _ := straightColor(color.NRGBA64{R: 0xffff, A: 0xff00}); alpha != 254 {
+				innerT.Errorf("Expected %v but got %v", 254, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Transparent has no color",
+		func(innerT *testing.T) {
+			for _, pixel := range []color.Color{color.NRGBA{R: 10}, color.NRGBA64{R: 10}, color.RGBA{}} {
+				if _, _, ok := straightColor(pixel); ok {
+					innerT.Errorf("Expected %v to have no color", pixel)
+				}
+			}
+		},
+	)
+}
+
+func TestBlendPixelStraightAlpha(t *testing.T) {
+	top := colorful.Color{R: 1, G: 0.5, B: 0.2}
+
+	cases := []struct {
+		name  string
+		pixel color.Color
+	}{
+		// dividing the premultiplied red back out in integers would give 199
+		{name: "Barely visible", pixel: color.NRGBA{R: 200, G: 100, B: 50, A: 1}},
+		{name: "Faint", pixel: color.NRGBA{R: 37, G: 211, B: 143, A: 3}},
+		{name: "Half", pixel: color.NRGBA{R: 200, G: 100, B: 50, A: 128}},
+		{name: "Opaque", pixel: color.NRGBA{R: 200, G: 100, B: 50, A: 255}},
+		{name: "Premultiplied", pixel: color.RGBA{R: 50, G: 25, B: 10, A: 64}},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				// multiply by hand in straight alpha, keeping the alpha
+				straight := color.NRGBAModel.Convert(c.pixel).(color.NRGBA)
+				if premultiplied, ok := c.pixel.(color.RGBA); ok {
+					a := float64(premultiplied.A)
+					straight = color.NRGBA{
+						R: uint8(math.Round(float64(premultiplied.R) * 255 / a)),
+						G: uint8(math.Round(float64(premultiplied.G) * 255 / a)),
+						B: uint8(math.Round(float64(premultiplied.B) * 255 / a)),
+						A: premultiplied.A,
+					}
+				}
+
+				expected := color.NRGBA{
+					R: uint8(math.Round(float64(straight.R) * top.R)),
+					G: uint8(math.Round(float64(straight.G) * top.G)),
+					B: uint8(math.Round(float64(straight.B) * top.B)),
+					A: straight.A,
+				}
+
+				actual := color.NRGBAModel.Convert(blendPixel(c.pixel, top, blendMultiply, 1)).(color.NRGBA)
+				if actual != expected {
+					innerT.Errorf("Expected %v but got %v", expected, actual)
+				}
+			},
+		)
+	}
+}
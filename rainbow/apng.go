@@ -0,0 +1,163 @@
+package rainbow
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+/* EncodeAPNG writes img to w as an animated PNG
+ * every frame is composited onto the full canvas and stored as 8 bit RGBA,
+ * so unlike a GIF partially transparent colors survive
+ */
+func EncodeAPNG(w io.Writer, img *gif.GIF) error {
+	if len(img.Image) == 0 {
+		return ErrNoFrames
+	}
+
+	frames := composite(img)
+	bounds := frames[0].Bounds()
+
+	encoder := apngEncoder{w: w}
+
+	encoder.write([]byte("\x89PNG\r\n\x1a\n"))
+
+	header := make([]byte, 13)
+	binary.BigEndian.PutUint32(header[0:4], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(header[4:8], uint32(bounds.Dy()))
+	// 8 bit depth, truecolor with alpha, deflate, adaptive filtering, no interlacing
+	header[8] = 8
+	header[9] = 6
+	encoder.writeChunk("IHDR", header)
+
+	control := make([]byte, 8)
+	binary.BigEndian.PutUint32(control[0:4], uint32(len(frames)))
+	binary.BigEndian.PutUint32(control[4:8], playCount(img.LoopCount))
+	encoder.writeChunk("acTL", control)
+
+	for i, frame := range frames {
+		var delay int
+		if i < len(img.Delay) {
+			delay = img.Delay[i]
+		}
+
+		frameControl := make([]byte, 26)
+		binary.BigEndian.PutUint32(frameControl[0:4], encoder.nextSequence())
+		binary.BigEndian.PutUint32(frameControl[4:8], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(frameControl[8:12], uint32(bounds.Dy()))
+		// offsets stay 0, every frame covers the canvas
+		binary.BigEndian.PutUint16(frameControl[20:22], uint16(delay))
+		binary.BigEndian.PutUint16(frameControl[22:24], 100)
+		// dispose op none and blend op source, each frame replaces the last one completely
+		encoder.writeChunk("fcTL", frameControl)
+
+		data, err := apngFrameData(frame)
+		if err != nil {
+			return err
+		}
+
+		// the first frame doubles as the still image for viewers without APNG support
+		if i == 0 {
+			encoder.writeChunk("IDAT", data)
+			continue
+		}
+
+		sequenced := make([]byte, 4+len(data))
+		binary.BigEndian.PutUint32(sequenced[0:4], encoder.nextSequence())
+		copy(sequenced[4:], data)
+		encoder.writeChunk("fdAT", sequenced)
+	}
+
+	encoder.writeChunk("IEND", nil)
+
+	return encoder.err
+}
+
+/* maps gif.GIF.LoopCount onto the play count used by APNG and WebP
+ * 0 loops forever in all of them, but a GIF's count is extra repeats while the others count every play
+ */
+func playCount(loopCount int) uint32 {
+	switch {
+	case loopCount == 0:
+		return 0
+	case loopCount < 0:
+		return 1
+	default:
+		return uint32(loopCount) + 1
+	}
+}
+
+// the zlib compressed scanlines of a frame, unfiltered and non premultiplied
+func apngFrameData(frame *image.RGBA) ([]byte, error) {
+	bounds := frame.Bounds()
+
+	var buf bytes.Buffer
+	compressor := zlib.NewWriter(&buf)
+	row := make([]byte, 1+4*bounds.Dx())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		// the first byte of every row is the filter type, 0 is none
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(frame.RGBAAt(x, y)).(color.NRGBA)
+			offset := 1 + 4*(x-bounds.Min.X)
+			row[offset] = c.R
+			row[offset+1] = c.G
+			row[offset+2] = c.B
+			row[offset+3] = c.A
+		}
+
+		if _, err := compressor.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := compressor.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writes PNG chunks, holding on to the first error like bufio.Writer
+type apngEncoder struct {
+	w        io.Writer
+	err      error
+	sequence uint32
+}
+
+// fcTL and fdAT chunks share a single sequence starting at 0
+func (encoder *apngEncoder) nextSequence() uint32 {
+	sequence := encoder.sequence
+	encoder.sequence++
+	return sequence
+}
+
+func (encoder *apngEncoder) write(b []byte) {
+	if encoder.err != nil {
+		return
+	}
+
+	_, encoder.err = encoder.w.Write(b)
+}
+
+func (encoder *apngEncoder) writeChunk(name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(data)))
+	copy(header[4:8], name)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	encoder.write(header)
+	encoder.write(data)
+	encoder.write(footer)
+}
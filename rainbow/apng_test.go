@@ -0,0 +1,160 @@
+package rainbow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+type apngChunk struct {
+	name string
+	data []byte
+}
+
+func readAPNGChunks(t *testing.T, b []byte) []apngChunk {
+	if !bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Fatalf("Expected a PNG signature but got %q", b[:8])
+	}
+	b = b[8:]
+
+	var chunks []apngChunk
+	for len(b) > 0 {
+		length := binary.BigEndian.Uint32(b[0:4])
+		chunk := apngChunk{name: string(b[4:8]), data: b[8 : 8+length]}
+		crc := binary.BigEndian.Uint32(b[8+length : 12+length])
+		if crc != crc32.ChecksumIEEE(b[4:8+length]) {
+			t.Fatalf("Chunk %v has a bad CRC", chunk.name)
+		}
+
+		chunks = append(chunks, chunk)
+		b = b[12+length:]
+	}
+
+	return chunks
+}
+
+// rebuilds a frame stored in fdAT chunks as a plain PNG so it can be decoded with image/png
+func decodeAPNGFrame(t *testing.T, header []byte, data []byte) image.Image {
+	var buf bytes.Buffer
+	encoder := apngEncoder{w: &buf}
+	encoder.write([]byte("\x89PNG\r\n\x1a\n"))
+	encoder.writeChunk("IHDR", header)
+	encoder.writeChunk("IDAT", data)
+	encoder.writeChunk("IEND", nil)
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Error decoding frame: %v", err)
+	}
+
+	return img
+}
+
+func TestEncodeAPNG(t *testing.T) {
+	palette := color.Palette{
+		color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+		color.NRGBA{R: 0, G: 0, B: 255, A: 128},
+	}
+	src := &gif.GIF{
+		Image:     make([]*image.Paletted, 3),
+		Delay:     []int{10, 20, 30},
+		Disposal:  []byte{gif.DisposalBackground, gif.DisposalBackground, gif.DisposalBackground},
+		LoopCount: 0,
+	}
+	for i := range src.Image {
+		src.Image[i] = image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+		src.Image[i].Pix[0] = uint8(i % 2)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeAPNG(&buf, src); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	chunks := readAPNGChunks(t, buf.Bytes())
+
+	t.Run(
+		"Frame count and delays",
+		func(innerT *testing.T) {
+			var frames int
+			var delays []int
+			for _, chunk := range chunks {
+				switch chunk.name {
+				case "acTL":
+					frames = int(binary.BigEndian.Uint32(chunk.data[0:4]))
+				case "fcTL":
+					delays = append(delays, int(binary.BigEndian.Uint16(chunk.data[20:22])))
+				}
+			}
+
+			if frames != 3 || len(delays) != 3 {
+				innerT.Errorf("Expected %v but got %v and %v", 3, frames, len(delays))
+			}
+
+			for i, delay := range delays {
+				if delay != src.Delay[i] {
+					innerT.Errorf("Expected %v but got %v", src.Delay[i], delay)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Sample pixels keep their alpha",
+		func(innerT *testing.T) {
+			first, err := png.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			expected := color.NRGBA{R: 255, G: 0, B: 0, A: 255}
+			if actual := color.NRGBAModel.Convert(first.At(0, 0)); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+
+			var header []byte
+			var second []byte
+			for _, chunk := range chunks {
+				if chunk.name == "IHDR" {
+					header = chunk.data
+				}
+
+				// the first fdAT is the second frame, minus its sequence number
+				if chunk.name == "fdAT" && second == nil {
+					second = chunk.data[4:]
+				}
+			}
+
+			frame := decodeAPNGFrame(innerT, header, second)
+			expected = color.NRGBA{R: 0, G: 0, B: 255, A: 128}
+			if actual := color.NRGBAModel.Convert(frame.At(0, 0)); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Play count",
+		func(innerT *testing.T) {
+			cases := []struct {
+				loopCount int
+				expected  uint32
+			}{
+				{loopCount: 0, expected: 0},
+				{loopCount: -1, expected: 1},
+				{loopCount: 2, expected: 3},
+			}
+
+			for _, c := range cases {
+				if actual := playCount(c.loopCount); actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			}
+		},
+	)
+}
@@ -0,0 +1,166 @@
+package rainbow
+
+/* Functions for different blend modes
+ */
+
+import (
+	"errors"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type blendFunc func(top colorful.Color, bottom colorful.Color) colorful.Color
+
+func getBlendFunc(mode string) (blendFunc, error) {
+	switch mode {
+	case "color":
+		return blendColor, nil
+	case "normal":
+		return blendOpaque, nil
+	case "multiply":
+		return blendMultiply, nil
+	case "screen":
+		return blendScreen, nil
+	case "overlay":
+		return blendOverlay, nil
+	case "softlight":
+		return blendSoftLight, nil
+	case "hue":
+		return blendHue, nil
+	default:
+		return nil, errors.New("Invalid blend mode")
+	}
+}
+
+// applies a per channel blend to each of R, G, and B
+func blendChannels(top colorful.Color, bottom colorful.Color, f func(top float64, bottom float64) float64) colorful.Color {
+	top = top.Clamped()
+	bottom = bottom.Clamped()
+
+	result := colorful.Color{
+		R: f(top.R, bottom.R),
+		G: f(top.G, bottom.G),
+		B: f(top.B, bottom.B),
+	}
+
+	return result.Clamped()
+}
+
+// top layer over bottom - most common
+func blendNormal(top colorful.Color, topAlpha float64, bottom colorful.Color, bottomAlpha float64) (colorful.Color, float64) {
+	alphaDelta := (1 - topAlpha) * bottomAlpha
+
+	alpha := alphaDelta + topAlpha
+	red := (alphaDelta*bottom.R + topAlpha*top.R) / alpha
+	green := (alphaDelta*bottom.G + topAlpha*top.G) / alpha
+	blue := (alphaDelta*bottom.B + topAlpha*top.B) / alpha
+
+	result := colorful.Color{R: red, G: green, B: blue}
+
+	return result.Clamped(), alpha
+}
+
+// normal blend with both layers fully opaque
+func blendOpaque(top colorful.Color, bottom colorful.Color) colorful.Color {
+	result, _ := blendNormal(top, 1, bottom, 1)
+
+	return result
+}
+
+// darkens - white is neutral
+func blendMultiply(top colorful.Color, bottom colorful.Color) colorful.Color {
+	return blendChannels(top, bottom, func(t float64, b float64) float64 {
+		return t * b
+	})
+}
+
+// lightens - black is neutral
+func blendScreen(top colorful.Color, bottom colorful.Color) colorful.Color {
+	return blendChannels(top, bottom, func(t float64, b float64) float64 {
+		return 1 - (1-t)*(1-b)
+	})
+}
+
+/* overlay blend
+ * multiplies the darks and screens the lights of the bottom
+ */
+func blendOverlay(top colorful.Color, bottom colorful.Color) colorful.Color {
+	return blendChannels(top, bottom, func(t float64, b float64) float64 {
+		if b < 0.5 {
+			return 2 * t * b
+		}
+
+		return 1 - 2*(1-t)*(1-b)
+	})
+}
+
+/* soft light blend
+ * a gentler overlay - uses the W3C compositing formula
+ */
+func blendSoftLight(top colorful.Color, bottom colorful.Color) colorful.Color {
+	return blendChannels(top, bottom, func(t float64, b float64) float64 {
+		if t <= 0.5 {
+			return b - (1-2*t)*b*(1-b)
+		}
+
+		var d float64
+		if b <= 0.25 {
+			d = ((16*b-12)*b + 4) * b
+		} else {
+			d = math.Sqrt(b)
+		}
+
+		return b + (2*t-1)*(d-b)
+	})
+}
+
+/* color blend
+ * preserves the luma of the bottom
+ * adopts the hue and chroma of the top
+ */
+func blendColor(top colorful.Color, bottom colorful.Color) colorful.Color {
+	topHue, topChroma, _ := top.Hcl()
+	_, _, bottomLuma := bottom.Hcl()
+
+	result := colorful.Hcl(topHue, topChroma, bottomLuma)
+
+	return result.Clamped()
+}
+
+/* color blend that also mixes factor of the top's luma into the bottom's
+ * 0 is the same as blendColor, 0.5 meets halfway between the two lumas, and 1 gives the top color itself
+ */
+func colorFactorBlend(factor float64) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		topHue, topChroma, topLuma := top.Hcl()
+		_, _, bottomLuma := bottom.Hcl()
+
+		result := colorful.Hcl(topHue, topChroma, bottomLuma+(topLuma-bottomLuma)*factor)
+
+		return result.Clamped()
+	}
+}
+
+/* hue blend
+ * preserves the chroma and luma of the bottom
+ * adopts the hue of the top
+ */
+func blendHue(top colorful.Color, bottom colorful.Color) colorful.Color {
+	topHue, _, _ := top.Hcl()
+	_, bottomChroma, bottomLuma := bottom.Hcl()
+
+	result := colorful.Hcl(topHue, bottomChroma, bottomLuma)
+
+	return result.Clamped()
+}
+
+/* opacity
+ * mixes the blended result back toward the bottom in HCL, the same space the blend happens in
+ * 0 yields the bottom untouched and 1 yields the blended result
+ */
+func blendOpacity(blended colorful.Color, bottom colorful.Color, opacity float64) colorful.Color {
+	result := bottom.BlendHcl(blended, opacity)
+
+	return result.Clamped()
+}
@@ -0,0 +1,271 @@
+package rainbow
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestBlendNormal(t *testing.T) {
+	t.Run(
+		"Top alpha 1 - bottom alpha 1",
+		func(innerT *testing.T) {
+			topColor := colorful.Color{R: 0, G: 0, B: 0}
+			topAlpha := 1.0
+			bottomColor := colorful.Color{R: 1, G: 1, B: 1}
+			bottomAlpha := 1.0
+
+			color, alpha := blendNormal(
+				topColor,
+				topAlpha,
+				bottomColor,
+				bottomAlpha,
+			)
+
+			if color != topColor {
+				innerT.Errorf("Expected %v but got %v", topColor, color)
+			}
+
+			if alpha != 1 {
+				innerT.Errorf("Expected %v but got %v", topAlpha, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Top alpha 0 - bottom alpha 1",
+		func(innerT *testing.T) {
+			topColor := colorful.Color{R: 0, G: 0, B: 0}
+			topAlpha := 0.0
+			bottomColor := colorful.Color{R: 1, G: 1, B: 1}
+			bottomAlpha := 1.0
+
+			color, alpha := blendNormal(
+				topColor,
+				topAlpha,
+				bottomColor,
+				bottomAlpha,
+			)
+
+			if color != bottomColor {
+				innerT.Errorf("Expected %v but got %v", bottomColor, color)
+			}
+
+			if alpha != bottomAlpha {
+				innerT.Errorf("Expected %v but got %v", bottomAlpha, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Top alpha 0.5 - bottom alpha 0.5",
+		func(innerT *testing.T) {
+			topColor := colorful.Color{R: 0, G: 0, B: 0}
+			topAlpha := 0.5
+			bottomColor := colorful.Color{R: 1, G: 1, B: 1}
+			bottomAlpha := 0.5
+
+			color, alpha := blendNormal(
+				topColor,
+				topAlpha,
+				bottomColor,
+				bottomAlpha,
+			)
+
+			if color == bottomColor || color == topColor {
+				innerT.Errorf("Expected %v but got %v", nil, color)
+			}
+
+			if alpha != 0.75 {
+				innerT.Errorf("Expected %v but got %v", 1, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Top alpha 0.5 - bottom alpha 1.0",
+		func(innerT *testing.T) {
+			topColor := colorful.Color{R: 0, G: 0, B: 0}
+			topAlpha := 0.5
+			bottomColor := colorful.Color{R: 1, G: 1, B: 1}
+			bottomAlpha := 1.0
+
+			color, alpha := blendNormal(
+				topColor,
+				topAlpha,
+				bottomColor,
+				bottomAlpha,
+			)
+
+			if color == bottomColor || color == topColor {
+				innerT.Errorf("Expected %v but got %v", nil, color)
+			}
+
+			if alpha != 1.0 {
+				innerT.Errorf("Expected %v but got %v", 1, alpha)
+			}
+		},
+	)
+}
+
+func TestBlendOpacity(t *testing.T) {
+	bottom := colorful.Color{R: 0.2, G: 0.4, B: 0.6}
+	blended := colorful.Color{R: 1, G: 0, B: 0}
+
+	t.Run(
+		"Opacity 0",
+		func(innerT *testing.T) {
+			result := blendOpacity(blended, bottom, 0)
+
+			if !result.AlmostEqualRgb(bottom) {
+				innerT.Errorf("Expected %v but got %v", bottom, result)
+			}
+		},
+	)
+
+	t.Run(
+		"Opacity 1",
+		func(innerT *testing.T) {
+			result := blendOpacity(blended, bottom, 1)
+
+			if !result.AlmostEqualRgb(blended) {
+				innerT.Errorf("Expected %v but got %v", blended, result)
+			}
+		},
+	)
+
+	t.Run(
+		"Opacity 0.5",
+		func(innerT *testing.T) {
+			result := blendOpacity(blended, bottom, 0.5)
+
+			if result.AlmostEqualRgb(blended) || result.AlmostEqualRgb(bottom) {
+				innerT.Errorf("Expected a mix but got %v", result)
+			}
+		},
+	)
+}
+
+func TestBlendModes(t *testing.T) {
+	cases := []struct {
+		mode     string
+		top      colorful.Color
+		bottom   colorful.Color
+		expected colorful.Color
+	}{
+		{
+			mode:     "normal",
+			top:      colorful.Color{R: 1, G: 0, B: 0},
+			bottom:   colorful.Color{R: 0, G: 0, B: 1},
+			expected: colorful.Color{R: 1, G: 0, B: 0},
+		},
+		{
+			mode:     "multiply",
+			top:      colorful.Color{R: 0.5, G: 1, B: 0},
+			bottom:   colorful.Color{R: 0.5, G: 0.5, B: 0.5},
+			expected: colorful.Color{R: 0.25, G: 0.5, B: 0},
+		},
+		{
+			mode:     "screen",
+			top:      colorful.Color{R: 0.5, G: 1, B: 0},
+			bottom:   colorful.Color{R: 0.5, G: 0.5, B: 0.5},
+			expected: colorful.Color{R: 0.75, G: 1, B: 0.5},
+		},
+		{
+			mode:     "overlay",
+			top:      colorful.Color{R: 0.5, G: 0.5, B: 1},
+			bottom:   colorful.Color{R: 0.25, G: 0.75, B: 0},
+			expected: colorful.Color{R: 0.25, G: 0.75, B: 0},
+		},
+		{
+			mode:     "softlight",
+			top:      colorful.Color{R: 0.5, G: 0, B: 1},
+			bottom:   colorful.Color{R: 0.25, G: 0.5, B: 0.25},
+			expected: colorful.Color{R: 0.25, G: 0.25, B: 0.5},
+		},
+		{
+			mode:     "hue",
+			top:      colorful.Color{R: 1, G: 0, B: 0},
+			bottom:   colorful.Color{R: 1, G: 1, B: 1},
+			expected: colorful.Color{R: 1, G: 1, B: 1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.mode,
+			func(innerT *testing.T) {
+				blend, err := getBlendFunc(c.mode)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				result := blend(c.top, c.bottom)
+				if !result.AlmostEqualRgb(c.expected) {
+					innerT.Errorf("Expected %v but got %v", c.expected, result)
+				}
+
+				r, g, b := result.RGB255()
+				expectedR, expectedG, expectedB := c.expected.RGB255()
+				if r != expectedR || g != expectedG || b != expectedB {
+					innerT.Errorf(
+						"Expected %v but got %v",
+						[]uint8{expectedR, expectedG, expectedB},
+						[]uint8{r, g, b},
+					)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Invalid mode",
+		func(innerT *testing.T) {
+			_, err := getBlendFunc("dodge")
+			if err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
+
+func TestColorFactorBlend(t *testing.T) {
+	red := colorful.Color{R: 1, G: 0, B: 0}
+	bottom := colorful.Color{R: 0.2, G: 0.6, B: 0.3}
+
+	t.Run(
+		"0 is the color blend",
+		func(innerT *testing.T) {
+			expected := blendColor(red, bottom)
+			if actual := colorFactorBlend(0)(red, bottom); !actual.AlmostEqualRgb(expected) {
+				innerT.Errorf("Expected %v but got %v", expected.Hex(), actual.Hex())
+			}
+		},
+	)
+
+	t.Run(
+		"1 is the overlay",
+		func(innerT *testing.T) {
+			if actual := colorFactorBlend(1)(red, bottom); !actual.AlmostEqualRgb(red) {
+				innerT.Errorf("Expected %v but got %v", red.Hex(), actual.Hex())
+			}
+		},
+	)
+
+	t.Run(
+		"0.5 meets halfway in lightness",
+		func(innerT *testing.T) {
+			// grays have no chroma, so only the lightness moves: L* 21.2 and 82.0 meet at 51.6, which is sRGB 0.483
+			light := colorful.Color{R: 0.8, G: 0.8, B: 0.8}
+			dark := colorful.Color{R: 0.2, G: 0.2, B: 0.2}
+
+			actual := colorFactorBlend(0.5)(light, dark)
+			for _, channel := range []float64{actual.R, actual.G, actual.B} {
+				if math.Abs(channel-0.483) > 0.002 {
+					innerT.Errorf("Expected %v but got %v", 0.483, channel)
+				}
+			}
+		},
+	)
+}
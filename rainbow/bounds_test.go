@@ -0,0 +1,172 @@
+package rainbow
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// a 6x6 GIF whose frames each cover a different part of the canvas
+func newOffsetGIF() *gif.GIF {
+	rects := []image.Rectangle{
+		image.Rect(0, 0, 6, 6),
+		image.Rect(2, 1, 5, 3),
+		image.Rect(3, 3, 6, 6),
+		image.Rect(1, 4, 2, 5),
+	}
+
+	img := &gif.GIF{
+		Image:    make([]*image.Paletted, len(rects)),
+		Delay:    make([]int, len(rects)),
+		Disposal: make([]byte, len(rects)),
+		Config:   image.Config{Width: 6, Height: 6},
+	}
+
+	for i, rect := range rects {
+		palette := color.Palette{
+			color.RGBA{R: uint8(40 * i), G: 200, B: 0, A: 255},
+			color.RGBA{R: 0, G: 0, B: 0, A: 0},
+		}
+		frame := image.NewPaletted(rect, palette)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(j % len(palette))
+		}
+
+		img.Image[i] = frame
+		img.Delay[i] = 10
+	}
+
+	return img
+}
+
+func TestRainbowifyFrameBounds(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(opts *Options)
+	}{
+		{name: "Blend", modify: func(opts *Options) {}},
+		{name: "Looped", modify: func(opts *Options) { opts.LoopCount = 3; opts.Threads = 2 }},
+		{name: "Huerotate", modify: func(opts *Options) { opts.Mode = "huerotate" }},
+		{name: "Spatial", modify: func(opts *Options) { opts.Spatial = "radial" }},
+		{name: "Mask", modify: func(opts *Options) { opts.Mask = image.NewGray(image.Rect(0, 0, 6, 6)) }},
+		{name: "Desaturate first", modify: func(opts *Options) { opts.DesaturateFirst = true }},
+		{name: "Interpolated", modify: func(opts *Options) { opts.InterpolateFrames = 1 }},
+		{name: "Every", modify: func(opts *Options) { opts.Every = 2 }},
+		{name: "Global palette", modify: func(opts *Options) { opts.GlobalPalette = true; opts.Dither = "floyd-steinberg" }},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				src := newOffsetGIF()
+
+				opts := DefaultOptions()
+				c.modify(&opts)
+
+				out, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				// frames are only ever repeated or dropped, so every output frame matches a source frame's bounds
+				for i, frame := range out.Image {
+					found := false
+					for _, srcFrame := range src.Image {
+						if frame.Bounds() == srcFrame.Bounds() {
+							found = true
+						}
+					}
+					if !found {
+						innerT.Errorf("Frame %d - unexpected bounds %v", i, frame.Bounds())
+					}
+				}
+
+				if opts.LoopCount == 1 && opts.InterpolateFrames == 0 && opts.Every == 1 {
+					for i, frame := range out.Image {
+						if frame.Bounds() != src.Image[i].Bounds() {
+							innerT.Errorf("Frame %d - expected %v but got %v", i, src.Image[i].Bounds(), frame.Bounds())
+						}
+					}
+				}
+
+				var buf bytes.Buffer
+				if err := gif.EncodeAll(&buf, out); err != nil {
+					innerT.Errorf("Error encoding: %v", err)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Coalesced frames cover the canvas",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Coalesce = true
+
+			out, err := Rainbowify(newOffsetGIF(), opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for i, frame := range out.Image {
+				if frame.Bounds() != image.Rect(0, 0, 6, 6) {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, image.Rect(0, 0, 6, 6), frame.Bounds())
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Canvas without a config",
+		func(innerT *testing.T) {
+			src := newOffsetGIF()
+			src.Config = image.Config{}
+			src.Image[0] = src.Image[0].SubImage(image.Rect(0, 0, 2, 2)).(*image.Paletted)
+
+			out, err := Rainbowify(src, DefaultOptions())
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if out.Config.Width != 6 || out.Config.Height != 6 {
+				innerT.Errorf("Expected %v but got %v", "6x6", fmt.Sprintf("%dx%d", out.Config.Width, out.Config.Height))
+			}
+
+			if out.Image[0].Bounds() != image.Rect(0, 0, 2, 2) {
+				innerT.Errorf("Expected %v but got %v", image.Rect(0, 0, 2, 2), out.Image[0].Bounds())
+			}
+
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, out); err != nil {
+				innerT.Errorf("Error encoding: %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Encoders",
+		func(innerT *testing.T) {
+			out, err := Rainbowify(newOffsetGIF(), DefaultOptions())
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if err := EncodeAPNG(&bytes.Buffer{}, out); err != nil {
+				innerT.Errorf("Error encoding APNG: %v", err)
+			}
+
+			montage, err := Montage(out, 2)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if montage.Bounds() != image.Rect(0, 0, 12, 12) {
+				innerT.Errorf("Expected %v but got %v", image.Rect(0, 0, 12, 12), montage.Bounds())
+			}
+		},
+	)
+}
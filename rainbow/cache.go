@@ -0,0 +1,69 @@
+package rainbow
+
+import (
+	"image/color"
+	"sync"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* identifies a blended palette
+ * frames decoded from the same GIF often share their palette slice, so the pointer to its first entry is enough
+ */
+type paletteKey struct {
+	palette *color.Color
+	length  int
+	overlay colorful.Color
+	opacity float64
+	// hue rotation in degrees, only used by the huerotate mode
+	rotation float64
+}
+
+func newPaletteKey(palette color.Palette, overlay colorful.Color, opacity float64) paletteKey {
+	key := paletteKey{
+		length:  len(palette),
+		overlay: overlay,
+		opacity: opacity,
+	}
+
+	if len(palette) > 0 {
+		key.palette = &palette[0]
+	}
+
+	return key
+}
+
+func newRotationKey(palette color.Palette, rotation float64, opacity float64) paletteKey {
+	key := newPaletteKey(palette, colorful.Color{}, opacity)
+	key.rotation = rotation
+
+	return key
+}
+
+// blended palettes shared by all the workers of a single run
+type paletteCache struct {
+	mutex    sync.Mutex
+	palettes map[paletteKey]color.Palette
+}
+
+func newPaletteCache() *paletteCache {
+	return &paletteCache{
+		palettes: make(map[paletteKey]color.Palette),
+	}
+}
+
+func (cache *paletteCache) get(key paletteKey) (color.Palette, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	palette, okay := cache.palettes[key]
+	return palette, okay
+}
+
+// palette must not be modified after it's been added
+func (cache *paletteCache) put(key paletteKey, palette color.Palette) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.palettes[key] = palette
+}
@@ -0,0 +1,55 @@
+package rainbow
+
+import (
+	"errors"
+	"image/color"
+	"strings"
+)
+
+// which of red, green, and blue the overlay is allowed to change
+type channelMask [3]bool
+
+var allChannels = channelMask{true, true, true}
+
+// parses any combination of r, g, and b like "rg", an empty string means all of them
+func parseChannels(s string) (channelMask, error) {
+	if len(s) == 0 {
+		return allChannels, nil
+	}
+
+	var channels channelMask
+	for _, channel := range strings.ToLower(s) {
+		index := strings.IndexRune("rgb", channel)
+		if index == -1 {
+			return channelMask{}, errors.New("Invalid channels, must be a combination of r, g, and b")
+		}
+
+		channels[index] = true
+	}
+
+	return channels, nil
+}
+
+/* puts the original value back into every channel the overlay isn't allowed to change
+ * done on the final color rather than inside the blend, mixing by opacity in HCL would leak into the other channels again
+ */
+func keepChannels(original color.Color, blended color.Color, channels channelMask) color.Color {
+	if channels == allChannels {
+		return blended
+	}
+
+	originalNRGBA := color.NRGBAModel.Convert(original).(color.NRGBA)
+	result := color.NRGBAModel.Convert(blended).(color.NRGBA)
+
+	if !channels[0] {
+		result.R = originalNRGBA.R
+	}
+	if !channels[1] {
+		result.G = originalNRGBA.G
+	}
+	if !channels[2] {
+		result.B = originalNRGBA.B
+	}
+
+	return result
+}
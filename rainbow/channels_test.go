@@ -0,0 +1,74 @@
+package rainbow
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseChannels(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected channelMask
+	}{
+		{input: "", expected: allChannels},
+		{input: "rgb", expected: allChannels},
+		{input: "r", expected: channelMask{true, false, false}},
+		{input: "GB", expected: channelMask{false, true, true}},
+		{input: "br", expected: channelMask{true, false, true}},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.input,
+			func(innerT *testing.T) {
+				actual, err := parseChannels(c.input)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+
+	if _, err := parseChannels("rx"); err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+}
+
+func TestRainbowifyChannels(t *testing.T) {
+	src := newTestGIF(3, 4, 4)
+
+	for _, opacity := range []float64{1, 0.5} {
+		opts := DefaultOptions()
+		opts.Channels = "r"
+		opts.Opacity = opacity
+
+		out, err := Rainbowify(src, opts)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		redChanged := false
+		for i, frame := range out.Image {
+			for j, c := range frame.Palette {
+				original := color.NRGBAModel.Convert(src.Image[i].Palette[j]).(color.NRGBA)
+				actual := color.NRGBAModel.Convert(c).(color.NRGBA)
+
+				if actual.G != original.G || actual.B != original.B {
+					t.Errorf("Opacity %v - expected %v but got %v", opacity, original, actual)
+				}
+
+				if actual.R != original.R {
+					redChanged = true
+				}
+			}
+		}
+
+		if !redChanged {
+			t.Errorf("Opacity %v - expected the red channel to change", opacity)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package rainbow
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+)
+
+/* composites every frame onto the full canvas, honoring each frame's disposal
+ * the composited frames are re-palettized so they're complete pictures by themselves
+ * and get DisposalBackground so their transparent pixels don't show what came before
+ */
+func coalesce(src *gif.GIF, quantizer string) (*gif.GIF, error) {
+	composited := composite(src)
+	frames := make([]*image.Paletted, len(composited))
+	disposal := make([]byte, len(composited))
+
+	for i, canvas := range composited {
+		coalesced, err := palettize(canvas, quantizer)
+		if err != nil {
+			return nil, err
+		}
+		frames[i] = coalesced
+		disposal[i] = gif.DisposalBackground
+	}
+
+	bounds := canvasBounds(src)
+
+	img := *src
+	img.Image = frames
+	img.Disposal = disposal
+	img.Config.Width = bounds.Dx()
+	img.Config.Height = bounds.Dy()
+
+	return &img, nil
+}
+
+/* Frames returns every frame of img as a viewer would show it, each one covering the full canvas
+ * in full RGBA, so partial frames, disposal, and transparency are all worked out already
+ */
+func Frames(img *gif.GIF) []*image.RGBA {
+	return composite(img)
+}
+
+// every frame as a viewer would show it, each one covering the full canvas
+func composite(src *gif.GIF) []*image.RGBA {
+	bounds := canvasBounds(src)
+
+	canvas := image.NewRGBA(bounds)
+	frames := make([]*image.RGBA, len(src.Image))
+
+	for i, frame := range src.Image {
+		var frameDisposal byte
+		if i < len(src.Disposal) {
+			frameDisposal = src.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if frameDisposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+
+		frames[i] = image.NewRGBA(bounds)
+		copy(frames[i].Pix, canvas.Pix)
+
+		switch frameDisposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return frames
+}
+
+// the logical screen, falling back to the union of all frames when the GIF doesn't say
+func canvasBounds(src *gif.GIF) image.Rectangle {
+	bounds := image.Rect(0, 0, src.Config.Width, src.Config.Height)
+	if bounds.Empty() {
+		for _, frame := range src.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+
+	return bounds
+}
@@ -0,0 +1,110 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestCoalesce(t *testing.T) {
+	gray := color.Palette{
+		color.RGBA{R: 128, G: 128, B: 128, A: 255},
+		color.RGBA{R: 0, G: 0, B: 0, A: 0},
+	}
+
+	// the second frame only covers the middle of the canvas and clears itself afterwards
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), gray)
+	partial := image.NewPaletted(image.Rect(1, 1, 3, 3), gray)
+	src := &gif.GIF{
+		Image:    []*image.Paletted{full, partial, full},
+		Delay:    []int{10, 10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalNone},
+		Config:   image.Config{Width: 4, Height: 4},
+	}
+
+	opts := DefaultOptions()
+	opts.Coalesce = true
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for i, frame := range out.Image {
+		t.Run(
+			"Frame is consistent",
+			func(innerT *testing.T) {
+				if frame.Bounds() != image.Rect(0, 0, 4, 4) {
+					innerT.Errorf("Expected %v but got %v", image.Rect(0, 0, 4, 4), frame.Bounds())
+				}
+
+				if out.Disposal[i] != gif.DisposalBackground {
+					innerT.Errorf("Expected %v but got %v", gif.DisposalBackground, out.Disposal[i])
+				}
+
+				expected := frame.At(0, 0)
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 4; x++ {
+						actual := frame.At(x, y)
+						if actual != expected {
+							innerT.Errorf("Expected %v but got %v at %d,%d", expected, actual, x, y)
+						}
+					}
+				}
+			},
+		)
+	}
+
+	if src.Image[1] != partial {
+		t.Errorf("Expected the source frames to be left untouched")
+	}
+}
+
+func TestCoalesceQuantizers(t *testing.T) {
+	gray := color.Palette{
+		color.RGBA{R: 128, G: 128, B: 128, A: 255},
+		color.RGBA{R: 0, G: 0, B: 0, A: 0},
+	}
+
+	// mostly transparent with only a couple of colors, which leaves median cut with empty buckets
+	full := image.NewPaletted(image.Rect(0, 0, 32, 32), gray)
+	for i := range full.Pix {
+		full.Pix[i] = 1
+	}
+	partial := image.NewPaletted(image.Rect(4, 4, 8, 8), gray)
+	src := &gif.GIF{
+		Image:    []*image.Paletted{full, partial},
+		Delay:    []int{10, 10},
+		Disposal: []byte{gif.DisposalNone, gif.DisposalNone},
+		Config:   image.Config{Width: 32, Height: 32},
+	}
+
+	for _, quantizer := range []string{"scalar", "populosity", "mediancut"} {
+		t.Run(
+			quantizer,
+			func(innerT *testing.T) {
+				opts := DefaultOptions()
+				opts.Coalesce = true
+				opts.Quantizer = quantizer
+				out, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				for _, frame := range out.Image {
+					for _, c := range frame.Palette {
+						if c == nil {
+							innerT.Fatalf("Expected no nil palette entries")
+						}
+					}
+				}
+
+				// the second frame draws over the middle, so that stays opaque
+				_, _, _, alpha := out.Image[1].At(5, 5).RGBA()
+				if alpha != 0xffff {
+					innerT.Errorf("Expected %v but got %v", 0xffff, alpha)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,108 @@
+package rainbow
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	// register the still image formats
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+/* DecodeImage decodes either an animated GIF, an animated WebP or a still image
+ * still images are turned into a single frame GIF using the given quantizer and reported as still
+ * r doesn't need to be seekable, it's buffered so the format can be sniffed
+ */
+func DecodeImage(r io.Reader, quantizer string) (*gif.GIF, bool, error) {
+	buffered := bufio.NewReader(r)
+
+	// GIF87a or GIF89a, anything shorter can't be a GIF and is left to image.Decode to reject
+	magic, _ := buffered.Peek(12)
+	if bytes.HasPrefix(magic, []byte("GIF8")) {
+		img, err := gif.DecodeAll(buffered)
+		return img, false, err
+	}
+
+	// RIFF, the file size and then WEBP
+	if len(magic) == 12 && string(magic[0:4]) == "RIFF" && string(magic[8:12]) == "WEBP" {
+		return decodeWebP(buffered, quantizer)
+	}
+
+	stillImg, format, err := image.Decode(buffered)
+	if errors.Is(err, image.ErrFormat) {
+		return nil, true, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	img, err := staticImageTransform(stillImg, format, quantizer, 0)
+	return img, true, err
+}
+
+// DecodeFrom decodes a GIF or still image from r, quantizing stills with the populosity quantizer
+func DecodeFrom(r io.Reader) (*gif.GIF, error) {
+	img, _, err := DecodeImage(r, "populosity")
+	return img, err
+}
+
+// EncodeTo writes img to w as an animated GIF
+func EncodeTo(w io.Writer, img *gif.GIF) error {
+	return gif.EncodeAll(w, img)
+}
+
+/* EncodeWithComment writes img to w as an animated GIF with comment in a Comment Extension
+ * image/gif can't write one, so it goes in right after the global color table of the encoded GIF
+ * an empty comment writes the GIF as is
+ */
+func EncodeWithComment(w io.Writer, img *gif.GIF, comment string) error {
+	if len(comment) == 0 {
+		return EncodeTo(w, img)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, img); err != nil {
+		return err
+	}
+	encoded := buf.Bytes()
+
+	// the header and logical screen descriptor, then the global color table if the packed field says there is one
+	offset := 13
+	if len(encoded) < offset {
+		return errors.New("Encoded GIF is truncated")
+	}
+	if packed := encoded[10]; packed&0x80 != 0 {
+		offset += 3 << ((packed & 0x07) + 1)
+	}
+
+	if _, err := w.Write(encoded[:offset]); err != nil {
+		return err
+	}
+	if _, err := w.Write(commentExtension(comment)); err != nil {
+		return err
+	}
+	_, err := w.Write(encoded[offset:])
+	return err
+}
+
+// the comment split into data sub-blocks of at most 255 bytes each, ended by an empty one
+func commentExtension(comment string) []byte {
+	extension := []byte{0x21, 0xfe}
+
+	for len(comment) > 0 {
+		n := len(comment)
+		if n > 255 {
+			n = 255
+		}
+
+		extension = append(extension, byte(n))
+		extension = append(extension, comment[:n]...)
+		comment = comment[n:]
+	}
+
+	return append(extension, 0x00)
+}
@@ -0,0 +1,81 @@
+package rainbow
+
+import (
+	"bytes"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	t.Run(
+		"Round trip through buffers",
+		func(innerT *testing.T) {
+			var input bytes.Buffer
+			if err := gif.EncodeAll(&input, newTestGIF(3, 4, 4)); err != nil {
+				innerT.Fatal(err)
+			}
+
+			img, err := DecodeFrom(&input)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			out, err := Rainbowify(img, DefaultOptions())
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			var output bytes.Buffer
+			if err := EncodeTo(&output, out); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			decoded, err := DecodeFrom(&output)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(decoded.Image) != 3 {
+				innerT.Errorf("Expected %v but got %v", 3, len(decoded.Image))
+			}
+		},
+	)
+
+	t.Run(
+		"Garbage input",
+		func(innerT *testing.T) {
+			if _, err := DecodeFrom(bytes.NewReader([]byte("GI"))); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
+
+func TestEncodeWithComment(t *testing.T) {
+	// long enough to need two sub-blocks
+	comment := strings.Repeat("rainbow ", 40)
+
+	var buf bytes.Buffer
+	if err := EncodeWithComment(&buf, newTestGIF(3, 4, 4), comment); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	extension := commentExtension(comment)
+	if !bytes.Contains(buf.Bytes(), extension) {
+		t.Errorf("Expected the comment extension in the output")
+	}
+
+	if len(extension) != 2+1+255+1+(len(comment)-255)+1 {
+		t.Errorf("Expected %v but got %v", 2+1+255+1+(len(comment)-255)+1, len(extension))
+	}
+
+	decoded, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(decoded.Image) != 3 {
+		t.Errorf("Expected %v but got %v", 3, len(decoded.Image))
+	}
+}
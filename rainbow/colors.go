@@ -0,0 +1,164 @@
+package rainbow
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* ParseGradientColors parses a comma separated list of hex colors
+ * each color can optionally start with # and be written as RGB, RRGGBB, or RRGGBBAA
+ * the alpha byte becomes that stop's opacity, stops without one are fully opaque
+ * positions are checked but dropped, use ParseGradientStops to keep them
+ */
+func ParseGradientColors(gradientColors string) ([]colorful.Color, []float64, error) {
+	stops, opacities, err := ParseGradientStops(gradientColors)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	colors := make([]colorful.Color, len(stops))
+	for i, stop := range stops {
+		colors[i] = stop.Color
+	}
+
+	return colors, opacities, nil
+}
+
+/* ParseGradientStops is ParseGradientColors but every color can be followed by @ and its position
+ * e.g. ff0000@0,00ff00@0.25,0000ff@1 - colors without a position are spread evenly between their neighbours
+ * positions must be between 0 and 1 and never go backwards
+ */
+func ParseGradientStops(gradientColors string) ([]Stop, []float64, error) {
+	if len(gradientColors) == 0 {
+		colors, _ := GradientPreset("rainbow")
+		stops := make([]Stop, len(colors))
+		for i, color := range colors {
+			stops[i] = Stop{Color: color, Pos: -1}
+		}
+		return stops, nil, nil
+	}
+
+	tokens := strings.Split(gradientColors, ",")
+	stops := make([]Stop, len(tokens))
+	opacities := make([]float64, len(tokens))
+	for i, token := range tokens {
+		hex := token
+		position := -1.0
+
+		if at := strings.Index(token, "@"); at != -1 {
+			hex = token[:at]
+
+			var err error
+			position, err = strconv.ParseFloat(strings.TrimSpace(token[at+1:]), 64)
+			if err != nil || position < 0 || position > 1 {
+				return nil, nil, fmt.Errorf("Invalid position %q: expected a number between 0 and 1", token)
+			}
+		}
+
+		color, opacity, err := parseHexColor(hex)
+		if err != nil {
+			return nil, nil, err
+		}
+		stops[i] = Stop{Color: color, Pos: position}
+		opacities[i] = opacity
+	}
+
+	if err := validateStops(stops); err != nil {
+		return nil, nil, err
+	}
+
+	return stops, opacities, nil
+}
+
+/* ReadGradientColors reads a gradient list written one or more colors per line
+ * blank lines and comments (lines starting with # that aren't a color) are skipped
+ * returns the colors joined by commas, ready for ParseGradientColors
+ */
+func ReadGradientColors(r io.Reader) (string, error) {
+	var hexes []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		tokens := strings.Split(line, ",")
+		if strings.HasPrefix(line, "#") && !isHex(strings.TrimSpace(tokens[0])[1:]) {
+			continue
+		}
+
+		for _, token := range tokens {
+			token = strings.TrimSpace(token)
+			if len(token) != 0 {
+				hexes = append(hexes, token)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	if len(hexes) == 0 {
+		return "", ErrEmptyGradient
+	}
+
+	return strings.Join(hexes, ","), nil
+}
+
+const hexDigits = "0123456789abcdefABCDEF"
+
+func isHex(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, digit := range s {
+		if !strings.ContainsRune(hexDigits, digit) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseHexColor(token string) (colorful.Color, float64, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(token), "#")
+
+	switch len(hex) {
+	case 3:
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	case 6, 8:
+	default:
+		return colorful.Color{}, 0, &ColorError{Token: token, Reason: fmt.Sprintf("expected 3, 6, or 8 hex digits but got %d", len(hex))}
+	}
+
+	for _, digit := range hex {
+		if !strings.ContainsRune(hexDigits, digit) {
+			return colorful.Color{}, 0, &ColorError{Token: token, Reason: fmt.Sprintf("%q is not a hex digit", digit)}
+		}
+	}
+
+	color, err := colorful.Hex("#" + hex[:6])
+	if err != nil {
+		return colorful.Color{}, 0, &ColorError{Token: token, Reason: err.Error()}
+	}
+
+	opacity := 1.0
+	if len(hex) == 8 {
+		alpha, err := strconv.ParseUint(hex[6:], 16, 8)
+		if err != nil {
+			return colorful.Color{}, 0, &ColorError{Token: token, Reason: err.Error()}
+		}
+		opacity = float64(alpha) / 255
+	}
+
+	return color, opacity, nil
+}
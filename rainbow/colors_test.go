@@ -0,0 +1,171 @@
+package rainbow
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestParseGradientColors(t *testing.T) {
+	red := colorful.Color{R: 1, G: 0, B: 0}
+
+	valid := []struct {
+		input   string
+		opacity float64
+	}{
+		{input: "f00", opacity: 1},
+		{input: "#f00", opacity: 1},
+		{input: "ff0000", opacity: 1},
+		{input: "#FF0000", opacity: 1},
+		{input: "ff000000", opacity: 0},
+		{input: "#ff0000ff", opacity: 1},
+		{input: " ff000066 ", opacity: 0.4},
+	}
+
+	for _, c := range valid {
+		t.Run(
+			c.input,
+			func(innerT *testing.T) {
+				colors, opacities, err := ParseGradientColors(c.input)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if len(colors) != 1 || colors[0] != red {
+					innerT.Errorf("Expected %v but got %v", []colorful.Color{red}, colors)
+				}
+
+				if len(opacities) != 1 || opacities[0] != c.opacity {
+					innerT.Errorf("Expected %v but got %v", []float64{c.opacity}, opacities)
+				}
+			},
+		)
+	}
+
+	invalid := []struct {
+		input    string
+		contains string
+	}{
+		{input: "ff", contains: `"ff": expected 3, 6, or 8 hex digits but got 2`},
+		{input: "ff00000", contains: "got 7"},
+		{input: "f00,00ff00,#12345", contains: `"#12345"`},
+		{input: "gg0000", contains: `'g' is not a hex digit`},
+		{input: "f00,", contains: "got 0"},
+	}
+
+	for _, c := range invalid {
+		t.Run(
+			c.input,
+			func(innerT *testing.T) {
+				_, _, err := ParseGradientColors(c.input)
+				if err == nil {
+					innerT.Fatalf("Expected an error but got %v", err)
+				}
+
+				if !strings.Contains(err.Error(), c.contains) {
+					innerT.Errorf("Expected %q to contain %q", err.Error(), c.contains)
+				}
+			},
+		)
+	}
+}
+
+func TestParseGradientStops(t *testing.T) {
+	valid := []struct {
+		input     string
+		positions []float64
+	}{
+		{input: "ff0000@0,00ff00@0.25,0000ff@1", positions: []float64{0, 0.25, 1}},
+		{input: "ff0000,00ff00@0.25,0000ff", positions: []float64{-1, 0.25, -1}},
+		{input: "ff000080@0.5,00ff00@0.5", positions: []float64{0.5, 0.5}},
+		{input: "ff0000,00ff00", positions: []float64{-1, -1}},
+	}
+
+	for _, c := range valid {
+		t.Run(
+			c.input,
+			func(innerT *testing.T) {
+				stops, _, err := ParseGradientStops(c.input)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if len(stops) != len(c.positions) {
+					innerT.Fatalf("Expected %v but got %v", len(c.positions), len(stops))
+				}
+
+				for i, position := range c.positions {
+					if stops[i].Pos != position {
+						innerT.Errorf("Expected %v but got %v", position, stops[i].Pos)
+					}
+				}
+			},
+		)
+	}
+
+	invalid := []struct {
+		input    string
+		contains string
+	}{
+		{input: "ff0000@0.5,00ff00@0.25", contains: "can't be lower"},
+		{input: "ff0000@0.5,00ff00,0000ff@0.25", contains: "can't be lower"},
+		{input: "ff0000@1.5", contains: "between 0 and 1"},
+		{input: "ff0000@abc", contains: `"ff0000@abc"`},
+		{input: "zz0000@0.5", contains: "not a hex digit"},
+	}
+
+	for _, c := range invalid {
+		t.Run(
+			c.input,
+			func(innerT *testing.T) {
+				_, _, err := ParseGradientStops(c.input)
+				if err == nil {
+					innerT.Fatalf("Expected an error but got %v", err)
+				}
+
+				if !strings.Contains(err.Error(), c.contains) {
+					innerT.Errorf("Expected %q to contain %q", err.Error(), c.contains)
+				}
+			},
+		)
+	}
+}
+
+func TestReadGradientColors(t *testing.T) {
+	t.Run(
+		"Newlines, commas, blanks, and comments",
+		func(innerT *testing.T) {
+			input := "# brand colors\n\nff0000\n  #00ff00, 00f  \n#comment\n\n#0000ffcc\n"
+
+			colorList, err := ReadGradientColors(strings.NewReader(input))
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := "ff0000,#00ff00,00f,#0000ffcc"
+			if colorList != expected {
+				innerT.Errorf("Expected %v but got %v", expected, colorList)
+			}
+
+			colors, _, err := ParseGradientColors(colorList)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(colors) != 4 {
+				innerT.Errorf("Expected %v but got %v", 4, len(colors))
+			}
+		},
+	)
+
+	t.Run(
+		"Empty after filtering",
+		func(innerT *testing.T) {
+			_, err := ReadGradientColors(strings.NewReader("\n# nothing here\n   \n"))
+			if err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,96 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+)
+
+/* Concat plays clips one after the other as a single GIF, so a single sweep of the gradient can go across all of them
+ * clips of different sizes are centered on a canvas as big as the largest, unless strict is set in which case that's an error
+ * every clip starts on a cleared canvas like it would by itself, if a clip's last frame doesn't cover the clip
+ * it's swapped for the whole picture at that point re-palettized with quantizer, so clearing it clears the clip
+ * the loop count is taken from the first clip
+ */
+func Concat(clips []*gif.GIF, strict bool, quantizer string) (*gif.GIF, error) {
+	if len(clips) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	var size image.Point
+	for _, clip := range clips {
+		if len(clip.Image) == 0 {
+			return nil, ErrNoFrames
+		}
+
+		clipSize := canvasBounds(clip).Size()
+		if strict && size != (image.Point{}) && clipSize != size {
+			return nil, errors.New("Concatenated GIFs must all be the same size")
+		}
+
+		if clipSize.X > size.X {
+			size.X = clipSize.X
+		}
+		if clipSize.Y > size.Y {
+			size.Y = clipSize.Y
+		}
+	}
+
+	var frames []*image.Paletted
+	var delay []int
+	var disposal []byte
+	for i, clip := range clips {
+		bounds := canvasBounds(clip)
+		offset := size.Sub(bounds.Size()).Div(2).Sub(bounds.Min)
+
+		clipFrames := clip.Image
+		clipDisposal := make([]byte, len(clipFrames))
+		copy(clipDisposal, clip.Disposal)
+
+		// the next clip would otherwise be drawn over what's left of this one
+		if i < len(clips)-1 {
+			last := len(clipFrames) - 1
+			if !bounds.In(clipFrames[last].Bounds()) {
+				composited := composite(clip)
+				whole, err := palettize(composited[last], quantizer)
+				if err != nil {
+					return nil, err
+				}
+
+				clipFrames = append(clipFrames[:last:last], whole)
+			}
+			clipDisposal[last] = gif.DisposalBackground
+		}
+
+		for j, frame := range clipFrames {
+			// copied so the output doesn't share pixels or palettes with the clips
+			pix := make([]uint8, len(frame.Pix))
+			copy(pix, frame.Pix)
+			palette := make(color.Palette, len(frame.Palette))
+			copy(palette, frame.Palette)
+
+			frames = append(frames, &image.Paletted{
+				Pix:     pix,
+				Stride:  frame.Stride,
+				Rect:    frame.Rect.Add(offset),
+				Palette: palette,
+			})
+
+			var frameDelay int
+			if j < len(clip.Delay) {
+				frameDelay = clip.Delay[j]
+			}
+			delay = append(delay, frameDelay)
+		}
+		disposal = append(disposal, clipDisposal...)
+	}
+
+	return &gif.GIF{
+		Image:     frames,
+		Delay:     delay,
+		Disposal:  disposal,
+		LoopCount: clips[0].LoopCount,
+		Config:    image.Config{Width: size.X, Height: size.Y},
+	}, nil
+}
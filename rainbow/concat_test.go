@@ -0,0 +1,185 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// a clip of frameCount frames, every one a size by size square of c
+func newSolidClip(frameCount int, size int, c color.Color) *gif.GIF {
+	clip := &gif.GIF{Config: image.Config{Width: size, Height: size}}
+	for i := 0; i < frameCount; i++ {
+		clip.Image = append(clip.Image, image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{c}))
+		clip.Delay = append(clip.Delay, 10*(i+1))
+		clip.Disposal = append(clip.Disposal, gif.DisposalNone)
+	}
+
+	return clip
+}
+
+func TestConcat(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	gray := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255}
+
+	t.Run(
+		"Frames are appended",
+		func(innerT *testing.T) {
+			a := newSolidClip(3, 4, gray)
+			b := newSolidClip(2, 4, gray)
+			b.LoopCount = 2
+
+			img, err := Concat([]*gif.GIF{a, b}, true, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(img.Image) != 5 {
+				innerT.Fatalf("Expected %v but got %v", 5, len(img.Image))
+			}
+
+			expectedDelay := []int{10, 20, 30, 10, 20}
+			for i, delay := range img.Delay {
+				if delay != expectedDelay[i] {
+					innerT.Errorf("Expected %v but got %v", expectedDelay, img.Delay)
+					break
+				}
+			}
+
+			// cleared before the second clip starts
+			if img.Disposal[2] != gif.DisposalBackground {
+				innerT.Errorf("Expected %v but got %v", gif.DisposalBackground, img.Disposal[2])
+			}
+
+			if img.LoopCount != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, img.LoopCount)
+			}
+		},
+	)
+
+	t.Run(
+		"One sweep across every clip",
+		func(innerT *testing.T) {
+			img, err := Concat([]*gif.GIF{newSolidClip(3, 4, gray), newSolidClip(2, 4, gray)}, true, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			var overlays []colorful.Color
+			opts := DefaultOptions()
+			opts.Overlay = func(colors []colorful.Color) { overlays = colors }
+			out, err := Rainbowify(img, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(overlays) != 5 {
+				innerT.Fatalf("Expected %v but got %v", 5, len(overlays))
+			}
+
+			for i, overlay := range overlays {
+				expected := color.RGBAModel.Convert(blendPixel(gray, overlay, blendColor, 1))
+				if actual := color.RGBAModel.Convert(out.Image[i].Palette[0]); actual != expected {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected, actual)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Smaller clips are centered",
+		func(innerT *testing.T) {
+			img, err := Concat([]*gif.GIF{newSolidClip(1, 4, red), newSolidClip(1, 2, gray)}, false, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if img.Config.Width != 4 || img.Config.Height != 4 {
+				innerT.Errorf("Expected %v by %v but got %v by %v", 4, 4, img.Config.Width, img.Config.Height)
+			}
+
+			if bounds := img.Image[1].Bounds(); bounds != image.Rect(1, 1, 3, 3) {
+				innerT.Errorf("Expected %v but got %v", image.Rect(1, 1, 3, 3), bounds)
+			}
+
+			// none of the first clip is left around the second
+			frames := Frames(img)
+			if _, _, _, alpha := frames[1].At(0, 0).RGBA(); alpha != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Partial last frames are filled in",
+		func(innerT *testing.T) {
+			a := newSolidClip(2, 4, red)
+			// the last frame only covers the corner, the rest of the canvas still shows the first
+			a.Image[1] = image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{gray})
+
+			img, err := Concat([]*gif.GIF{a, newSolidClip(1, 2, gray)}, false, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			frames := Frames(img)
+			if expected, actual := color.RGBAModel.Convert(red), frames[1].At(3, 3); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+
+			if _, _, _, alpha := frames[2].At(3, 3).RGBA(); alpha != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Clips are left alone",
+		func(innerT *testing.T) {
+			a := newSolidClip(2, 4, gray)
+			b := newSolidClip(1, 4, gray)
+
+			img, err := Concat([]*gif.GIF{a, b}, true, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for _, frame := range img.Image {
+				frame.Pix[0] = 1
+				frame.Palette[0] = red
+			}
+
+			for _, clip := range []*gif.GIF{a, b} {
+				for _, frame := range clip.Image {
+					if frame.Pix[0] != 0 || frame.Palette[0] != gray {
+						innerT.Errorf("Expected %v and %v but got %v and %v", 0, gray, frame.Pix[0], frame.Palette[0])
+					}
+				}
+			}
+		},
+	)
+
+	invalid := []struct {
+		name   string
+		clips  []*gif.GIF
+		strict bool
+	}{
+		{name: "No clips", clips: nil},
+		{name: "Empty clip", clips: []*gif.GIF{newSolidClip(1, 4, red), {}}},
+		{name: "Strict sizes", clips: []*gif.GIF{newSolidClip(1, 4, red), newSolidClip(1, 2, red)}, strict: true},
+	}
+
+	for _, c := range invalid {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				if _, err := Concat(c.clips, c.strict, "populosity"); err == nil {
+					innerT.Errorf("Expected an error")
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,44 @@
+package rainbow
+
+import (
+	"image"
+	"image/gif"
+)
+
+/* cuts every frame down to crop and moves it so crop's corner is the origin
+ * partial frames keep whatever part of them is inside crop, their disposal still covers the same area within it
+ * frames entirely outside of crop would be left with nothing, so those GIFs are coalesced first
+ */
+func cropFrames(src *gif.GIF, crop image.Rectangle, quantizer string) (*gif.GIF, error) {
+	for _, frame := range src.Image {
+		if !frame.Bounds().Overlaps(crop) {
+			var err error
+			src, err = coalesce(src, quantizer)
+			if err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
+	frames := make([]*image.Paletted, len(src.Image))
+	for i, frame := range src.Image {
+		bounds := frame.Bounds().Intersect(crop)
+
+		cropped := image.NewPaletted(bounds.Sub(crop.Min), frame.Palette)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			start := frame.PixOffset(bounds.Min.X, y)
+			copy(cropped.Pix[cropped.PixOffset(bounds.Min.X-crop.Min.X, y-crop.Min.Y):], frame.Pix[start:start+bounds.Dx()])
+		}
+
+		frames[i] = cropped
+	}
+
+	img := *src
+	img.Image = frames
+	img.Config.Width = crop.Dx()
+	img.Config.Height = crop.Dy()
+
+	return &img, nil
+}
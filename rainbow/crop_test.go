@@ -0,0 +1,75 @@
+package rainbow
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCropFrames(t *testing.T) {
+	src := newTestGIF(2, 100, 100)
+	src.Config.Width = 100
+	src.Config.Height = 100
+	crop := image.Rect(10, 10, 30, 30)
+
+	t.Run(
+		"Frames",
+		func(innerT *testing.T) {
+			img, err := cropFrames(src, crop, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if img.Config.Width != 20 || img.Config.Height != 20 {
+				innerT.Errorf("Expected %v but got %vx%v", "20x20", img.Config.Width, img.Config.Height)
+			}
+
+			for i, frame := range img.Image {
+				if frame.Bounds() != image.Rect(0, 0, 20, 20) {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, image.Rect(0, 0, 20, 20), frame.Bounds())
+				}
+
+				expected := src.Image[i].ColorIndexAt(10, 10)
+				if actual := frame.ColorIndexAt(0, 0); actual != expected {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected, actual)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Partial frames keep their overlap",
+		func(innerT *testing.T) {
+			partial := newTestGIF(2, 100, 100)
+			partial.Config = src.Config
+			partial.Image[1] = partial.Image[1].SubImage(image.Rect(20, 20, 50, 50)).(*image.Paletted)
+
+			img, err := cropFrames(partial, crop, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := image.Rect(10, 10, 20, 20)
+			if img.Image[1].Bounds() != expected {
+				innerT.Errorf("Expected %v but got %v", expected, img.Image[1].Bounds())
+			}
+		},
+	)
+
+	t.Run(
+		"Frames outside are coalesced",
+		func(innerT *testing.T) {
+			outside := newTestGIF(2, 100, 100)
+			outside.Config = src.Config
+			outside.Image[1] = outside.Image[1].SubImage(image.Rect(60, 60, 70, 70)).(*image.Paletted)
+
+			img, err := cropFrames(outside, crop, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if img.Image[1].Bounds() != image.Rect(0, 0, 20, 20) {
+				innerT.Errorf("Expected %v but got %v", image.Rect(0, 0, 20, 20), img.Image[1].Bounds())
+			}
+		},
+	)
+}
@@ -0,0 +1,103 @@
+package rainbow
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+)
+
+/* keeps every nth frame starting with the first
+ * the delays of the dropped frames are added to the kept frame before them so the timing stays the same
+ */
+func decimateFrames(src *gif.GIF, n int) *gif.GIF {
+	// rounded up without adding to n, which a huge n would overflow
+	kept := len(src.Image) / n
+	if len(src.Image)%n != 0 {
+		kept++
+	}
+
+	frames := make([]*image.Paletted, kept)
+	delays := make([]int, kept)
+	var disposals []byte
+	if len(src.Disposal) > 0 {
+		disposals = make([]byte, kept)
+	}
+
+	for i, frame := range src.Image {
+		keptIndex := i / n
+		if i%n == 0 {
+			frames[keptIndex] = frame
+			if disposals != nil {
+				disposals[keptIndex] = src.Disposal[i%len(src.Disposal)]
+			}
+		}
+
+		if len(src.Delay) > 0 {
+			delays[keptIndex] += src.Delay[i%len(src.Delay)]
+		}
+	}
+
+	img := *src
+	img.Image = frames
+	img.Delay = delays
+	img.Disposal = disposals
+
+	return &img
+}
+
+/* merges runs of consecutive frames that look exactly the same into their first frame, adding up their delays
+ * the frames come out of blending, so frames only match when their overlay colors did too
+ */
+func dedupeFrames(frames []*image.Paletted, delays []int, disposals []byte) ([]*image.Paletted, []int, []byte) {
+	if len(frames) == 0 {
+		return frames, delays, disposals
+	}
+
+	keptFrames := []*image.Paletted{frames[0]}
+	keptDelays := []int{delays[0]}
+	keptDisposals := []byte{disposals[0]}
+
+	for i := 1; i < len(frames); i++ {
+		last := len(keptFrames) - 1
+		if disposals[i] == keptDisposals[last] && sameFrame(keptFrames[last], frames[i], disposals[i]) {
+			keptDelays[last] += delays[i]
+			continue
+		}
+
+		keptFrames = append(keptFrames, frames[i])
+		keptDelays = append(keptDelays, delays[i])
+		keptDisposals = append(keptDisposals, disposals[i])
+	}
+
+	return keptFrames, keptDelays, keptDisposals
+}
+
+/* whether drawing b right after a shows the same picture as showing a for longer
+ * clearing to the background between them changes what shows through transparent pixels, so those never match
+ */
+func sameFrame(a *image.Paletted, b *image.Paletted, disposal byte) bool {
+	if a.Rect != b.Rect || len(a.Palette) != len(b.Palette) {
+		return false
+	}
+
+	for i := range a.Palette {
+		if color.NRGBAModel.Convert(a.Palette[i]) != color.NRGBAModel.Convert(b.Palette[i]) {
+			return false
+		}
+
+		if _, _, _, alpha := a.Palette[i].RGBA(); alpha != 0xffff && disposal == gif.DisposalBackground {
+			return false
+		}
+	}
+
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		rowA := a.Pix[a.PixOffset(a.Rect.Min.X, y):][:a.Rect.Dx()]
+		rowB := b.Pix[b.PixOffset(b.Rect.Min.X, y):][:b.Rect.Dx()]
+		if !bytes.Equal(rowA, rowB) {
+			return false
+		}
+	}
+
+	return true
+}
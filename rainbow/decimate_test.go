@@ -0,0 +1,129 @@
+package rainbow
+
+import (
+	"image"
+	"image/gif"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestDecimateFrames(t *testing.T) {
+	cases := []struct {
+		name     string
+		every    int
+		delays   []int
+		expected []int
+	}{
+		{name: "Uniform", every: 2, delays: []int{10, 10, 10, 10, 10, 10}, expected: []int{20, 20, 20}},
+		{name: "Uneven", every: 2, delays: []int{1, 2, 3, 4, 5}, expected: []int{3, 7, 5}},
+		{name: "More than the frames", every: 10, delays: []int{1, 2, 3}, expected: []int{6}},
+		{name: "Largest int", every: math.MaxInt, delays: []int{1, 2, 3}, expected: []int{6}},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				src := newTestGIF(len(c.delays), 2, 2)
+				src.Delay = c.delays
+
+				img := decimateFrames(src, c.every)
+				if !reflect.DeepEqual(img.Delay, c.expected) {
+					innerT.Errorf("Expected %v but got %v", c.expected, img.Delay)
+				}
+
+				for i, frame := range img.Image {
+					if frame != src.Image[i*c.every] {
+						innerT.Errorf("Frame %d - expected source frame %v", i, i*c.every)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyEvery(t *testing.T) {
+	src := newTestGIF(6, 2, 2)
+	for i := range src.Delay {
+		src.Delay[i] = 10
+	}
+
+	opts := DefaultOptions()
+	opts.Every = 2
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(out.Image) != 3 {
+		t.Fatalf("Expected %v but got %v", 3, len(out.Image))
+	}
+
+	expected := []int{20, 20, 20}
+	if !reflect.DeepEqual(out.Delay, expected) {
+		t.Errorf("Expected %v but got %v", expected, out.Delay)
+	}
+}
+
+func TestRainbowifyDedupe(t *testing.T) {
+	src := newTestGIF(3, 2, 2)
+	// the first two frames are the same picture
+	src.Image[1] = src.Image[0]
+
+	t.Run(
+		"Same overlay color",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Colors = []colorful.Color{{R: 1, G: 0, B: 0}}
+			opts.Dedupe = true
+
+			out, err := Rainbowify(src, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(out.Image) != 2 {
+				innerT.Fatalf("Expected %v but got %v", 2, len(out.Image))
+			}
+
+			expected := []int{20, 10}
+			if !reflect.DeepEqual(out.Delay, expected) {
+				innerT.Errorf("Expected %v but got %v", expected, out.Delay)
+			}
+		},
+	)
+
+	t.Run(
+		"Different overlay colors",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Dedupe = true
+
+			out, err := Rainbowify(src, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(out.Image) != 3 {
+				innerT.Errorf("Expected %v but got %v", 3, len(out.Image))
+			}
+		},
+	)
+
+	t.Run(
+		"Clearing between transparent frames",
+		func(innerT *testing.T) {
+			frames := []*image.Paletted{src.Image[0], src.Image[0]}
+			background := []byte{gif.DisposalBackground, gif.DisposalBackground}
+
+			kept, _, _ := dedupeFrames(frames, []int{10, 10}, background)
+			if len(kept) != 2 {
+				innerT.Errorf("Expected %v but got %v", 2, len(kept))
+			}
+		},
+	)
+}
@@ -0,0 +1,70 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// draws src into dst using dst's palette, spreading the difference to the real colors around
+type ditherFunc func(dst *image.Paletted, src image.Image)
+
+// nil means every pixel just gets the closest palette color
+func getDitherFunc(name string) (ditherFunc, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "floyd-steinberg":
+		return ditherFloydSteinberg, nil
+	case "ordered":
+		return ditherOrdered, nil
+	default:
+		return nil, errors.New("Invalid dither")
+	}
+}
+
+// pushes every pixel's error onto the pixels right and below it
+func ditherFloydSteinberg(dst *image.Paletted, src image.Image) {
+	bounds := dst.Bounds()
+	draw.FloydSteinberg.Draw(dst, bounds, src, bounds.Min)
+}
+
+// a 4x4 Bayer matrix, the thresholds are spread out so neighbouring pixels never get similar ones
+var bayer4 = [16]float64{
+	0, 8, 2, 10,
+	12, 4, 14, 6,
+	3, 11, 1, 9,
+	15, 7, 13, 5,
+}
+
+/* nudges every pixel by a threshold from the Bayer matrix before picking the closest color
+ * gives a regular cross hatch instead of Floyd-Steinberg's noise, which also compresses better and doesn't crawl between frames
+ * the nudge is about the distance between colors of an evenly spread palette of the same size
+ */
+func ditherOrdered(dst *image.Paletted, src image.Image) {
+	bounds := dst.Bounds()
+	spread := 255 / math.Cbrt(float64(len(dst.Palette)))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			threshold := (bayer4[(y&3)*4+(x&3)]+0.5)/16 - 0.5
+			offset := threshold * spread
+
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			nudged := color.NRGBA{
+				R: clampChannel(float64(c.R) + offset),
+				G: clampChannel(float64(c.G) + offset),
+				B: clampChannel(float64(c.B) + offset),
+				A: c.A,
+			}
+
+			dst.SetColorIndex(x, y, uint8(dst.Palette.Index(nudged)))
+		}
+	}
+}
+
+func clampChannel(value float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(value))))
+}
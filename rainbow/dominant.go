@@ -0,0 +1,75 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* the color covering the most pixels across all frames, which on a subject against a solid background is the background
+ * pixels are counted by how often their palette index shows up in Pix, transparent ones are skipped
+ * colors are compared the way blending sees them, as 8 bit sRGB without the alpha, false when every pixel is transparent
+ */
+func dominantColor(frames []*image.Paletted) (color.RGBA, bool) {
+	counts := make(map[color.RGBA]int)
+
+	for _, frame := range frames {
+		indexCounts := make([]int, len(frame.Palette))
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := frame.PixOffset(bounds.Min.X, y)
+			for _, index := range frame.Pix[offset : offset+bounds.Dx()] {
+				if int(index) < len(indexCounts) {
+					indexCounts[index]++
+				}
+			}
+		}
+
+		for index, count := range indexCounts {
+			converted, _, ok := straightColor(frame.Palette[index])
+			if count == 0 || !ok {
+				continue
+			}
+
+			r, g, b := converted.Clamped().RGB255()
+			counts[color.RGBA{R: r, G: g, B: b, A: 255}] += count
+		}
+	}
+
+	var dominant color.RGBA
+	best := 0
+	for c, count := range counts {
+		// ties go to the smaller color so map order doesn't pick one
+		if count > best || (count == best && rgbaLess(c, dominant)) {
+			dominant = c
+			best = count
+		}
+	}
+
+	return dominant, best > 0
+}
+
+func rgbaLess(a color.RGBA, b color.RGBA) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+
+	return a.B < b.B
+}
+
+// returns the bottom color as is when it's the dominant color, everything else is blended as usual
+func dominantBlend(blend blendFunc, dominant color.RGBA) blendFunc {
+	return func(top colorful.Color, bottom colorful.Color) colorful.Color {
+		r, g, b := bottom.Clamped().RGB255()
+
+		if r == dominant.R && g == dominant.G && b == dominant.B {
+			return bottom
+		}
+
+		return blend(top, bottom)
+	}
+}
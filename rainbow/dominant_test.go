@@ -0,0 +1,101 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestDominantColor(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	red := color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	transparent := color.RGBA{}
+
+	cases := []struct {
+		name     string
+		frames   []*image.Paletted
+		expected color.RGBA
+		ok       bool
+	}{
+		{
+			name: "Most pixels",
+			frames: []*image.Paletted{
+				{Pix: []uint8{0, 0, 0, 1}, Stride: 2, Rect: image.Rect(0, 0, 2, 2), Palette: color.Palette{white, red}},
+			},
+			expected: white,
+			ok:       true,
+		},
+		{
+			name: "Counted across frames with different palettes",
+			frames: []*image.Paletted{
+				{Pix: []uint8{0, 0, 1, 1}, Stride: 2, Rect: image.Rect(0, 0, 2, 2), Palette: color.Palette{white, red}},
+				{Pix: []uint8{0, 0, 0, 1}, Stride: 2, Rect: image.Rect(0, 0, 2, 2), Palette: color.Palette{red, white}},
+			},
+			expected: red,
+			ok:       true,
+		},
+		{
+			name: "Transparent is skipped",
+			frames: []*image.Paletted{
+				{Pix: []uint8{0, 0, 0, 1}, Stride: 2, Rect: image.Rect(0, 0, 2, 2), Palette: color.Palette{transparent, red}},
+			},
+			expected: red,
+			ok:       true,
+		},
+		{
+			name: "Only transparent",
+			frames: []*image.Paletted{
+				{Pix: []uint8{0, 0, 0, 0}, Stride: 2, Rect: image.Rect(0, 0, 2, 2), Palette: color.Palette{transparent}},
+			},
+			ok: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				actual, ok := dominantColor(c.frames)
+				if ok != c.ok {
+					innerT.Fatalf("Expected %v but got %v", c.ok, ok)
+				}
+
+				if ok && actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyKeepDominant(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 0x20, G: 0x40, B: 0x60, A: 255},
+		color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255},
+	}
+	frame := image.NewPaletted(image.Rect(0, 0, 3, 3), palette)
+	// the first color fills everything but the middle
+	frame.SetColorIndex(1, 1, 1)
+	src := &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{10}}
+
+	opts := DefaultOptions()
+	opts.Colors = []colorful.Color{{R: 1, G: 0, B: 0}}
+	opts.KeepDominant = true
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	blended := out.Image[0].Palette
+	if expected, actual := color.RGBAModel.Convert(palette[0]), color.RGBAModel.Convert(blended[0]); actual != expected {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+
+	if expected, actual := color.RGBAModel.Convert(blendPixel(palette[1], opts.Colors[0], blendColor, 1)), color.RGBAModel.Convert(blended[1]); actual != expected {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,54 @@
+package rainbow
+
+import (
+	"errors"
+	"math"
+)
+
+// reshapes a position in [0, 1], keeping 0 and 1 where they are
+type easingFunc func(t float64) float64
+
+func getEasingFunc(name string) (easingFunc, error) {
+	switch name {
+	case "", "linear":
+		return easeLinear, nil
+	case "ease-in":
+		return easeIn, nil
+	case "ease-out":
+		return easeOut, nil
+	case "ease-in-out":
+		return easeInOut, nil
+	case "sine":
+		return easeSine, nil
+	default:
+		return nil, errors.New("Invalid easing")
+	}
+}
+
+func easeLinear(t float64) float64 {
+	return t
+}
+
+// starts slow and speeds up
+func easeIn(t float64) float64 {
+	return t * t
+}
+
+// starts fast and slows down
+func easeOut(t float64) float64 {
+	return 1 - (1-t)*(1-t)
+}
+
+// slow at both ends, fast in the middle
+func easeInOut(t float64) float64 {
+	if t < 0.5 {
+		return 2 * t * t
+	}
+
+	return 1 - 2*(1-t)*(1-t)
+}
+
+// like ease-in-out but following a cosine so the speed changes smoothly
+func easeSine(t float64) float64 {
+	return (1 - math.Cos(math.Pi*t)) / 2
+}
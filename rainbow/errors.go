@@ -0,0 +1,33 @@
+package rainbow
+
+import (
+	"errors"
+	"fmt"
+)
+
+/* Errors callers can check for with errors.Is, e.g. to tell bad input apart from a failure
+ * most are returned wrapped with more detail, a bad color is also a *ColorError holding the offending text
+ */
+var (
+	ErrInvalidColor      = errors.New("Invalid color")
+	ErrUnsupportedFormat = errors.New("Unsupported format")
+	ErrEmptyGradient     = errors.New("Gradient needs at least one color")
+	ErrNoFrames          = errors.New("Image has no frames")
+	ErrTooManyFrames     = errors.New("Too many output frames")
+)
+
+// ColorError is returned for a color that can't be parsed, it matches ErrInvalidColor
+type ColorError struct {
+	// the color as it was given
+	Token string
+	// what's wrong with it
+	Reason string
+}
+
+func (e *ColorError) Error() string {
+	return fmt.Sprintf("Invalid color %q: %s", e.Token, e.Reason)
+}
+
+func (e *ColorError) Is(target error) bool {
+	return target == ErrInvalidColor
+}
@@ -0,0 +1,144 @@
+package rainbow
+
+import (
+	"bytes"
+	"errors"
+	"image/gif"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run(
+		"Invalid color",
+		func(innerT *testing.T) {
+			_, _, err := ParseGradientColors("ff0000,12345g")
+			if !errors.Is(err, ErrInvalidColor) {
+				innerT.Fatalf("Expected %v but got %v", ErrInvalidColor, err)
+			}
+
+			var colorErr *ColorError
+			if !errors.As(err, &colorErr) {
+				innerT.Fatalf("Expected a *ColorError but got %T", err)
+			}
+
+			if colorErr.Token != "12345g" {
+				innerT.Errorf("Expected %v but got %v", "12345g", colorErr.Token)
+			}
+		},
+	)
+
+	t.Run(
+		"Empty gradient",
+		func(innerT *testing.T) {
+			_, err := ReadGradientColors(strings.NewReader("# nothing\n"))
+			if !errors.Is(err, ErrEmptyGradient) {
+				innerT.Errorf("Expected %v but got %v", ErrEmptyGradient, err)
+			}
+
+			opts := DefaultOptions()
+			opts.Colors = nil
+			if _, err := Rainbowify(newTestGIF(1, 2, 2), opts); !errors.Is(err, ErrEmptyGradient) {
+				innerT.Errorf("Expected %v but got %v", ErrEmptyGradient, err)
+			}
+		},
+	)
+
+	t.Run(
+		"No frames",
+		func(innerT *testing.T) {
+			if _, err := Rainbowify(&gif.GIF{}, DefaultOptions()); !errors.Is(err, ErrNoFrames) {
+				innerT.Errorf("Expected %v but got %v", ErrNoFrames, err)
+			}
+		},
+	)
+
+	t.Run(
+		"Unsupported format",
+		func(innerT *testing.T) {
+			_, _, err := DecodeImage(bytes.NewReader([]byte("not an image at all")), "populosity")
+			if !errors.Is(err, ErrUnsupportedFormat) {
+				innerT.Errorf("Expected %v but got %v", ErrUnsupportedFormat, err)
+			}
+		},
+	)
+}
+
+func TestRainbowifyMaxFrames(t *testing.T) {
+	src := newTestGIF(4, 2, 2)
+
+	opts := DefaultOptions()
+	opts.MaxFrames = 100
+
+	// far more frames than there is memory for, so allocating them would crash the test
+	opts.LoopCount = 1 << 30
+	if _, err := Rainbowify(src, opts); !errors.Is(err, ErrTooManyFrames) {
+		t.Errorf("Expected %v but got %v", ErrTooManyFrames, err)
+	}
+
+	opts.LoopCount = 25
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(out.Image) != 100 {
+		t.Errorf("Expected %v but got %v", 100, len(out.Image))
+	}
+}
+
+func TestRainbowifyMaxFramesProjected(t *testing.T) {
+	src := newTestGIF(4, 2, 2)
+
+	cases := []struct {
+		name     string
+		modify   func(opts *Options)
+		expected int
+	}{
+		{name: "Interpolated", modify: func(opts *Options) { opts.InterpolateFrames = 24 }, expected: 100},
+		{name: "Interpolated and looped", modify: func(opts *Options) { opts.InterpolateFrames = 4; opts.LoopCount = 5 }, expected: 100},
+		{name: "Every", modify: func(opts *Options) { opts.Every = 3; opts.InterpolateFrames = 49 }, expected: 100},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				opts := DefaultOptions()
+				opts.MaxFrames = 100
+				c.modify(&opts)
+
+				out, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+				if len(out.Image) != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, len(out.Image))
+				}
+
+				// one frame past the limit is caught the same way
+				opts.MaxFrames--
+				if _, err := Rainbowify(src, opts); !errors.Is(err, ErrTooManyFrames) {
+					innerT.Errorf("Expected %v but got %v", ErrTooManyFrames, err)
+				}
+			},
+		)
+	}
+
+	// interpolating these would run out of memory long before the frames are counted afterwards
+	huge := []func(opts *Options){
+		func(opts *Options) { opts.InterpolateFrames = 1 << 40 },
+		func(opts *Options) { opts.Coalesce = true; opts.InterpolateFrames = 1 << 40 },
+		func(opts *Options) { opts.InterpolateFrames = math.MaxInt },
+	}
+
+	for i, modify := range huge {
+		opts := DefaultOptions()
+		opts.MaxFrames = 100
+		modify(&opts)
+
+		if _, err := Rainbowify(src, opts); !errors.Is(err, ErrTooManyFrames) {
+			t.Errorf("%d - expected %v but got %v", i, ErrTooManyFrames, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package rainbow_test
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+
+	"github.com/jwoos/rainbowgif/rainbow"
+)
+
+func ExampleRainbowify() {
+	palette := color.Palette{color.White, color.Black}
+	src := &gif.GIF{
+		Image: []*image.Paletted{
+			image.NewPaletted(image.Rect(0, 0, 8, 8), palette),
+			image.NewPaletted(image.Rect(0, 0, 8, 8), palette),
+		},
+		Delay: []int{10, 20},
+	}
+
+	opts := rainbow.DefaultOptions()
+	opts.LoopCount = 2
+	opts.Threads = 2
+
+	out, err := rainbow.Rainbowify(src, opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(len(out.Image), out.Delay)
+	// Output: 4 [10 20 10 20]
+}
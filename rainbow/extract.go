@@ -0,0 +1,181 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// a color and how many pixels have it
+type weightedColor struct {
+	color color.NRGBA
+	count int
+}
+
+/* GradientFromImage picks up to n representative colors of img to use as gradient stops
+ * the colors are found with a median cut over the opaque pixels and ordered by hue
+ */
+func GradientFromImage(img image.Image, n int) ([]colorful.Color, error) {
+	if n < 1 {
+		return nil, ErrEmptyGradient
+	}
+
+	counts := make(map[color.NRGBA]int)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			// mostly transparent pixels aren't really part of the picture
+			if c.A < 128 {
+				continue
+			}
+
+			c.A = 255
+			counts[c]++
+		}
+	}
+
+	if len(counts) == 0 {
+		return nil, errors.New("Image has no opaque pixels")
+	}
+
+	bucket := make([]weightedColor, 0, len(counts))
+	for c, count := range counts {
+		bucket = append(bucket, weightedColor{color: c, count: count})
+	}
+
+	colors := make([]colorful.Color, 0, n)
+	for _, box := range medianCutBoxes(bucket, n) {
+		colors = append(colors, averageColor(box))
+	}
+
+	sort.Slice(colors, func(i int, j int) bool {
+		iHue, _, _ := colors[i].Hsv()
+		jHue, _, _ := colors[j].Hsv()
+		return iHue < jHue
+	})
+
+	return colors, nil
+}
+
+/* splits the colors into up to n boxes, always cutting the box with the widest channel
+ * at its weighted median - boxes holding a single color can't be cut any further
+ */
+func medianCutBoxes(bucket []weightedColor, n int) [][]weightedColor {
+	boxes := [][]weightedColor{bucket}
+
+	for len(boxes) < n {
+		widest := -1
+		widestChannel := 0
+		widestRange := 0
+		for i, box := range boxes {
+			channel, channelRange := widestChannelOf(box)
+			if channelRange > widestRange {
+				widest = i
+				widestChannel = channel
+				widestRange = channelRange
+			}
+		}
+
+		// every box is a single color
+		if widest == -1 {
+			break
+		}
+
+		box := boxes[widest]
+		sort.Slice(box, func(i int, j int) bool {
+			a, b := channelOf(box[i].color, widestChannel), channelOf(box[j].color, widestChannel)
+			if a != b {
+				return a < b
+			}
+
+			// buckets come out of maps in any order, ties go by the whole color so the same colors always cut the same way
+			return packNRGBA(box[i].color) < packNRGBA(box[j].color)
+		})
+
+		var total int
+		for _, c := range box {
+			total += c.count
+		}
+
+		// cut after the color that reaches half the pixels, keeping at least one color on each side
+		cut := 1
+		var seen int
+		for i, c := range box[:len(box)-1] {
+			seen += c.count
+			cut = i + 1
+			if seen*2 >= total {
+				break
+			}
+		}
+
+		boxes[widest] = box[:cut]
+		boxes = append(boxes, box[cut:])
+	}
+
+	return boxes
+}
+
+func packNRGBA(c color.NRGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// the channel (0 red, 1 green, 2 blue) with the largest spread and that spread
+func widestChannelOf(box []weightedColor) (int, int) {
+	widest := 0
+	widestRange := 0
+
+	for channel := 0; channel < 3; channel++ {
+		low := 255
+		high := 0
+		for _, c := range box {
+			value := int(channelOf(c.color, channel))
+			if value < low {
+				low = value
+			}
+			if value > high {
+				high = value
+			}
+		}
+
+		if high-low > widestRange {
+			widest = channel
+			widestRange = high - low
+		}
+	}
+
+	return widest, widestRange
+}
+
+func channelOf(c color.NRGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	default:
+		return c.B
+	}
+}
+
+// the pixel weighted average of a box
+func averageColor(box []weightedColor) colorful.Color {
+	var r, g, b float64
+	var total int
+
+	for _, c := range box {
+		r += float64(c.color.R) * float64(c.count)
+		g += float64(c.color.G) * float64(c.count)
+		b += float64(c.color.B) * float64(c.count)
+		total += c.count
+	}
+
+	return colorful.Color{
+		R: r / float64(total) / 255,
+		G: g / float64(total) / 255,
+		B: b / float64(total) / 255,
+	}
+}
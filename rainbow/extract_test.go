@@ -0,0 +1,75 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestGradientFromImage(t *testing.T) {
+	t.Run(
+		"Solid red",
+		func(innerT *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+			draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+
+			colors, err := GradientFromImage(img, 5)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(colors) != 1 {
+				innerT.Fatalf("Expected %v but got %v", 1, len(colors))
+			}
+
+			if colors[0].Hex() != "#ff0000" {
+				innerT.Errorf("Expected %v but got %v", "#ff0000", colors[0].Hex())
+			}
+		},
+	)
+
+	t.Run(
+		"Stripes are ordered by hue",
+		func(innerT *testing.T) {
+			stripes := []color.RGBA{
+				{R: 0, G: 0, B: 255, A: 255},
+				{R: 255, G: 0, B: 0, A: 255},
+				{R: 0, G: 255, B: 0, A: 255},
+			}
+			img := image.NewRGBA(image.Rect(0, 0, 3, 4))
+			for x, stripe := range stripes {
+				for y := 0; y < 4; y++ {
+					img.Set(x, y, stripe)
+				}
+			}
+			// a transparent pixel shouldn't turn into a stop
+			img.Set(0, 0, color.RGBA{})
+
+			colors, err := GradientFromImage(img, 3)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := []string{"#ff0000", "#00ff00", "#0000ff"}
+			if len(colors) != len(expected) {
+				innerT.Fatalf("Expected %v but got %v", len(expected), len(colors))
+			}
+
+			for i, hex := range expected {
+				if colors[i].Hex() != hex {
+					innerT.Errorf("Expected %v but got %v", hex, colors[i].Hex())
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Fully transparent",
+		func(innerT *testing.T) {
+			if _, err := GradientFromImage(image.NewRGBA(image.Rect(0, 0, 2, 2)), 3); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,372 @@
+package rainbow
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type interpolationFunc func(from colorful.Color, to colorful.Color, t float64) colorful.Color
+
+func getInterpolationFunc(space string) (interpolationFunc, error) {
+	switch space {
+	case "rgb":
+		return colorful.Color.BlendRgb, nil
+	case "hsv":
+		return colorful.Color.BlendHsv, nil
+	case "hcl":
+		return colorful.Color.BlendHcl, nil
+	case "lab":
+		return colorful.Color.BlendLab, nil
+	default:
+		return nil, errors.New("Invalid interpolation")
+	}
+}
+
+type Gradient struct {
+	colors    []colorful.Color
+	positions []float64
+	// per stop opacity, in the same order as the colors given to newGradient - nil means fully opaque
+	opacities []float64
+	// how colors between two stops are blended, HCL by default
+	interpolate interpolationFunc
+	// the number of full sweeps through the gradient across all frames
+	cycles uint
+	// run the sweep from the last frame to the first
+	reverse bool
+	// offset in [0, 1) added to every position, wrapping around the end
+	phase float64
+	// reshapes the sweep over time, nil is linear
+	easing easingFunc
+	// sweep forward then back once per cycle instead of only forward
+	bounce bool
+	// the last color leads back into the first, so frames are spread over [0, 1) for a seamless loop
+	wrap bool
+	// start the sweep over every this many frames instead of spreading it across all of them, 0 never starts over
+	loopLength uint
+	// when non zero a sweep takes this many 100ths of a second, and frames are placed by when they start rather than by their index
+	sweepDuration float64
+	// every frame's delay in 100ths of a second, only used with sweepDuration
+	delays []int
+}
+
+type GradientKeyFrame struct {
+	color    colorful.Color
+	position float64
+	index    int
+}
+
+/* a color on the gradient
+ * Pos places it in [0, 1], a negative Pos spreads it evenly between its neighbours
+ */
+type Stop struct {
+	Color colorful.Color
+	Pos   float64
+}
+
+func newGradient(colors []colorful.Color, wrap bool) Gradient {
+	stops := make([]Stop, len(colors))
+	for i, color := range colors {
+		stops[i] = Stop{Color: color, Pos: -1}
+	}
+
+	return newGradientStops(stops, wrap)
+}
+
+/* builds a gradient out of stops that may or may not have positions
+ * wrapping adds the first color again at the end so the gradient loops,
+ * unless the last stop was explicitly put at the end already
+ */
+func newGradientStops(stops []Stop, wrap bool) Gradient {
+	if wrap && len(stops) > 1 && stops[len(stops)-1].Pos != 1 {
+		// wrap around
+		stops = append(stops[:len(stops):len(stops)], Stop{Color: stops[0].Color, Pos: 1})
+	}
+
+	gradient := Gradient{
+		colors:      make([]colorful.Color, len(stops)),
+		positions:   stopPositions(stops),
+		cycles:      1,
+		interpolate: colorful.Color.BlendHcl,
+		wrap:        wrap,
+	}
+
+	for i, stop := range stops {
+		gradient.colors[i] = stop.Color
+	}
+
+	return gradient
+}
+
+/* fills in the positions of stops without one
+ * the first and last default to 0 and 1, anything else is spread evenly between the closest positioned stops
+ */
+func stopPositions(stops []Stop) []float64 {
+	positions := make([]float64, len(stops))
+	for i, stop := range stops {
+		positions[i] = stop.Pos
+	}
+
+	if len(positions) == 0 {
+		return positions
+	}
+
+	if positions[0] < 0 {
+		positions[0] = 0
+	}
+
+	if len(positions) == 1 {
+		return positions
+	}
+
+	if positions[len(positions)-1] < 0 {
+		positions[len(positions)-1] = 1
+	}
+
+	// the first stop always has a position by now
+	last := 0
+	for i := 1; i < len(positions); i++ {
+		if positions[i] < 0 {
+			continue
+		}
+
+		// distribute the unpositioned stops between last and i evenly
+		for j := last + 1; j < i; j++ {
+			positions[j] = positions[last] + (positions[i]-positions[last])*float64(j-last)/float64(i-last)
+		}
+		last = i
+	}
+
+	return positions
+}
+
+// positions must be in [0, 1] and never go backwards, negative positions are skipped
+func validateStops(stops []Stop) error {
+	last := 0.0
+	for _, stop := range stops {
+		if stop.Pos < 0 {
+			continue
+		}
+
+		if stop.Pos > 1 {
+			return fmt.Errorf("Invalid position %v: must be between 0 and 1", stop.Pos)
+		}
+
+		if stop.Pos < last {
+			return fmt.Errorf("Invalid position %v: positions can't be lower than the ones before them", stop.Pos)
+		}
+		last = stop.Pos
+	}
+
+	return nil
+}
+
+func (gradient Gradient) generate(frameCount uint) []colorful.Color {
+	generated := make([]colorful.Color, frameCount)
+
+	for i, position := range gradient.framePositions(frameCount) {
+		generated[i] = gradient.at(position)
+	}
+
+	return generated
+}
+
+// the opacity of every frame, following the same positions as generate
+func (gradient Gradient) generateOpacity(frameCount uint) []float64 {
+	generated := make([]float64, frameCount)
+
+	for i, position := range gradient.framePositions(frameCount) {
+		generated[i] = gradient.opacityAt(position)
+	}
+
+	return generated
+}
+
+func (gradient Gradient) framePositions(frameCount uint) []float64 {
+	if gradient.sweepDuration > 0 {
+		return gradient.timePositions(frameCount)
+	}
+
+	positions := make([]float64, frameCount)
+
+	sweepCount := frameCount
+	if gradient.loopLength > 0 && gradient.loopLength < frameCount {
+		sweepCount = gradient.loopLength
+	}
+
+	for i := uint(0); i < sweepCount; i++ {
+		positions[i] = gradient.framePosition(i, sweepCount)
+	}
+
+	if gradient.reverse {
+		for i, j := 0, int(sweepCount)-1; i < j; i, j = i+1, j-1 {
+			positions[i], positions[j] = positions[j], positions[i]
+		}
+	}
+
+	// every later loop repeats the first one's sweep
+	for i := sweepCount; i < frameCount; i++ {
+		positions[i] = positions[i%sweepCount]
+	}
+
+	return positions
+}
+
+/* maps a frame to its position on the gradient, sweeping through it once per cycle
+ * a wrapped gradient ends where it starts, so the last frame stops one step short of the end
+ * and looping the animation doesn't show the same color twice in a row
+ */
+func (gradient Gradient) framePosition(frameIndex uint, frameCount uint) float64 {
+	if frameCount <= 1 {
+		return wrapPosition(gradient.phase)
+	}
+
+	steps := float64(frameCount - 1)
+	if gradient.wrap {
+		steps = float64(frameCount)
+	}
+
+	position := gradient.ease(float64(frameIndex) / steps * float64(gradient.cycles))
+	if gradient.bounce {
+		position = triangleWave(position)
+	}
+
+	return wrapPosition(position + gradient.phase)
+}
+
+/* places every frame by the time it starts at, so a sweep takes sweepDuration however many frames it's spread over
+ * the time starts over every loopLength frames, the same way the index does otherwise
+ */
+func (gradient Gradient) timePositions(frameCount uint) []float64 {
+	positions := make([]float64, frameCount)
+
+	elapsed := 0
+	for i := range positions {
+		if gradient.loopLength > 0 && uint(i)%gradient.loopLength == 0 {
+			elapsed = 0
+		}
+
+		position := gradient.ease(float64(elapsed) / gradient.sweepDuration)
+		if gradient.reverse {
+			position = -position
+		}
+		if gradient.bounce {
+			position = triangleWave(position)
+		}
+
+		positions[i] = wrapPosition(position + gradient.phase)
+
+		if len(gradient.delays) > 0 {
+			elapsed += gradient.delays[i%len(gradient.delays)]
+		}
+	}
+
+	return positions
+}
+
+// eases every sweep by itself, so each cycle speeds up and slows down the same way
+func (gradient Gradient) ease(sweeps float64) float64 {
+	if gradient.easing == nil {
+		return sweeps
+	}
+
+	whole := math.Floor(sweeps)
+	return whole + gradient.easing(sweeps-whole)
+}
+
+// goes from 0 up to 1 and back down to 0 over every whole number
+func triangleWave(position float64) float64 {
+	return 1 - math.Abs(2*(position-math.Floor(position))-1)
+}
+
+/* wraps a position back into [0, 1]
+ * the end of a sweep stays at 1 rather than jumping back to 0
+ */
+func wrapPosition(position float64) float64 {
+	wrapped := position - math.Floor(position)
+
+	if wrapped == 0 && position > 0 {
+		return 1
+	}
+
+	return wrapped
+}
+
+// samples the gradient at a position between 0 and 1
+func (gradient Gradient) at(position float64) colorful.Color {
+	keyframes := gradient.positionSearch(position)
+
+	// exactly on a stop, no need to blend
+	if len(keyframes) == 1 || position == keyframes[0].position {
+		return keyframes[0].color.Clamped()
+	}
+
+	relativePosition := (position - keyframes[0].position) / (keyframes[1].position - keyframes[0].position)
+	return gradient.interpolate(keyframes[0].color, keyframes[1].color, relativePosition).Clamped()
+}
+
+// samples the per stop opacities at a position between 0 and 1
+func (gradient Gradient) opacityAt(position float64) float64 {
+	if len(gradient.opacities) == 0 {
+		return 1
+	}
+
+	keyframes := gradient.positionSearch(position)
+
+	// the wrapped around stop shares the first stop's opacity
+	lower := gradient.opacities[keyframes[0].index%len(gradient.opacities)]
+	if len(keyframes) == 1 || position == keyframes[0].position {
+		return lower
+	}
+
+	upper := gradient.opacities[keyframes[1].index%len(gradient.opacities)]
+	relativePosition := (position - keyframes[0].position) / (keyframes[1].position - keyframes[0].position)
+	return lower + (upper-lower)*relativePosition
+}
+
+/* finds the stops on either side of position
+ * a single stop is returned when position is at or past the last stop, or before the first
+ */
+func (gradient Gradient) positionSearch(position float64) []GradientKeyFrame {
+	// the last stop at or before position, stops sharing a position make a hard edge
+	upperIndex := sort.Search(len(gradient.positions), func(i int) bool {
+		return gradient.positions[i] > position
+	})
+	lowerIndex := upperIndex - 1
+
+	if lowerIndex < 0 {
+		return []GradientKeyFrame{
+			{
+				color:    gradient.colors[0],
+				position: gradient.positions[0],
+				index:    0,
+			},
+		}
+	}
+
+	if upperIndex >= len(gradient.colors) {
+		return []GradientKeyFrame{
+			{
+				color:    gradient.colors[lowerIndex],
+				position: gradient.positions[lowerIndex],
+				index:    lowerIndex,
+			},
+		}
+	}
+
+	return []GradientKeyFrame{
+		{
+			color:    gradient.colors[lowerIndex],
+			position: gradient.positions[lowerIndex],
+			index:    lowerIndex,
+		},
+		{
+			color:    gradient.colors[upperIndex],
+			position: gradient.positions[upperIndex],
+			index:    upperIndex,
+		},
+	}
+}
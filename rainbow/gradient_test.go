@@ -0,0 +1,1160 @@
+package rainbow
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestNewGradient(t *testing.T) {
+	t.Run(
+		"Zero color",
+		func(innerT *testing.T) {
+			gradient := newGradient(
+				[]colorful.Color{},
+				false,
+			)
+
+			if len(gradient.positions) != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, len(gradient.positions))
+			}
+		},
+	)
+
+	t.Run(
+		"One color",
+		func(innerT *testing.T) {
+			gradient := newGradient(
+				[]colorful.Color{
+					{R: 0, G: 0, B: 0},
+				},
+				false,
+			)
+
+			if len(gradient.positions) != 1 {
+				innerT.Errorf("Expected %v but got %v", 1, len(gradient.positions))
+			}
+
+			if gradient.positions[0] != 0.0 {
+				innerT.Errorf("Expected %v but got %v", 0.0, gradient.positions[0])
+			}
+		},
+	)
+
+	t.Run(
+		"Two colors",
+		func(innerT *testing.T) {
+			gradient := newGradient(
+				[]colorful.Color{
+					{R: 0, G: 0, B: 0},
+					{R: 1, G: 1, B: 1},
+				},
+				false,
+			)
+
+			if len(gradient.positions) != 2 {
+				innerT.Errorf("Expected %v but got %v", 2, len(gradient.positions))
+			}
+
+			if gradient.positions[0] != 0.0 {
+				innerT.Errorf("Expected %v but got %v", 0.0, gradient.positions[0])
+			}
+
+			if gradient.positions[1] != 1.0 {
+				innerT.Errorf("%v", gradient.colors)
+				innerT.Errorf("%v", gradient.positions)
+				innerT.Errorf("Expected %v but got %v", 1.0, gradient.positions[1])
+			}
+		},
+	)
+}
+
+func TestPositionSearch(t *testing.T) {
+	t.Run(
+		"One color",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+			}
+			gradient := newGradient(colors, false)
+
+			for i := 0.0; i <= 1.0; i += 0.1 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[0] {
+					innerT.Errorf(
+						"Expected %v but got %v",
+						colors[0],
+						returnedKeyFrames[0].color,
+					)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Two colors",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+
+			for i := 0.0; i <= 1.0; i += 0.1 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[0] {
+					innerT.Errorf(
+						"Expected %v but got %v",
+						colors[0],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[1] {
+					innerT.Errorf(
+						"Expected %v but got %v",
+						colors[1],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Three colors",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 0.5, G: 0.5, B: 0.5},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+
+			for i := 0.0; i < 0.5; i += 0.1 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[0] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[0],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[1] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[1],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+
+			for i := 0.5; i <= 1.0; i += 0.1 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[1] {
+					innerT.Errorf(
+						"Expected %v but got %v",
+						colors[1],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[2] {
+					innerT.Errorf(
+						"Expected %v but got %v",
+						colors[2],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Three colors",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 0.33, G: 0.33, B: 0.33},
+				{R: 0.66, G: 0.66, B: 0.66},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+
+			for i := 0.0; i <= 0.33; i += 0.03 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[0] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[0],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[1] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[1],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+
+			for i := 0.34; i < 0.66; i += 0.03 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[1] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[1],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[2] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[2],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+
+			for i := 0.67; i <= 1; i += 0.03 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[2] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[2],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[3] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[3],
+						returnedKeyFrames[0].color,
+					)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Four colors",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 0.25, G: 0.25, B: 0.25},
+				{R: 0.5, G: 0.5, B: 0.5},
+				{R: 0.75, G: 0.75, B: 0.75},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+
+			for i := 0.0; i < 0.25; i += 0.05 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[0] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[0],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[1] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[1],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+
+			for i := 0.26; i < 0.5; i += 0.05 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[1] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[1],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[2] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[2],
+						returnedKeyFrames[1].color,
+					)
+				}
+			}
+
+			for i := 0.51; i < 0.75; i += 0.05 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[2] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[2],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[3] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[3],
+						returnedKeyFrames[0].color,
+					)
+				}
+			}
+
+			for i := 0.76; i <= 1; i += 0.05 {
+				returnedKeyFrames := gradient.positionSearch(i)
+
+				if returnedKeyFrames[0].color != colors[3] {
+					innerT.Errorf(
+						"positionSearch(%f)[0] - expected %v but got %v",
+						i,
+						colors[3],
+						returnedKeyFrames[0].color,
+					)
+				}
+
+				if returnedKeyFrames[1].color != colors[4] {
+					innerT.Errorf(
+						"positionSearch(%f)[1] - expected %v but got %v",
+						i,
+						colors[4],
+						returnedKeyFrames[0].color,
+					)
+				}
+			}
+		},
+	)
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run(
+		"Two colors - two frames",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(2)
+
+			if len(generated) != 2 {
+				innerT.Errorf("Expected %v but got %v", 2, len(generated))
+			}
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			if generated[1] != colors[1] {
+				innerT.Errorf("Expected %v but got %v", colors[1], generated[1])
+			}
+		},
+	)
+
+	t.Run(
+		"Two colors - three frames",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(3)
+
+			if len(generated) != 3 {
+				innerT.Errorf("Expected %v but got %v", 2, len(generated))
+			}
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			if generated[1] == colors[0] || generated[1] == colors[1] {
+				innerT.Errorf("Expected %v but got %v", nil, generated[0])
+			}
+
+			if generated[2] != colors[1] {
+				innerT.Errorf("Expected %v but got %v", colors[1], generated[2])
+			}
+		},
+	)
+
+	t.Run(
+		"Two colors - four frames",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(4)
+
+			if len(generated) != 4 {
+				innerT.Errorf("Expected %v but got %v", 2, len(generated))
+			}
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			if generated[1] == colors[0] || generated[1] == colors[1] {
+				innerT.Errorf("Expected %v but got %v", nil, generated[1])
+			}
+
+			if generated[2] == colors[0] || generated[2] == colors[1] {
+				innerT.Errorf("Expected %v but got %v", nil, generated[2])
+			}
+
+			if generated[3] != colors[1] {
+				innerT.Errorf("Expected %v but got %v", colors[1], generated[3])
+			}
+		},
+	)
+
+	t.Run(
+		"Three colors - two frames",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 0.5, G: 0.5, B: 0.5},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(2)
+
+			if len(generated) != 2 {
+				innerT.Errorf("Expected %v but got %v", 2, len(generated))
+			}
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			if generated[1] != colors[2] {
+				innerT.Errorf("Expected %v but got %v", colors[2], generated[1])
+			}
+		},
+	)
+
+	t.Run(
+		"Three colors - three frames",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 0.5, G: 0.5, B: 0.5},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(3)
+
+			if len(generated) != 3 {
+				innerT.Errorf("Expected %v but got %v", 3, len(generated))
+			}
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			/*
+			 *if generated[1] != colors[1] {
+			 *    innerT.Errorf("Expected %v but got %v", colors[1], generated[1])
+			 *}
+			 */
+
+			if generated[2] != colors[2] {
+				innerT.Errorf("Expected %v but got %v", colors[2], generated[2])
+			}
+		},
+	)
+
+	t.Run(
+		"Three colors - four frames",
+		func(innerT *testing.T) {
+			colors := []colorful.Color{
+				{R: 0, G: 0, B: 0},
+				{R: 0.5, G: 0.5, B: 0.5},
+				{R: 1, G: 1, B: 1},
+			}
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(4)
+
+			if len(generated) != 4 {
+				innerT.Errorf("Expected %v but got %v", 3, len(generated))
+			}
+
+			/*
+			 *if generated[0] != colors[0] {
+			 *    innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			 *}
+			 */
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			if generated[3] != colors[2] {
+				innerT.Errorf("Expected %v but got %v", colors[2], generated[3])
+			}
+		},
+	)
+}
+
+func TestGenerateCycles(t *testing.T) {
+	colors := []colorful.Color{
+		{R: 0, G: 0, B: 0},
+		{R: 1, G: 1, B: 1},
+	}
+
+	t.Run(
+		"Two cycles",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, false)
+			gradient.cycles = 2
+			generated := gradient.generate(5)
+
+			if len(generated) != 5 {
+				innerT.Fatalf("Expected %v but got %v", 5, len(generated))
+			}
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+
+			if generated[2] != colors[1] || generated[4] != colors[1] {
+				innerT.Errorf("Expected %v but got %v and %v", colors[1], generated[2], generated[4])
+			}
+
+			if generated[1] != generated[3] {
+				innerT.Errorf("Expected %v but got %v", generated[1], generated[3])
+			}
+		},
+	)
+
+	t.Run(
+		"Zero and one frames",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, false)
+
+			if generated := gradient.generate(0); len(generated) != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, len(generated))
+			}
+
+			if generated := gradient.generate(1); generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+		},
+	)
+}
+
+func TestGenerateReverse(t *testing.T) {
+	colors := []colorful.Color{
+		{R: 1, G: 0, B: 0},
+		{R: 0, G: 1, B: 0},
+		{R: 0, G: 0, B: 1},
+	}
+
+	for _, cycles := range []uint{1, 2} {
+		t.Run(
+			fmt.Sprintf("%d cycles", cycles),
+			func(innerT *testing.T) {
+				forward := newGradient(colors, true)
+				forward.cycles = cycles
+				backward := newGradient(colors, true)
+				backward.cycles = cycles
+				backward.reverse = true
+
+				forwardGenerated := forward.generate(7)
+				backwardGenerated := backward.generate(7)
+
+				for i := range forwardGenerated {
+					if forwardGenerated[i] != backwardGenerated[len(backwardGenerated)-1-i] {
+						innerT.Errorf(
+							"Expected %v but got %v",
+							forwardGenerated[i],
+							backwardGenerated[len(backwardGenerated)-1-i],
+						)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestGeneratePhase(t *testing.T) {
+	colors := []colorful.Color{
+		{R: 1, G: 0, B: 0},
+		{R: 0, G: 1, B: 0},
+		{R: 0, G: 0, B: 1},
+	}
+
+	t.Run(
+		"Phase 0 is unchanged",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			generated := gradient.generate(4)
+
+			if generated[0] != colors[0] {
+				innerT.Errorf("Expected %v but got %v", colors[0], generated[0])
+			}
+		},
+	)
+
+	t.Run(
+		"Phase offsets and wraps positions",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			gradient.phase = 0.5
+			generated := gradient.generate(4)
+
+			for i, expectedPosition := range []float64{0.5, 0.75, 1, 0.25} {
+				expected := gradient.at(expectedPosition)
+				if generated[i] != expected {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected, generated[i])
+				}
+			}
+		},
+	)
+}
+
+func TestGenerateSeamless(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hueDelta := func(a colorful.Color, b colorful.Color) float64 {
+		aHue, _, _ := a.Hcl()
+		bHue, _, _ := b.Hcl()
+		delta := math.Abs(aHue - bHue)
+		return math.Min(delta, 360-delta)
+	}
+
+	t.Run(
+		"Last frame leads into the first",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			generated := gradient.generate(24)
+
+			// a sweep through 6 stops over 24 frames moves a quarter of a stop per frame
+			loopDelta := hueDelta(generated[len(generated)-1], generated[0])
+			if loopDelta == 0 || loopDelta > 30 {
+				innerT.Errorf("Expected a small step but got %v", loopDelta)
+			}
+		},
+	)
+
+	t.Run(
+		"Half a phase swaps the halves",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			unshifted := gradient.generate(8)
+
+			gradient.phase = 0.5
+			shifted := gradient.generate(8)
+
+			for i := range shifted {
+				expected := unshifted[(i+4)%len(unshifted)]
+				if shifted[i] != expected {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected, shifted[i])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Not wrapped ends on the last color",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, false)
+			generated := gradient.generate(8)
+
+			if generated[len(generated)-1] != colors[len(colors)-1] {
+				innerT.Errorf("Expected %v but got %v", colors[len(colors)-1], generated[len(generated)-1])
+			}
+		},
+	)
+}
+
+func TestGradientInterpolation(t *testing.T) {
+	colors := []colorful.Color{
+		{R: 1, G: 0, B: 0},
+		{R: 0, G: 1, B: 0},
+	}
+
+	midpoints := make(map[string]colorful.Color)
+	for _, space := range []string{"rgb", "hsv", "hcl", "lab"} {
+		interpolate, err := getInterpolationFunc(space)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+
+		gradient := newGradient(colors, false)
+		gradient.interpolate = interpolate
+		midpoints[space] = gradient.at(0.5)
+	}
+
+	t.Run(
+		"RGB midpoint",
+		func(innerT *testing.T) {
+			expected := colorful.Color{R: 0.5, G: 0.5, B: 0}
+			if !midpoints["rgb"].AlmostEqualRgb(expected) {
+				innerT.Errorf("Expected %v but got %v", expected, midpoints["rgb"])
+			}
+		},
+	)
+
+	t.Run(
+		"HCL midpoint differs from RGB",
+		func(innerT *testing.T) {
+			if midpoints["hcl"].AlmostEqualRgb(midpoints["rgb"]) {
+				innerT.Errorf("Expected %v to differ from %v", midpoints["hcl"], midpoints["rgb"])
+			}
+
+			// the perceptual blend doesn't dip in lightness like RGB does
+			_, _, hclLuma := midpoints["hcl"].Hcl()
+			_, _, rgbLuma := midpoints["rgb"].Hcl()
+			if hclLuma <= rgbLuma {
+				innerT.Errorf("Expected luma above %v but got %v", rgbLuma, hclLuma)
+			}
+		},
+	)
+
+	t.Run(
+		"Invalid interpolation",
+		func(innerT *testing.T) {
+			if _, err := getInterpolationFunc("cmyk"); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
+
+func TestGenerateOpacity(t *testing.T) {
+	colors := []colorful.Color{
+		{R: 1, G: 0, B: 0},
+		{R: 0, G: 0, B: 1},
+	}
+
+	t.Run(
+		"No opacities",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+
+			for _, opacity := range gradient.generateOpacity(4) {
+				if opacity != 1 {
+					innerT.Errorf("Expected %v but got %v", 1, opacity)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Interpolated opacities",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, false)
+			gradient.opacities = []float64{0, 1}
+			generated := gradient.generateOpacity(5)
+
+			for i, expected := range []float64{0, 0.25, 0.5, 0.75, 1} {
+				if math.Abs(generated[i]-expected) > 1e-9 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected, generated[i])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Wrapped stop reuses the first opacity",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			gradient.opacities = []float64{0.2, 1}
+
+			if opacity := gradient.opacityAt(1); opacity != 0.2 {
+				innerT.Errorf("Expected %v but got %v", 0.2, opacity)
+			}
+		},
+	)
+}
+
+func TestGradientStops(t *testing.T) {
+	red := colorful.Color{R: 1, G: 0, B: 0}
+	green := colorful.Color{R: 0, G: 1, B: 0}
+	blue := colorful.Color{R: 0, G: 0, B: 1}
+
+	cases := []struct {
+		name      string
+		stops     []Stop
+		wrap      bool
+		positions []float64
+	}{
+		{
+			name:      "Explicit",
+			stops:     []Stop{{Color: red, Pos: 0}, {Color: green, Pos: 0.25}, {Color: blue, Pos: 1}},
+			positions: []float64{0, 0.25, 1},
+		},
+		{
+			name:      "Mixed",
+			stops:     []Stop{{Color: red, Pos: -1}, {Color: green, Pos: 0.5}, {Color: blue, Pos: -1}, {Color: red, Pos: -1}},
+			positions: []float64{0, 0.5, 0.75, 1},
+		},
+		{
+			name:      "Mixed wrapped",
+			stops:     []Stop{{Color: red, Pos: 0.2}, {Color: green, Pos: -1}, {Color: blue, Pos: -1}},
+			wrap:      true,
+			positions: []float64{0.2, 0.4666666666666667, 0.7333333333333334, 1},
+		},
+		{
+			name:      "Wrapped ending at 1",
+			stops:     []Stop{{Color: red, Pos: -1}, {Color: blue, Pos: 1}},
+			wrap:      true,
+			positions: []float64{0, 1},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				gradient := newGradientStops(c.stops, c.wrap)
+
+				if !reflect.DeepEqual(gradient.positions, c.positions) {
+					innerT.Errorf("Expected %v but got %v", c.positions, gradient.positions)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Samples follow the positions",
+		func(innerT *testing.T) {
+			gradient := newGradientStops([]Stop{{Color: red, Pos: 0}, {Color: green, Pos: 0.25}, {Color: blue, Pos: 1}}, false)
+			gradient.interpolate = colorful.Color.BlendRgb
+
+			cases := []struct {
+				position float64
+				expected colorful.Color
+			}{
+				{position: 0, expected: red},
+				{position: 0.125, expected: colorful.Color{R: 0.5, G: 0.5, B: 0}},
+				{position: 0.25, expected: green},
+				{position: 0.625, expected: colorful.Color{R: 0, G: 0.5, B: 0.5}},
+				{position: 1, expected: blue},
+			}
+
+			for _, c := range cases {
+				if actual := gradient.at(c.position); !actual.AlmostEqualRgb(c.expected) {
+					innerT.Errorf("At %v - expected %v but got %v", c.position, c.expected, actual)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Before the first stop",
+		func(innerT *testing.T) {
+			gradient := newGradientStops([]Stop{{Color: red, Pos: 0.5}, {Color: blue, Pos: 1}}, false)
+
+			if actual := gradient.at(0.25); actual != red {
+				innerT.Errorf("Expected %v but got %v", red, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Hard edge",
+		func(innerT *testing.T) {
+			gradient := newGradientStops([]Stop{{Color: red, Pos: 0}, {Color: red, Pos: 0.5}, {Color: blue, Pos: 0.5}, {Color: blue, Pos: 1}}, false)
+
+			if actual := gradient.at(0.49); !actual.AlmostEqualRgb(red) {
+				innerT.Errorf("Expected %v but got %v", red, actual)
+			}
+
+			if actual := gradient.at(0.5); actual != blue {
+				innerT.Errorf("Expected %v but got %v", blue, actual)
+			}
+		},
+	)
+}
+
+func TestGenerateEasing(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// how many of 20 frames stay in the first quarter of the gradient
+	nearStart := func(name string) int {
+		easing, err := getEasingFunc(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gradient := newGradient(colors, true)
+		gradient.easing = easing
+
+		var count int
+		for _, position := range gradient.framePositions(20) {
+			if position < 0.25 {
+				count++
+			}
+		}
+
+		return count
+	}
+
+	linear := nearStart("linear")
+
+	t.Run(
+		"Ease in starts slower",
+		func(innerT *testing.T) {
+			if actual := nearStart("ease-in"); actual <= linear {
+				innerT.Errorf("Expected more than %v but got %v", linear, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Ease out starts faster",
+		func(innerT *testing.T) {
+			if actual := nearStart("ease-out"); actual >= linear {
+				innerT.Errorf("Expected less than %v but got %v", linear, actual)
+			}
+		},
+	)
+
+	for _, name := range []string{"linear", "ease-in", "ease-out", "ease-in-out", "sine"} {
+		t.Run(
+			name+" keeps the ends",
+			func(innerT *testing.T) {
+				easing, err := getEasingFunc(name)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				if easing(0) != 0 || easing(1) != 1 {
+					innerT.Errorf("Expected %v but got %v", []float64{0, 1}, []float64{easing(0), easing(1)})
+				}
+			},
+		)
+	}
+}
+
+func TestGenerateEasingCycles(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	easing, err := getEasingFunc("ease-in")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two sweeps of 10 frames each, once by frame count and once by time
+	frames := newGradient(colors, true)
+	frames.cycles = 2
+	frames.easing = easing
+
+	timed := newGradient(colors, true)
+	timed.sweepDuration = 100
+	timed.delays = []int{10}
+	timed.easing = easing
+
+	modes := []struct {
+		name     string
+		gradient Gradient
+	}{
+		{name: "Frames", gradient: frames},
+		{name: "Time", gradient: timed},
+	}
+
+	for _, mode := range modes {
+		t.Run(
+			mode.name+" eases every sweep",
+			func(innerT *testing.T) {
+				positions := mode.gradient.framePositions(20)
+				// the second sweep starts where the first one ends, at 1
+				if positions[0] != 0 || positions[10] != 1 {
+					innerT.Errorf("Expected %v but got %v", []float64{0, 1}, []float64{positions[0], positions[10]})
+				}
+
+				for i := 1; i < 10; i++ {
+					expected := easing(float64(i) / 10)
+					if math.Abs(positions[i]-expected) > 1e-9 || math.Abs(positions[i+10]-expected) > 1e-9 {
+						innerT.Errorf("Expected %v but got %v and %v at %d", expected, positions[i], positions[i+10], i)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestGenerateBounce(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cycles := range []uint{1, 2, 3} {
+		t.Run(
+			fmt.Sprintf("%d cycles", cycles),
+			func(innerT *testing.T) {
+				gradient := newGradient(colors, false)
+				gradient.cycles = cycles
+				gradient.bounce = true
+
+				generated := gradient.generate(13)
+				if generated[0] != generated[len(generated)-1] {
+					innerT.Errorf("Expected %v but got %v", generated[0], generated[len(generated)-1])
+				}
+
+				// every bounce turns around at the last color
+				var turns int
+				for _, position := range gradient.framePositions(13) {
+					if position == 1 {
+						turns++
+					}
+				}
+
+				if turns != int(cycles) {
+					innerT.Errorf("Expected %v but got %v", cycles, turns)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Triangle wave",
+		func(innerT *testing.T) {
+			cases := []struct {
+				position float64
+				expected float64
+			}{
+				{position: 0, expected: 0},
+				{position: 0.25, expected: 0.5},
+				{position: 0.5, expected: 1},
+				{position: 0.75, expected: 0.5},
+				{position: 1, expected: 0},
+				{position: 1.5, expected: 1},
+			}
+
+			for _, c := range cases {
+				if actual := triangleWave(c.position); actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			}
+		},
+	)
+}
+
+func TestGenerateSweepDuration(t *testing.T) {
+	colors := []colorful.Color{
+		{R: 1, G: 0, B: 0},
+		{R: 0, G: 1, B: 0},
+		{R: 0, G: 0, B: 1},
+	}
+
+	// how far along the gradient every frame moves
+	step := func(delay int, duration float64) float64 {
+		gradient := newGradient(colors, true)
+		gradient.sweepDuration = duration
+		gradient.delays = []int{delay}
+
+		positions := gradient.framePositions(4)
+		if positions[0] != 0 {
+			t.Errorf("Expected %v but got %v", 0, positions[0])
+		}
+
+		return positions[1]
+	}
+
+	cases := []struct {
+		name     string
+		delay    int
+		duration float64
+		expected float64
+	}{
+		{name: "A sweep per second", delay: 10, duration: 100, expected: 0.1},
+		{name: "Doubled delays", delay: 20, duration: 100, expected: 0.2},
+		{name: "Doubled duration", delay: 10, duration: 200, expected: 0.05},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				if actual := step(c.delay, c.duration); math.Abs(actual-c.expected) > 1e-9 {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Follows uneven delays",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			gradient.sweepDuration = 100
+			gradient.delays = []int{10, 40, 50}
+
+			expected := []float64{0, 0.1, 0.5, 1, 0.1}
+			positions := gradient.framePositions(5)
+			for i := range expected {
+				if math.Abs(positions[i]-expected[i]) > 1e-9 {
+					innerT.Errorf("Expected %v but got %v", expected, positions)
+					break
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Starts over every loop",
+		func(innerT *testing.T) {
+			gradient := newGradient(colors, true)
+			gradient.sweepDuration = 100
+			gradient.delays = []int{30}
+			gradient.loopLength = 2
+
+			positions := gradient.framePositions(4)
+			if positions[2] != 0 || positions[3] != positions[1] {
+				innerT.Errorf("Expected %v but got %v", []float64{0, 0.3, 0, 0.3}, positions)
+			}
+		},
+	)
+}
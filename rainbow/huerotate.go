@@ -0,0 +1,79 @@
+package rainbow
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* rotates the hue of every palette entry by degrees, keeping saturation and lightness
+ * unlike an overlay this keeps all the colors of the image apart while cycling them
+ */
+func prepareFrameHueRotate(src *image.Paletted, dst *image.Paletted, degrees float64, opacity float64) {
+	copyPixels(dst, src)
+
+	for pixelIndex, pixel := range src.Palette {
+		dst.Palette[pixelIndex] = rotatePixel(pixel, degrees, opacity)
+	}
+}
+
+// rotates a single color in HSL, transparent colors and colors at opacity 0 are returned as is
+func rotatePixel(pixel color.Color, degrees float64, opacity float64) color.Color {
+	convertedPixel, alpha, ok := straightColor(pixel)
+
+	if !ok || opacity == 0 {
+		return pixel
+	}
+
+	convertedPixel = convertedPixel.Clamped()
+
+	hue, saturation, lightness := convertedPixel.Hsl()
+	hue = math.Mod(hue+degrees, 360)
+	rotatedPixel := blendOpacity(colorful.Hsl(hue, saturation, lightness).Clamped(), convertedPixel, opacity)
+
+	// only the color is rotated, the original alpha is kept as is
+	rotatedR, rotatedG, rotatedB := rotatedPixel.RGB255()
+	return color.NRGBA{
+		rotatedR,
+		rotatedG,
+		rotatedB,
+		alpha,
+	}
+}
+
+// like processFrames but every frame gets its hue rotated by its own angle, opacities has one entry per frame
+func processFramesHueRotate(ctx context.Context, frames []*image.Paletted, rotations []float64, opacities []float64, progress func(done int, total int), threads uint) ([]*image.Paletted, error) {
+	frameCount := uint(len(rotations))
+	newFrames := make([]*image.Paletted, frameCount)
+	for i := range newFrames {
+		newFrames[i] = image.NewPaletted(frames[i%len(frames)].Bounds(), nil)
+	}
+
+	cache := newPaletteCache()
+
+	err := forEachFrame(ctx, frameCount, threads, progress, func(frameIndex uint) error {
+		src := frames[frameIndex%uint(len(frames))]
+		dst := newFrames[frameIndex]
+
+		key := newRotationKey(src.Palette, rotations[frameIndex], opacities[frameIndex])
+		if palette, okay := cache.get(key); okay {
+			copyPixels(dst, src)
+			dst.Palette = palette
+			return nil
+		}
+
+		dst.Palette = make(color.Palette, len(src.Palette))
+		prepareFrameHueRotate(src, dst, rotations[frameIndex], opacities[frameIndex])
+		cache.put(key, dst.Palette)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newFrames, nil
+}
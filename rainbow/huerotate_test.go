@@ -0,0 +1,86 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestHueRotate(t *testing.T) {
+	palette := color.Palette{
+		color.NRGBA{R: 128, G: 128, B: 128, A: 255},
+		color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+		color.NRGBA{R: 255, G: 0, B: 0, A: 0},
+	}
+	src := &gif.GIF{
+		Image: []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 3, 1), palette)},
+		Delay: []int{10},
+	}
+
+	opts := DefaultOptions()
+	opts.Mode = "huerotate"
+	opts.LoopCount = 4
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Gray is unchanged",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				if frame.Palette[0] != palette[0] {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, palette[0], frame.Palette[0])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Red advances a quarter turn per frame",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				rotated, _ := colorful.MakeColor(frame.Palette[1])
+				hue, saturation, _ := rotated.Hsl()
+
+				expected := float64(i) * 90
+				if math.Abs(hue-expected) > 1 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected, hue)
+				}
+
+				if math.Abs(saturation-1) > 0.01 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, 1, saturation)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Transparent is unchanged",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				if frame.Palette[2] != palette[2] {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, palette[2], frame.Palette[2])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Spatial isn't supported",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Mode = "huerotate"
+			opts.Spatial = "horizontal"
+
+			if _, err := Rainbowify(src, opts); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,63 @@
+package rainbow
+
+import (
+	"errors"
+	"math"
+)
+
+// scales the opacity of a frame by where it is in the animation
+type rampFunc func(frameIndex uint, frameCount uint) float64
+
+// nil means every frame gets the full opacity
+func getRampFunc(name string) (rampFunc, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "fade-in":
+		return rampFadeIn, nil
+	case "fade-out":
+		return rampFadeOut, nil
+	case "pulse":
+		return rampPulse, nil
+	default:
+		return nil, errors.New("Invalid intensity ramp")
+	}
+}
+
+// from 0 on the first frame to 1 on the last
+func rampProgress(frameIndex uint, frameCount uint) float64 {
+	if frameCount < 2 {
+		return 1
+	}
+
+	return float64(frameIndex) / float64(frameCount-1)
+}
+
+func rampFadeIn(frameIndex uint, frameCount uint) float64 {
+	return rampProgress(frameIndex, frameCount)
+}
+
+func rampFadeOut(frameIndex uint, frameCount uint) float64 {
+	return 1 - rampProgress(frameIndex, frameCount)
+}
+
+/* fades out and back in once over the animation
+ * spread like a seamless loop so the first frame doesn't show up twice in a row
+ */
+func rampPulse(frameIndex uint, frameCount uint) float64 {
+	return 0.5 + 0.5*math.Cos(2*math.Pi*float64(frameIndex)/float64(frameCount))
+}
+
+// the opacity of every frame, base scaled by the ramp
+func frameIntensities(ramp rampFunc, frameCount uint, base float64) []float64 {
+	intensities := make([]float64, frameCount)
+
+	for i := range intensities {
+		intensities[i] = base
+		if ramp != nil {
+			intensities[i] *= ramp(uint(i), frameCount)
+		}
+	}
+
+	return intensities
+}
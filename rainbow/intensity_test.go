@@ -0,0 +1,68 @@
+package rainbow
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrameIntensities(t *testing.T) {
+	t.Run(
+		"Fade in",
+		func(innerT *testing.T) {
+			intensities := frameIntensities(rampFadeIn, 10, 0.8)
+
+			if intensities[0] > 0.01 {
+				innerT.Errorf("Expected about %v but got %v", 0, intensities[0])
+			}
+
+			if math.Abs(intensities[9]-0.8) > 1e-9 {
+				innerT.Errorf("Expected %v but got %v", 0.8, intensities[9])
+			}
+
+			for i := 1; i < len(intensities); i++ {
+				if intensities[i] <= intensities[i-1] {
+					innerT.Errorf("Frame %d - expected more than %v but got %v", i, intensities[i-1], intensities[i])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Fade out",
+		func(innerT *testing.T) {
+			intensities := frameIntensities(rampFadeOut, 5, 1)
+			expected := []float64{1, 0.75, 0.5, 0.25, 0}
+
+			for i := range expected {
+				if math.Abs(intensities[i]-expected[i]) > 1e-9 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected[i], intensities[i])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Pulse",
+		func(innerT *testing.T) {
+			intensities := frameIntensities(rampPulse, 4, 1)
+			expected := []float64{1, 0.5, 0, 0.5}
+
+			for i := range expected {
+				if math.Abs(intensities[i]-expected[i]) > 1e-9 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, expected[i], intensities[i])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"None",
+		func(innerT *testing.T) {
+			for i, intensity := range frameIntensities(nil, 3, 0.5) {
+				if intensity != 0.5 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, 0.5, intensity)
+				}
+			}
+		},
+	)
+}
@@ -0,0 +1,50 @@
+package rainbow
+
+import (
+	"image"
+	"image/gif"
+)
+
+/* repeats every frame n more times so the gradient gets more steps than the source has frames
+ * the repeats share the source frame's pixels, only the overlay color differs once blended
+ * each frame's delay is split over its repeats so the total duration stays the same
+ */
+func interpolateFrames(src *gif.GIF, n int) *gif.GIF {
+	steps := n + 1
+
+	frames := make([]*image.Paletted, 0, len(src.Image)*steps)
+	delays := make([]int, 0, len(src.Image)*steps)
+	var disposals []byte
+	if len(src.Disposal) > 0 {
+		disposals = make([]byte, 0, len(src.Image)*steps)
+	}
+
+	for i, frame := range src.Image {
+		var delay int
+		if len(src.Delay) > 0 {
+			delay = src.Delay[i%len(src.Delay)]
+		}
+
+		for step := 0; step < steps; step++ {
+			frames = append(frames, frame)
+
+			// the remainder goes to the first repeats so nothing is lost to rounding
+			stepDelay := delay / steps
+			if step < delay%steps {
+				stepDelay++
+			}
+			delays = append(delays, stepDelay)
+
+			if disposals != nil {
+				disposals = append(disposals, src.Disposal[i%len(src.Disposal)])
+			}
+		}
+	}
+
+	img := *src
+	img.Image = frames
+	img.Delay = delays
+	img.Disposal = disposals
+
+	return &img
+}
@@ -0,0 +1,101 @@
+package rainbow
+
+import (
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestInterpolateFrames(t *testing.T) {
+	src := newTestGIF(3, 2, 2)
+	src.Delay = []int{10, 5, 1}
+
+	img := interpolateFrames(src, 2)
+
+	t.Run(
+		"Frames are repeated",
+		func(innerT *testing.T) {
+			if len(img.Image) != 9 {
+				innerT.Fatalf("Expected %v but got %v", 9, len(img.Image))
+			}
+
+			for i, frame := range img.Image {
+				if frame != src.Image[i/3] {
+					innerT.Errorf("Frame %d - expected source frame %v", i, i/3)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Duration is kept",
+		func(innerT *testing.T) {
+			expected := []int{4, 3, 3, 2, 2, 1, 1, 0, 0}
+			if !reflect.DeepEqual(img.Delay, expected) {
+				innerT.Errorf("Expected %v but got %v", expected, img.Delay)
+			}
+		},
+	)
+}
+
+func TestRainbowifyInterpolateFrames(t *testing.T) {
+	src := newTestGIF(4, 2, 2)
+
+	opts := DefaultOptions()
+	opts.Colors = []colorful.Color{{R: 1, G: 0, B: 0}, {R: 0, G: 0, B: 1}}
+
+	plain, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	opts.InterpolateFrames = 1
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Frame count doubles",
+		func(innerT *testing.T) {
+			if len(out.Image) != 8 {
+				innerT.Errorf("Expected %v but got %v", 8, len(out.Image))
+			}
+
+			for i, delay := range out.Delay {
+				if delay != 5 {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, 5, delay)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Source frames keep their colors",
+		func(innerT *testing.T) {
+			for i, frame := range plain.Image {
+				if !reflect.DeepEqual(out.Image[i*2].Palette, frame.Palette) {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, frame.Palette, out.Image[i*2].Palette)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Inserted frames get the midpoint",
+		func(innerT *testing.T) {
+			// halfway between the first two of four steps
+			overlay := newGradient(opts.Colors, true).at(1.0 / 8)
+
+			for i, pixel := range src.Image[0].Palette {
+				expected := color.NRGBAModel.Convert(blendPixel(pixel, overlay, blendColor, 1))
+				actual := color.NRGBAModel.Convert(out.Image[1].Palette[i])
+				if actual != expected {
+					innerT.Errorf("Palette %d - expected %v but got %v", i, expected, actual)
+				}
+			}
+		},
+	)
+}
@@ -0,0 +1,36 @@
+package rainbow
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// the gradient is the same everywhere on the canvas, only used so a mask can go through the per pixel path
+func spatialFlat(x float64, y float64) float64 {
+	return 0
+}
+
+// the mask has to line up with the canvas pixel for pixel
+func validateMask(mask image.Image, canvas image.Rectangle) error {
+	size := mask.Bounds().Size()
+	if size != canvas.Size() {
+		return fmt.Errorf("Mask is %dx%d but the frames are %dx%d", size.X, size.Y, canvas.Dx(), canvas.Dy())
+	}
+
+	return nil
+}
+
+/* how much of the effect a pixel on the canvas gets, white is all of it and black is none
+ * a nil mask applies the effect everywhere
+ */
+func maskAt(mask image.Image, canvas image.Rectangle, x int, y int) float64 {
+	if mask == nil {
+		return 1
+	}
+
+	bounds := mask.Bounds()
+	gray := color.Gray16Model.Convert(mask.At(bounds.Min.X+x-canvas.Min.X, bounds.Min.Y+y-canvas.Min.Y)).(color.Gray16)
+
+	return float64(gray.Y) / 0xffff
+}
@@ -0,0 +1,66 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestRainbowifyMask(t *testing.T) {
+	original := color.RGBA{R: 40, G: 90, B: 200, A: 255}
+	frame := image.NewPaletted(image.Rect(0, 0, 2, 1), color.Palette{original})
+	src := &gif.GIF{Image: []*image.Paletted{frame}, Delay: []int{10}}
+
+	// the left half gets none of the effect and the right half all of it
+	mask := image.NewGray(image.Rect(0, 0, 2, 1))
+	mask.SetGray(0, 0, color.Gray{Y: 0})
+	mask.SetGray(1, 0, color.Gray{Y: 255})
+
+	opts := DefaultOptions()
+	opts.Colors = []colorful.Color{{R: 1, G: 0, B: 0}}
+	opts.Mask = mask
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Black keeps the original",
+		func(innerT *testing.T) {
+			actual := color.RGBAModel.Convert(out.Image[0].At(0, 0))
+			if actual != original {
+				innerT.Errorf("Expected %v but got %v", original, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"White is fully blended",
+		func(innerT *testing.T) {
+			blendFunc, err := getBlendFunc(opts.Blend)
+			if err != nil {
+				innerT.Fatal(err)
+			}
+
+			expected := color.RGBAModel.Convert(blendPixel(original, opts.Colors[0], blendFunc, 1))
+			actual := color.RGBAModel.Convert(out.Image[0].At(1, 0))
+			if actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+		},
+	)
+
+	t.Run(
+		"Size mismatch",
+		func(innerT *testing.T) {
+			opts.Mask = image.NewGray(image.Rect(0, 0, 3, 1))
+			if _, err := Rainbowify(src, opts); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
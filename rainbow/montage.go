@@ -0,0 +1,89 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+)
+
+/* Montage lays every frame of img out in a grid with the given number of columns
+ * every cell is the size of the canvas, cells past the last frame stay transparent
+ */
+func Montage(img *gif.GIF, columns int) (*image.RGBA, error) {
+	if columns < 1 {
+		return nil, errors.New("Montage needs at least 1 column")
+	}
+
+	if len(img.Image) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	frames := composite(img)
+	cell := frames[0].Bounds()
+	rows := (len(frames) + columns - 1) / columns
+
+	montage := image.NewRGBA(image.Rect(0, 0, cell.Dx()*columns, cell.Dy()*rows))
+	for i, frame := range frames {
+		offset := image.Pt((i%columns)*cell.Dx(), (i/columns)*cell.Dy())
+		draw.Draw(montage, cell.Sub(cell.Min).Add(offset), frame, cell.Min, draw.Src)
+	}
+
+	return montage, nil
+}
+
+/* Stack puts every frame of a next to the same frame of b, above it for a vertical layout or left of it for a horizontal one
+ * both need the same number of frames and the delays are taken from a, which is handy for comparing two takes on the same GIF
+ * the stacked frames are complete pictures re-palettized with quantizer, so they get DisposalBackground like coalesced ones
+ */
+func Stack(a *gif.GIF, b *gif.GIF, layout string, quantizer string) (*gif.GIF, error) {
+	if len(a.Image) == 0 || len(b.Image) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	if len(a.Image) != len(b.Image) {
+		return nil, errors.New("Stacked GIFs need the same number of frames")
+	}
+
+	cellA := canvasBounds(a)
+	cellB := canvasBounds(b)
+
+	// where b's corner goes
+	var offset image.Point
+	switch layout {
+	case "", "vertical":
+		offset = image.Pt(0, cellA.Dy())
+	case "horizontal":
+		offset = image.Pt(cellA.Dx(), 0)
+	default:
+		return nil, errors.New("Invalid layout")
+	}
+
+	bounds := image.Rectangle{Max: cellA.Size()}.Union(image.Rectangle{Min: offset, Max: offset.Add(cellB.Size())})
+
+	framesA := composite(a)
+	framesB := composite(b)
+	frames := make([]*image.Paletted, len(framesA))
+	disposal := make([]byte, len(framesA))
+	for i := range framesA {
+		stacked := image.NewRGBA(bounds)
+		draw.Draw(stacked, cellA.Sub(cellA.Min), framesA[i], cellA.Min, draw.Src)
+		draw.Draw(stacked, cellB.Sub(cellB.Min).Add(offset), framesB[i], cellB.Min, draw.Src)
+
+		frame, err := palettize(stacked, quantizer)
+		if err != nil {
+			return nil, err
+		}
+		frames[i] = frame
+		disposal[i] = gif.DisposalBackground
+	}
+
+	img := *a
+	img.Image = frames
+	img.Delay = append([]int(nil), a.Delay...)
+	img.Disposal = disposal
+	img.Config = image.Config{Width: bounds.Dx(), Height: bounds.Dy()}
+	img.BackgroundIndex = 0
+
+	return &img, nil
+}
@@ -0,0 +1,142 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestMontage(t *testing.T) {
+	src := newTestGIF(5, 4, 2)
+	// every frame stands on its own so the cells can be compared to the source frames
+	for i := range src.Disposal {
+		src.Disposal[i] = gif.DisposalBackground
+	}
+
+	montage, err := Montage(src, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Grid size",
+		func(innerT *testing.T) {
+			expected := image.Rect(0, 0, 12, 4)
+			if montage.Bounds() != expected {
+				innerT.Errorf("Expected %v but got %v", expected, montage.Bounds())
+			}
+		},
+	)
+
+	t.Run(
+		"Cells hold the frames in order",
+		func(innerT *testing.T) {
+			for i, frame := range src.Image {
+				x := (i % 3) * 4
+				y := (i / 3) * 2
+
+				r, g, b, a := frame.At(1, 1).RGBA()
+				actualR, actualG, actualB, actualA := montage.At(x+1, y+1).RGBA()
+				if r != actualR || g != actualG || b != actualB || a != actualA {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, frame.At(1, 1), montage.At(x+1, y+1))
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Padding is transparent",
+		func(innerT *testing.T) {
+			for y := 2; y < 4; y++ {
+				for x := 8; x < 12; x++ {
+					if _, _, _, a := montage.At(x, y).RGBA(); a != 0 {
+						innerT.Errorf("(%d, %d) - expected %v but got %v", x, y, 0, a)
+					}
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"No columns",
+		func(innerT *testing.T) {
+			if _, err := Montage(src, 0); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
+
+func TestStack(t *testing.T) {
+	a := newTestGIF(3, 4, 2)
+	b := newTestGIF(3, 4, 2)
+	for i := range a.Disposal {
+		a.Disposal[i] = gif.DisposalBackground
+		b.Disposal[i] = gif.DisposalBackground
+		// b's frames are a's shifted by one, so the two halves differ
+		b.Image[i] = a.Image[(i+1)%3]
+	}
+
+	cases := []struct {
+		layout string
+		size   image.Rectangle
+		offset image.Point
+	}{
+		{layout: "vertical", size: image.Rect(0, 0, 4, 4), offset: image.Pt(0, 2)},
+		{layout: "horizontal", size: image.Rect(0, 0, 8, 2), offset: image.Pt(4, 0)},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.layout,
+			func(innerT *testing.T) {
+				stacked, err := Stack(a, b, c.layout, "populosity")
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if len(stacked.Image) != 3 {
+					innerT.Fatalf("Expected %v but got %v", 3, len(stacked.Image))
+				}
+
+				for i, frame := range stacked.Image {
+					if frame.Bounds() != c.size {
+						innerT.Errorf("Expected %v but got %v", c.size, frame.Bounds())
+					}
+
+					for _, p := range []image.Point{image.Pt(0, 0), image.Pt(3, 1)} {
+						expectedA := color.RGBAModel.Convert(a.Image[i].At(p.X, p.Y))
+						if actual := color.RGBAModel.Convert(frame.At(p.X, p.Y)); actual != expectedA {
+							innerT.Errorf("Frame %d at %v - expected %v but got %v", i, p, expectedA, actual)
+						}
+
+						expectedB := color.RGBAModel.Convert(b.Image[i].At(p.X, p.Y))
+						q := p.Add(c.offset)
+						if actual := color.RGBAModel.Convert(frame.At(q.X, q.Y)); actual != expectedB {
+							innerT.Errorf("Frame %d at %v - expected %v but got %v", i, q, expectedB, actual)
+						}
+					}
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Different frame counts",
+		func(innerT *testing.T) {
+			if _, err := Stack(a, newTestGIF(2, 4, 2), "vertical", "populosity"); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Invalid layout",
+		func(innerT *testing.T) {
+			if _, err := Stack(a, b, "diagonal", "populosity"); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+}
@@ -0,0 +1,101 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/gif"
+)
+
+// where the pixel at p of a canvas of the given size ends up
+type orientFunc func(p image.Point, size image.Point) image.Point
+
+func getOrientFunc(name string) (orientFunc, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "90":
+		return orient90, nil
+	case "180":
+		return orient180, nil
+	case "270":
+		return orient270, nil
+	case "flip-h":
+		return orientFlipH, nil
+	case "flip-v":
+		return orientFlipV, nil
+	default:
+		return nil, errors.New("Invalid orientation")
+	}
+}
+
+// a quarter turn clockwise
+func orient90(p image.Point, size image.Point) image.Point {
+	return image.Pt(size.Y-1-p.Y, p.X)
+}
+
+func orient180(p image.Point, size image.Point) image.Point {
+	return image.Pt(size.X-1-p.X, size.Y-1-p.Y)
+}
+
+// a quarter turn counterclockwise
+func orient270(p image.Point, size image.Point) image.Point {
+	return image.Pt(p.Y, size.X-1-p.X)
+}
+
+// mirrored left to right
+func orientFlipH(p image.Point, size image.Point) image.Point {
+	return image.Pt(size.X-1-p.X, p.Y)
+}
+
+// mirrored top to bottom
+func orientFlipV(p image.Point, size image.Point) image.Point {
+	return image.Pt(p.X, size.Y-1-p.Y)
+}
+
+// where r ends up, moving its first and last pixels is enough since every orientation keeps rectangles upright
+func orientRect(orient orientFunc, r image.Rectangle, size image.Point) image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+
+	first := orient(r.Min, size)
+	last := orient(r.Max.Sub(image.Pt(1, 1)), size)
+
+	// image.Rect sorts the corners, the last pixel is inside so max is one past it
+	moved := image.Rect(first.X, first.Y, last.X, last.Y)
+	moved.Max = moved.Max.Add(image.Pt(1, 1))
+
+	return moved
+}
+
+/* moves every pixel of every frame with orient, frames smaller than the canvas move to where their area ends up
+ * the canvas starts at the origin afterwards and has its width and height swapped for quarter turns
+ */
+func orientFrames(src *gif.GIF, orient orientFunc) *gif.GIF {
+	canvas := canvasBounds(src)
+	size := canvas.Size()
+
+	frames := make([]*image.Paletted, len(src.Image))
+	for i, frame := range src.Image {
+		bounds := frame.Bounds().Intersect(canvas)
+		oriented := image.NewPaletted(orientRect(orient, bounds.Sub(canvas.Min), size), frame.Palette)
+
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				p := orient(image.Pt(x, y).Sub(canvas.Min), size)
+				oriented.Pix[oriented.PixOffset(p.X, p.Y)] = frame.Pix[frame.PixOffset(x, y)]
+			}
+		}
+
+		frames[i] = oriented
+	}
+
+	orientedSize := orientRect(orient, image.Rectangle{Max: size}, size).Size()
+
+	img := *src
+	img.Image = frames
+	img.Config.Width = orientedSize.X
+	img.Config.Height = orientedSize.Y
+
+	return &img
+}
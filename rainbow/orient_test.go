@@ -0,0 +1,88 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestOrientFrames(t *testing.T) {
+	// 3 wide and 2 tall, every pixel its own color so it can be followed
+	palette := make(color.Palette, 6)
+	for i := range palette {
+		palette[i] = color.RGBA{R: uint8(40 * i), A: 255}
+	}
+	frame := image.NewPaletted(image.Rect(0, 0, 3, 2), palette)
+	for i := range frame.Pix {
+		frame.Pix[i] = uint8(i)
+	}
+	src := &gif.GIF{
+		Image:  []*image.Paletted{frame},
+		Delay:  []int{10},
+		Config: image.Config{Width: 3, Height: 2},
+	}
+
+	cases := []struct {
+		name   string
+		bounds image.Rectangle
+		// where the top left pixel ends up
+		corner image.Point
+	}{
+		{name: "90", bounds: image.Rect(0, 0, 2, 3), corner: image.Pt(1, 0)},
+		{name: "180", bounds: image.Rect(0, 0, 3, 2), corner: image.Pt(2, 1)},
+		{name: "270", bounds: image.Rect(0, 0, 2, 3), corner: image.Pt(0, 2)},
+		{name: "flip-h", bounds: image.Rect(0, 0, 3, 2), corner: image.Pt(2, 0)},
+		{name: "flip-v", bounds: image.Rect(0, 0, 3, 2), corner: image.Pt(0, 1)},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				orient, err := getOrientFunc(c.name)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				img := orientFrames(src, orient)
+				oriented := img.Image[0]
+
+				if oriented.Bounds() != c.bounds {
+					innerT.Errorf("Expected %v but got %v", c.bounds, oriented.Bounds())
+				}
+
+				if img.Config.Width != c.bounds.Dx() || img.Config.Height != c.bounds.Dy() {
+					innerT.Errorf("Expected %v but got %vx%v", c.bounds.Size(), img.Config.Width, img.Config.Height)
+				}
+
+				if index := oriented.ColorIndexAt(c.corner.X, c.corner.Y); index != 0 {
+					innerT.Errorf("Expected %v but got %v", 0, index)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Partial frames move with the canvas",
+		func(innerT *testing.T) {
+			partial := image.NewPaletted(image.Rect(2, 0, 3, 1), palette)
+			img := orientFrames(&gif.GIF{Image: []*image.Paletted{partial}, Config: src.Config}, orient90)
+
+			expected := image.Rect(1, 2, 2, 3)
+			if img.Image[0].Bounds() != expected {
+				innerT.Errorf("Expected %v but got %v", expected, img.Image[0].Bounds())
+			}
+		},
+	)
+
+	t.Run(
+		"None leaves the frames alone",
+		func(innerT *testing.T) {
+			orient, err := getOrientFunc("none")
+			if err != nil || orient != nil {
+				innerT.Errorf("Expected %v but got %v", nil, err)
+			}
+		},
+	)
+}
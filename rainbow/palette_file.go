@@ -0,0 +1,50 @@
+package rainbow
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+)
+
+/* EncodeACT writes palette to w as an Adobe Color Table, 256 RGB triples where the ones past the palette are black
+ * that's 768 bytes, ACT has no alpha so every color is written without it and transparent ones as black
+ */
+func EncodeACT(w io.Writer, palette color.Palette) error {
+	if len(palette) > 256 {
+		return errors.New("Color tables hold at most 256 colors")
+	}
+
+	table := make([]byte, 256*3)
+	for i, c := range palette {
+		rgb := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if rgb.A == 0 {
+			continue
+		}
+
+		table[i*3], table[i*3+1], table[i*3+2] = rgb.R, rgb.G, rgb.B
+	}
+
+	_, err := w.Write(table)
+	return err
+}
+
+/* EncodeGPL writes palette to w as a GIMP palette called name, one color per line in the palette's order
+ * GIMP palettes have no alpha either, so colors are written the same way as EncodeACT
+ */
+func EncodeGPL(w io.Writer, palette color.Palette, name string) error {
+	buffered := bufio.NewWriter(w)
+
+	fmt.Fprintf(buffered, "GIMP Palette\nName: %s\nColumns: 16\n#\n", name)
+	for i, c := range palette {
+		rgb := color.NRGBAModel.Convert(c).(color.NRGBA)
+		if rgb.A == 0 {
+			rgb = color.NRGBA{}
+		}
+
+		fmt.Fprintf(buffered, "%3d %3d %3d\tIndex %d\n", rgb.R, rgb.G, rgb.B, i)
+	}
+
+	return buffered.Flush()
+}
@@ -0,0 +1,61 @@
+package rainbow
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestEncodeACT(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 255, G: 0, B: 0, A: 255},
+		color.NRGBA{R: 10, G: 20, B: 30, A: 128},
+		color.RGBA{},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeACT(&buf, palette); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	table := buf.Bytes()
+	if len(table) != 768 {
+		t.Fatalf("Expected %v but got %v", 768, len(table))
+	}
+
+	// the colors without their alpha, then black the rest of the way
+	expected := append([]byte{255, 0, 0, 10, 20, 30}, make([]byte, 768-6)...)
+	if !bytes.Equal(table, expected) {
+		t.Errorf("Expected %v but got %v", expected[:9], table[:9])
+	}
+
+	if err := EncodeACT(&bytes.Buffer{}, make(color.Palette, 257)); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
+
+func TestEncodeGPL(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 255, G: 128, B: 0, A: 255},
+		color.RGBA{},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeGPL(&buf, palette, "rainbow"); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"GIMP Palette",
+		"Name: rainbow",
+		"Columns: 16",
+		"#",
+		"255 128   0\tIndex 0",
+		"  0   0   0\tIndex 1",
+		"",
+	}, "\n")
+	if actual := buf.String(); actual != expected {
+		t.Errorf("Expected %q but got %q", expected, actual)
+	}
+}
@@ -0,0 +1,116 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// whether a goes before b in a sorted palette
+type paletteOrderFunc func(a color.NRGBA, b color.NRGBA) bool
+
+func getPaletteOrderFunc(name string) (paletteOrderFunc, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "luminance":
+		return orderLuminance, nil
+	case "hue":
+		return orderHue, nil
+	default:
+		return nil, errors.New("Invalid palette order")
+	}
+}
+
+// Rec. 601 luma, close enough to how bright a color looks and cheap
+func luma(c color.NRGBA) float64 {
+	return 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+}
+
+func orderLuminance(a color.NRGBA, b color.NRGBA) bool {
+	return luma(a) < luma(b)
+}
+
+// around the color wheel, grays have no hue so they come first, ties go darkest first
+func orderHue(a color.NRGBA, b color.NRGBA) bool {
+	hueA, _, _ := colorful.Color{R: float64(a.R) / 255, G: float64(a.G) / 255, B: float64(a.B) / 255}.Hsv()
+	hueB, _, _ := colorful.Color{R: float64(b.R) / 255, G: float64(b.G) / 255, B: float64(b.B) / 255}.Hsv()
+
+	if hueA != hueB {
+		return hueA < hueB
+	}
+
+	return orderLuminance(a, b)
+}
+
+// palette sorted with less, along with where every old index moved to
+func sortPalette(palette color.Palette, less paletteOrderFunc) (color.Palette, []uint8) {
+	colors := make([]color.NRGBA, len(palette))
+	order := make([]int, len(palette))
+	for i, c := range palette {
+		colors[i] = color.NRGBAModel.Convert(c).(color.NRGBA)
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i int, j int) bool {
+		return less(colors[order[i]], colors[order[j]])
+	})
+
+	sorted := make(color.Palette, len(palette))
+	moved := make([]uint8, len(palette))
+	for newIndex, oldIndex := range order {
+		sorted[newIndex] = palette[oldIndex]
+		moved[oldIndex] = uint8(newIndex)
+	}
+
+	return sorted, moved
+}
+
+/* sorts every frame's palette with less and points the pixels at the new indices, so the frames look exactly the same
+ * LZW compresses runs of similar indices better, so neighbouring colors sitting next to each other makes smaller files
+ * frames sharing a palette keep sharing the sorted one
+ */
+func orderPalettes(frames []*image.Paletted, less paletteOrderFunc) []*image.Paletted {
+	type sortedPalette struct {
+		palette color.Palette
+		moved   []uint8
+	}
+	// keyed like the palette cache, a shorter slice of the same palette starts at the same entry
+	palettes := make(map[paletteKey]sortedPalette)
+
+	ordered := make([]*image.Paletted, len(frames))
+	for i, frame := range frames {
+		if len(frame.Palette) == 0 {
+			ordered[i] = frame
+			continue
+		}
+
+		key := newPaletteKey(frame.Palette, colorful.Color{}, 0)
+		sorted, ok := palettes[key]
+		if !ok {
+			sorted.palette, sorted.moved = sortPalette(frame.Palette, less)
+			palettes[key] = sorted
+		}
+
+		pix := make([]uint8, len(frame.Pix))
+		for j, index := range frame.Pix {
+			// indices past the palette are left alone, the encoder rejects them either way
+			if int(index) < len(sorted.moved) {
+				index = sorted.moved[index]
+			}
+			pix[j] = index
+		}
+
+		ordered[i] = &image.Paletted{
+			Pix:     pix,
+			Stride:  frame.Stride,
+			Rect:    frame.Rect,
+			Palette: sorted.palette,
+		}
+	}
+
+	return ordered
+}
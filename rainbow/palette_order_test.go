@@ -0,0 +1,125 @@
+package rainbow
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+)
+
+func TestRainbowifyPaletteOrder(t *testing.T) {
+	src := newTestGIF(3, 8, 8)
+
+	opts := DefaultOptions()
+	plain, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, name := range []string{"luminance", "hue"} {
+		t.Run(
+			name,
+			func(innerT *testing.T) {
+				opts.PaletteOrder = name
+				ordered, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				less, _ := getPaletteOrderFunc(name)
+
+				var buf bytes.Buffer
+				if err := gif.EncodeAll(&buf, ordered); err != nil {
+					innerT.Fatalf("Error encoding: %v", err)
+				}
+				decoded, err := gif.DecodeAll(&buf)
+				if err != nil {
+					innerT.Fatalf("Error decoding: %v", err)
+				}
+
+				for i, frame := range decoded.Image {
+					for j := 1; j < len(frame.Palette); j++ {
+						previous := color.NRGBAModel.Convert(frame.Palette[j-1]).(color.NRGBA)
+						current := color.NRGBAModel.Convert(frame.Palette[j]).(color.NRGBA)
+						if less(current, previous) {
+							innerT.Errorf("Frame %d - expected %v before %v", i, current, previous)
+						}
+					}
+
+					if !reflect.DeepEqual(framePixels(frame), framePixels(plain.Image[i])) {
+						innerT.Errorf("Frame %d - expected the same pixels as without sorting", i)
+					}
+				}
+
+				if reflect.DeepEqual(ordered.Image[0].Pix, plain.Image[0].Pix) {
+					innerT.Errorf("Expected the indices to be remapped")
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Global palette stays shared",
+		func(innerT *testing.T) {
+			opts.PaletteOrder = "luminance"
+			opts.GlobalPalette = true
+			ordered, err := Rainbowify(src, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			palette := ordered.Config.ColorModel.(color.Palette)
+			for i, frame := range ordered.Image {
+				if &frame.Palette[0] != &palette[0] {
+					innerT.Errorf("Frame %d - expected the global palette", i)
+				}
+			}
+		},
+	)
+}
+
+func TestOrderPalettesShortened(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		color.RGBA{A: 255},
+		color.RGBA{R: 128, G: 128, B: 128, A: 255},
+		color.RGBA{R: 255, A: 255},
+	}
+
+	// the first frame's palette is a shorter slice of the second's, starting at the same entry
+	short := image.NewPaletted(image.Rect(0, 0, 2, 1), palette[:2])
+	short.Pix = []uint8{0, 1}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 1), palette)
+	full.Pix = []uint8{0, 1, 2, 3}
+
+	less, err := getPaletteOrderFunc("luminance")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ordered := orderPalettes([]*image.Paletted{short, full}, less)
+	for i, frame := range []*image.Paletted{short, full} {
+		if len(ordered[i].Palette) != len(frame.Palette) {
+			t.Fatalf("Frame %d - expected %v but got %v", i, len(frame.Palette), len(ordered[i].Palette))
+		}
+
+		if !reflect.DeepEqual(framePixels(ordered[i]), framePixels(frame)) {
+			t.Errorf("Frame %d - expected the same pixels as without sorting", i)
+		}
+	}
+}
+
+// every pixel's color in order
+func framePixels(frame *image.Paletted) []color.RGBA {
+	var pixels []color.RGBA
+	bounds := frame.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixels = append(pixels, color.RGBAModel.Convert(frame.At(x, y)).(color.RGBA))
+		}
+	}
+
+	return pixels
+}
@@ -0,0 +1,90 @@
+package rainbow
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// named gradients, stored the same way they'd be passed to -gradient
+var gradientPresets = map[string]string{
+	// ROYGBV
+	"rainbow": "ff0000,ff7f00,ffff00,00ff00,0000ff,8b00ff",
+	"pride":   "e40303,ff8c00,ffed00,008026,24408e,732982",
+	"trans":   "5bcefa,f5a9b8,ffffff,f5a9b8,5bcefa",
+	"bi":      "d60270,9b4f96,0038a8",
+	"lesbian": "d52d00,ef7627,ff9a56,ffffff,d162a4,b55690,a30262",
+	"ace":     "000000,a3a3a3,ffffff,800080",
+}
+
+func GradientPreset(name string) ([]colorful.Color, bool) {
+	hexes, okay := gradientPresets[name]
+	if !okay {
+		return nil, false
+	}
+
+	colors, _, err := ParseGradientColors(hexes)
+	if err != nil {
+		return nil, false
+	}
+
+	return colors, true
+}
+
+/* RandomGradient picks count colors with evenly spaced hues, the same seed always gives the same colors
+ * the first hue and the spacing between hues are random, as are the saturation and value shared by all of them
+ */
+func RandomGradient(count int, seed int64) ([]colorful.Color, error) {
+	if count < 2 {
+		return nil, errors.New("Random gradients need at least 2 colors")
+	}
+
+	random := rand.New(rand.NewSource(seed))
+
+	base := random.Float64() * 360
+	// at least half and at most all of the color wheel
+	spacing := (0.5 + random.Float64()*0.5) * 360 / float64(count)
+	saturation := 0.6 + random.Float64()*0.4
+	value := 0.8 + random.Float64()*0.2
+
+	colors := make([]colorful.Color, count)
+	for i := range colors {
+		hue := base + spacing*float64(i)
+		for hue >= 360 {
+			hue -= 360
+		}
+		colors[i] = colorful.Hsv(hue, saturation, value)
+	}
+
+	return colors, nil
+}
+
+/* HueGradient spans the color wheel from start to end degrees at full saturation and value, e.g. 180 to 300 for cyans to purples
+ * a start past the end wraps around through 360, so 300 to 60 goes through red, and 0 to 360 is the full circle
+ * stops are at most 30 degrees apart so blending between them doesn't stray from the hues in between
+ */
+func HueGradient(start float64, end float64) ([]colorful.Color, error) {
+	if start < 0 || start > 360 || end < 0 || end > 360 {
+		return nil, errors.New("Hues must be between 0 and 360")
+	}
+
+	span := end - start
+	if span < 0 {
+		span += 360
+	}
+
+	if span == 0 {
+		return nil, errors.New("Hue range can't be empty")
+	}
+
+	count := int(math.Ceil(span/30)) + 1
+
+	colors := make([]colorful.Color, count)
+	for i := range colors {
+		colors[i] = colorful.Hsv(math.Mod(start+span*float64(i)/float64(count-1), 360), 1, 1)
+	}
+
+	return colors, nil
+}
@@ -0,0 +1,224 @@
+package rainbow
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestGradientPreset(t *testing.T) {
+	cases := []struct {
+		name  string
+		count int
+		first string
+		last  string
+	}{
+		{name: "rainbow", count: 6, first: "#ff0000", last: "#8b00ff"},
+		{name: "pride", count: 6, first: "#e40303", last: "#732982"},
+		{name: "trans", count: 5, first: "#5bcefa", last: "#5bcefa"},
+		{name: "bi", count: 3, first: "#d60270", last: "#0038a8"},
+		{name: "lesbian", count: 7, first: "#d52d00", last: "#a30262"},
+		{name: "ace", count: 4, first: "#000000", last: "#800080"},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				colors, okay := GradientPreset(c.name)
+				if !okay {
+					innerT.Fatalf("Expected %v but got %v", true, okay)
+				}
+
+				if len(colors) != c.count {
+					innerT.Fatalf("Expected %v but got %v", c.count, len(colors))
+				}
+
+				if colors[0].Hex() != c.first {
+					innerT.Errorf("Expected %v but got %v", c.first, colors[0].Hex())
+				}
+
+				if colors[len(colors)-1].Hex() != c.last {
+					innerT.Errorf("Expected %v but got %v", c.last, colors[len(colors)-1].Hex())
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Unknown preset",
+		func(innerT *testing.T) {
+			colors, okay := GradientPreset("plaid")
+			if okay || colors != nil {
+				innerT.Errorf("Expected %v but got %v", false, okay)
+			}
+		},
+	)
+
+	t.Run(
+		"Rainbow is the default gradient",
+		func(innerT *testing.T) {
+			rainbow, _ := GradientPreset("rainbow")
+			colors, _, err := ParseGradientColors("")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := []colorful.Color{
+				{R: 1, G: 0, B: 0},
+				{R: 1, G: 127.0 / 255.0, B: 0},
+				{R: 1, G: 1, B: 0},
+				{R: 0, G: 1, B: 0},
+				{R: 0, G: 0, B: 1},
+				{R: 139.0 / 255.0, G: 0, B: 1},
+			}
+			for i := range expected {
+				if !colors[i].AlmostEqualRgb(expected[i]) || colors[i] != rainbow[i] {
+					innerT.Errorf("Expected %v but got %v", expected[i], colors[i])
+				}
+			}
+		},
+	)
+}
+
+func TestRandomGradient(t *testing.T) {
+	first, err := RandomGradient(5, 42)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Same seed gives the same colors",
+		func(innerT *testing.T) {
+			second, err := RandomGradient(5, 42)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for i := range first {
+				if first[i] != second[i] {
+					innerT.Errorf("Expected %v but got %v", first[i].Hex(), second[i].Hex())
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Different seeds give different colors",
+		func(innerT *testing.T) {
+			other, err := RandomGradient(5, 43)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			same := true
+			for i := range first {
+				if first[i] != other[i] {
+					same = false
+				}
+			}
+
+			if same {
+				innerT.Errorf("Expected different colors but got %v twice", first)
+			}
+		},
+	)
+
+	t.Run(
+		"Hues are evenly spaced",
+		func(innerT *testing.T) {
+			spacing := hueDistance(first[0], first[1])
+			for i := 1; i < len(first)-1; i++ {
+				if actual := hueDistance(first[i], first[i+1]); math.Abs(actual-spacing) > 1e-6 {
+					innerT.Errorf("Expected %v but got %v", spacing, actual)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Too few colors",
+		func(innerT *testing.T) {
+			if _, err := RandomGradient(1, 42); err == nil {
+				innerT.Errorf("Expected an error but got none")
+			}
+		},
+	)
+}
+
+// how far b's hue is ahead of a's, going around the color wheel
+func hueDistance(a colorful.Color, b colorful.Color) float64 {
+	hueA, _, _ := a.Hsv()
+	hueB, _, _ := b.Hsv()
+
+	return math.Mod(hueB-hueA+360, 360)
+}
+
+func TestHueGradient(t *testing.T) {
+	cases := []struct {
+		name  string
+		start float64
+		end   float64
+		// the hues every stop has to be within, going from the first to the second
+		from float64
+		to   float64
+	}{
+		{name: "Red to green", start: 0, end: 120, from: 0, to: 120},
+		{name: "Blues to purples", start: 200, end: 290, from: 200, to: 290},
+		{name: "Wrap around", start: 300, end: 60, from: 300, to: 60},
+		{name: "Full circle", start: 0, end: 360, from: 0, to: 360},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				colors, err := HueGradient(c.start, c.end)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				width := math.Mod(c.to-c.from+360, 360)
+				if width == 0 {
+					width = 360
+				}
+
+				for _, color := range colors {
+					hue, saturation, value := color.Hsv()
+					if offset := math.Mod(hue-c.from+360, 360); offset > width+1e-6 {
+						innerT.Errorf("Expected a hue from %v to %v but got %v", c.from, c.to, hue)
+					}
+
+					if math.Abs(saturation-1) > 1e-6 || math.Abs(value-1) > 1e-6 {
+						innerT.Errorf("Expected %v but got %v, %v", 1, saturation, value)
+					}
+				}
+
+				first, _, _ := colors[0].Hsv()
+				last, _, _ := colors[len(colors)-1].Hsv()
+				if math.Abs(first-c.start) > 1e-6 || math.Abs(last-math.Mod(c.end, 360)) > 1e-6 {
+					innerT.Errorf("Expected %v to %v but got %v to %v", c.start, c.end, first, last)
+				}
+
+				for i := 1; i < len(colors); i++ {
+					if step := hueDistance(colors[i-1], colors[i]); step > 30+1e-6 {
+						innerT.Errorf("Expected stops at most %v apart but got %v", 30, step)
+					}
+				}
+			},
+		)
+	}
+
+	for _, c := range [][2]float64{{90, 90}, {-10, 90}, {0, 400}} {
+		t.Run(
+			fmt.Sprintf("Invalid %v to %v", c[0], c[1]),
+			func(innerT *testing.T) {
+				if _, err := HueGradient(c[0], c[1]); err == nil {
+					innerT.Errorf("Expected an error but got none")
+				}
+			},
+		)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package rainbow
 
 import (
 	"errors"
@@ -57,7 +57,7 @@ func (q Quantizer) identity(colors []color.RGBA) ([]*color.RGBA, []int) {
 				addr  *color.RGBA
 				index int
 			}{
-				addr:  &c,
+				addr:  &color.RGBA{R: c.R, G: c.G, B: c.B, A: c.A},
 				index: len(paletteSlice),
 			}
 			palette[c] = colorInfo
@@ -223,6 +223,8 @@ func (q Quantizer) medianCut(colors []color.RGBA) ([]*color.RGBA, []int) {
 			index++
 		}
 	}
+	// fewer colors than buckets leaves some of them empty
+	palette = palette[:index]
 
 	for i := range uniqueColors {
 		colorPtr := uniqueColors[i]
@@ -322,8 +324,10 @@ func (q Quantizer) medianCutSplit(bucket []*color.RGBA, depth int) ([]*color.RGB
 		})
 	}
 
-	aPalette, aMappedcolor := q.medianCutSplit(bucket[:len(bucket)/2+1], depth-1)
-	bPalette, bMappedColor := q.medianCutSplit(bucket[len(bucket)/2+1:], depth-1)
+	// rounding up so a bucket of two colors still splits in two
+	half := (len(bucket) + 1) / 2
+	aPalette, aMappedcolor := q.medianCutSplit(bucket[:half], depth-1)
+	bPalette, bMappedColor := q.medianCutSplit(bucket[half:], depth-1)
 
 	palette := append(aPalette, bPalette...)
 	mappedColor := append(aMappedcolor, bMappedColor...)
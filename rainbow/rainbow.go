@@ -0,0 +1,1154 @@
+// Package rainbow overlays a color gradient over the frames of a GIF.
+package rainbow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"sync"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+type Options struct {
+	// the gradient stops, defaults to ROYGBV
+	Colors []colorful.Color
+	// per stop opacity in the same order as Colors, nil means fully opaque
+	Opacities []float64
+	// per stop position in [0, 1] in the same order as Colors, nil or negative spreads stops evenly
+	Positions []float64
+	// the number of goroutines processing frames
+	Threads int
+	// called with the number of finished frames and the total after every frame, never concurrently, can be nil
+	Progress func(done int, total int)
+	// called with anything about the output worth knowing that isn't an error, can be nil
+	Warn func(message string)
+	// called once before any frame is processed with the overlay color of every output frame, can be nil
+	// they're worked out after Every, InterpolateFrames, and the delays, so they line up with the output
+	Overlay func(colors []colorful.Color)
+	// the number of times the frames are repeated in the output
+	LoopCount int
+	// the most frames the output may have, checked before any are made so a huge LoopCount can't run out of memory, 0 for no limit
+	MaxFrames int
+	// continuous spreads one sweep across all the repeats, per-loop gives every repeat a full sweep of its own
+	LoopMode string
+	// how strongly the gradient is blended in, from 0 (untouched) to 1 (fully blended)
+	Opacity float64
+	// varies the opacity over the animation: none, fade-in, fade-out, or pulse
+	IntensityRamp string
+	// blend mode: color, normal, multiply, screen, overlay, softlight, or hue
+	Blend string
+	/* how much of the overlay's own lightness the color blend takes on, from 0 (keeps the source's, the plain color blend) to 1 (the overlay as is)
+	 * Opacity is applied on top of it, mixing the result back towards the source
+	 */
+	BlendFactor float64
+	// the channels the overlay may change, any combination of r, g, and b, e.g. r to only tint the red channel
+	Channels string
+	// replaces the Blend mode with custom math when non nil, gets the overlay and the source color and returns the blended color
+	BlendFunc func(overlay colorful.Color, base colorful.Color) colorful.Color
+	// raises every channel of the overlay to this power before blending, 1 leaves it as is
+	Gamma float64
+	// scales the saturation and lightness of every blended color, 1 leaves them as is
+	Saturation float64
+	Brightness float64
+	// blend in linear light instead of sRGB, only for the normal, multiply, screen, overlay, and softlight blends
+	Linear bool
+	// scale the opacity by every color's own alpha so semi transparent areas only get some of the effect
+	RespectAlpha bool
+	// snap colors that are already within a couple of steps of the gradient's color to it instead of blending them again
+	Preserve bool
+	// leave colors within BlackWhiteTolerance of pure black or white unblended, so outlines and backgrounds of logos stay clean
+	PreserveBlackWhite  bool
+	BlackWhiteTolerance int
+	// leave the color covering the most pixels across all frames unblended, which is usually a solid background
+	KeepDominant bool
+	// turn every source color into its negative before blending
+	Invert bool
+	// turn the source colors into grays of the same luminance before blending, for a clean wash
+	DesaturateFirst bool
+	// blend mixes the gradient in using Blend, huerotate instead turns every color's hue a full circle over the animation
+	Mode string
+	// color space to interpolate the gradient in: rgb, hsv, hcl, or lab
+	Interpolation string
+	// how each sweep speeds up and slows down: linear, ease-in, ease-out, ease-in-out, or sine
+	// with more than one sweep every one is eased by itself, whether they come from Cycles or SweepSeconds
+	Easing string
+	// the number of full sweeps through the gradient across the whole animation
+	Cycles int
+	// when non zero a full sweep takes this many seconds of playback going by the output's delays, instead of Cycles sweeps over the frames
+	SweepSeconds float64
+	// run the gradient backwards
+	Reverse bool
+	// sweep through the gradient and back again in every cycle, so the animation ends where it started
+	Bounce bool
+	// stop the last frame one step short of the first color so looping doesn't show it twice in a row
+	Seamless bool
+	// where in the gradient the first frame starts, in [0, 1)
+	Phase float64
+	// merge consecutive output frames that look exactly the same into one with their delays added up
+	Dedupe bool
+	// only keep every nth source frame, the dropped frames' delays go to the kept frame before them
+	Every int
+	// the number of frames to insert after every source frame so short GIFs get a smoother sweep
+	InterpolateFrames int
+	// overrides every frame's delay in 100ths of a second when non zero
+	Delay int
+	// multiplies every frame's delay, 0.5 plays twice as fast and 2 half as fast
+	DelayScale float64
+	// overrides every frame's delay to play at this many frames per second when non zero, can't be combined with Delay
+	FPS float64
+	// produce a single frame using the gradient's midpoint instead of an animation
+	Still bool
+	// reduce the output to one palette of at most this many colors shared by all frames, 0 keeps every frame's own palette
+	MaxColors int
+	// share a single palette of at most 256 colors, or MaxColors when set, between all frames and the GIF's Config
+	GlobalPalette bool
+	// share the first frame's blended palette between all frames and the GIF's Config, the other frames get its closest colors
+	PaletteFromFirst bool
+	/* the color viewers should show behind the frames, mapped to the closest color of the global palette
+	 * without GlobalPalette the first frame's palette is written as the global one
+	 */
+	Background *colorful.Color
+	// point the background at the transparent color instead, can't be combined with Background
+	Transparent bool
+	// composite partly transparent colors over this color after blending so their edges don't fringe, nil leaves them as they are
+	Matte *colorful.Color
+	// sort every palette by luminance or hue so similar colors sit together and compress better, none keeps them as they are
+	PaletteOrder string
+	// how much effort goes into picking the reduced colors, from 1 (a plain median cut, fastest) to 10 (most accurate)
+	Quality int
+	// how to hide banding when reducing the colors or mapping spatial gradients to a palette: none, floyd-steinberg, or ordered
+	Dither string
+	// composite frames onto the full canvas before blending so partial frames get consistent colors
+	Coalesce bool
+	/* resize the output to this many pixels wide and tall with bilinear filtering, 0 for one of them keeps the aspect ratio
+	 * and 0 for both keeps the size, done after blending so the blend still only has to go over the source's shared palettes
+	 */
+	Width  int
+	Height int
+	// play the source frames backwards, unlike Reverse which runs the gradient backwards
+	ReversePlayback bool
+	// only keep this part of the canvas, the output starts at its corner, empty keeps the whole canvas
+	Crop image.Rectangle
+	// a caption drawn over every frame after blending and resizing, in a 7x13 pixel font, empty draws nothing
+	Text string
+	// where the caption goes: top, bottom, or center
+	TextPosition string
+	TextColor    colorful.Color
+	// turn or mirror every frame before blending: none, 90, 180, 270 (clockwise), flip-h, or flip-v, a Mask has to match the result
+	Orient string
+	// quantizer used when frames need to be re-palettized: scalar, populosity, or mediancut
+	Quantizer string
+	// vary the gradient across each frame as well: none, horizontal, vertical, diagonal, or radial
+	Spatial string
+	/* a grayscale image the size of the frames that scales the opacity per pixel, nil applies the effect everywhere
+	 * white gets the full effect and black none
+	 */
+	Mask image.Image
+	// where the radial spatial gradient is centered, as fractions of the width and height
+	CenterX float64
+	CenterY float64
+}
+
+// the options the CLI uses when no flags are given
+func DefaultOptions() Options {
+	colors, _ := GradientPreset("rainbow")
+
+	return Options{
+		Colors:              colors,
+		Threads:             1,
+		LoopCount:           1,
+		MaxFrames:           10000,
+		LoopMode:            "continuous",
+		Opacity:             1,
+		Blend:               "color",
+		Channels:            "rgb",
+		Mode:                "blend",
+		Gamma:               1,
+		Saturation:          1,
+		Brightness:          1,
+		BlackWhiteTolerance: 8,
+		Interpolation:       "hcl",
+		Easing:              "linear",
+		IntensityRamp:       "none",
+		Cycles:              1,
+		Every:               1,
+		Quality:             5,
+		Dither:              "none",
+		Seamless:            true,
+		DelayScale:          1,
+		Quantizer:           "populosity",
+		Spatial:             "none",
+		Orient:              "none",
+		PaletteOrder:        "none",
+		TextPosition:        "bottom",
+		TextColor:           colorful.Color{R: 1, G: 1, B: 1},
+		CenterX:             0.5,
+		CenterY:             0.5,
+	}
+}
+
+/* Rainbowify overlays the gradient described by opts over every frame of src
+ * src is left untouched and every frame of the result owns its pixels
+ * frames that end up with the same colors share their palette, so palettes shouldn't be modified in place
+ */
+func Rainbowify(src *gif.GIF, opts Options) (*gif.GIF, error) {
+	return RainbowifyContext(context.Background(), src, opts)
+}
+
+/* RainbowifyContext is Rainbowify but stops processing frames once ctx is done
+ * returns ctx.Err() in that case
+ */
+func RainbowifyContext(ctx context.Context, src *gif.GIF, opts Options) (*gif.GIF, error) {
+	return rainbowify(ctx, src, opts, nil, nil)
+}
+
+/* RainbowifyFrame renders only the output frame pick returns when given the number of output frames, and returns it as a single frame GIF
+ * the frame is blended exactly like it would be by RainbowifyContext, with the same overlay color, opacity, and delay, but none of the others are
+ * max colors reduces the frame by itself, global palettes, palette from first, palette order, and dedupe don't apply to a single frame
+ * coalesce to get a complete picture rather than what the frame draws over the ones before it
+ */
+func RainbowifyFrame(ctx context.Context, src *gif.GIF, opts Options, pick func(frameCount int) int) (*gif.GIF, error) {
+	if pick == nil {
+		return nil, errors.New("Rendering a single frame needs a way to pick it")
+	}
+
+	return rainbowify(ctx, src, opts, nil, pick)
+}
+
+/* whether frameCount source frames would make more than MaxFrames output frames, going by Every, InterpolateFrames, and LoopCount
+ * divided rather than multiplied at every step so huge values can't overflow past the limit
+ */
+func exceedsMaxFrames(frameCount int, opts Options) bool {
+	kept := frameCount / opts.Every
+	if frameCount%opts.Every != 0 {
+		kept++
+	}
+	if kept > opts.MaxFrames {
+		return true
+	}
+
+	if opts.InterpolateFrames >= opts.MaxFrames/kept {
+		return true
+	}
+
+	perLoop := kept * (opts.InterpolateFrames + 1)
+	return opts.LoopCount > opts.MaxFrames/perLoop
+}
+
+/* does the work of RainbowifyContext, or of RainbowifyStream when out isn't nil, in which case there's no GIF to return
+ * when pick isn't nil it's given the number of output frames and only the one it returns is rendered, see RainbowifyFrame
+ */
+func rainbowify(ctx context.Context, src *gif.GIF, opts Options, out FrameWriter, pick func(frameCount int) int) (*gif.GIF, error) {
+	if len(src.Image) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	if len(opts.Colors) == 0 {
+		return nil, ErrEmptyGradient
+	}
+
+	if opts.Opacities != nil && len(opts.Opacities) != len(opts.Colors) {
+		return nil, errors.New("Gradient needs one opacity per color")
+	}
+
+	if opts.Positions != nil && len(opts.Positions) != len(opts.Colors) {
+		return nil, errors.New("Gradient needs one position per color")
+	}
+
+	stops := make([]Stop, len(opts.Colors))
+	for i, color := range opts.Colors {
+		stops[i] = Stop{Color: color, Pos: -1}
+		if opts.Positions != nil {
+			stops[i].Pos = opts.Positions[i]
+		}
+	}
+
+	if err := validateStops(stops); err != nil {
+		return nil, err
+	}
+
+	if opts.Threads < 1 {
+		return nil, errors.New("Thread count must be at least 1")
+	}
+
+	if opts.LoopCount < 1 {
+		return nil, errors.New("Loop count must be at least 1")
+	}
+
+	if opts.Cycles < 1 {
+		return nil, errors.New("Cycles must be at least 1")
+	}
+
+	if opts.SweepSeconds < 0 {
+		return nil, errors.New("Sweep seconds must be at least 0")
+	}
+
+	if opts.SweepSeconds > 0 && opts.Cycles != 1 {
+		return nil, errors.New("Sweep seconds and cycles are mutually exclusive")
+	}
+
+	if opts.Phase < 0 || opts.Phase >= 1 {
+		return nil, errors.New("Phase must be at least 0 and less than 1")
+	}
+
+	if opts.Opacity < 0 || opts.Opacity > 1 {
+		return nil, errors.New("Opacity must be between 0 and 1")
+	}
+
+	if opts.Every < 1 {
+		return nil, errors.New("Every must be at least 1")
+	}
+
+	if opts.InterpolateFrames < 0 {
+		return nil, errors.New("Interpolated frames must be at least 0")
+	}
+
+	// known from the options alone, so checked before coalescing or interpolating makes any frames
+	if opts.MaxFrames > 0 && !opts.Still && exceedsMaxFrames(len(src.Image), opts) {
+		return nil, fmt.Errorf("%w: %d frames with every %d, interpolate frames %d, and loop count %d are more than the limit of %d", ErrTooManyFrames, len(src.Image), opts.Every, opts.InterpolateFrames, opts.LoopCount, opts.MaxFrames)
+	}
+
+	if opts.Delay < 0 {
+		return nil, errors.New("Delay must be at least 0")
+	}
+
+	if opts.DelayScale <= 0 {
+		return nil, errors.New("Delay scale must be greater than 0")
+	}
+
+	if opts.FPS < 0 {
+		return nil, errors.New("FPS must be at least 0")
+	}
+
+	if opts.FPS > 0 && opts.Delay != 0 {
+		return nil, errors.New("FPS and delay are mutually exclusive")
+	}
+
+	if opts.MaxColors != 0 && (opts.MaxColors < 2 || opts.MaxColors > 256) {
+		return nil, errors.New("Max colors must be between 2 and 256")
+	}
+
+	if opts.Quality < 1 || opts.Quality > 10 {
+		return nil, errors.New("Quality must be between 1 and 10")
+	}
+
+	dither, err := getDitherFunc(opts.Dither)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Background != nil && opts.Transparent {
+		return nil, errors.New("Background and transparent are mutually exclusive")
+	}
+
+	if opts.PaletteFromFirst && (opts.GlobalPalette || opts.MaxColors > 0) {
+		return nil, errors.New("Palette from first can't be combined with global palettes or max colors")
+	}
+
+	if out != nil && (opts.GlobalPalette || opts.MaxColors > 0 || opts.PaletteFromFirst || opts.Background != nil || opts.Transparent || opts.Dedupe) {
+		return nil, errors.New("Global palettes, max colors, palette from first, backgrounds, and dedupe need every frame at once, so they can't be streamed")
+	}
+
+	blend, err := getBlendFunc(opts.Blend)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BlendFunc != nil {
+		blend = opts.BlendFunc
+	}
+
+	if opts.BlendFactor < 0 || opts.BlendFactor > 1 {
+		return nil, errors.New("Blend factor must be between 0 and 1")
+	}
+
+	if opts.BlendFactor != 0 {
+		if opts.BlendFunc != nil || opts.Blend != "color" {
+			return nil, errors.New("Blend factor only works with the color blend mode")
+		}
+
+		blend = colorFactorBlend(opts.BlendFactor)
+	}
+
+	if opts.Linear {
+		if opts.BlendFunc == nil && (opts.Blend == "color" || opts.Blend == "hue") {
+			return nil, errors.New("Linear blending only works with the per channel blend modes")
+		}
+
+		blend = linearBlend(blend)
+	}
+
+	if opts.Gamma <= 0 {
+		return nil, errors.New("Gamma must be greater than 0")
+	}
+
+	if opts.Gamma != 1 {
+		blend = gammaBlend(blend, opts.Gamma)
+	}
+
+	if opts.Saturation < 0 || opts.Brightness < 0 {
+		return nil, errors.New("Saturation and brightness must be at least 0")
+	}
+
+	if opts.Saturation != 1 || opts.Brightness != 1 {
+		blend = adjustBlend(blend, opts.Saturation, opts.Brightness)
+	}
+
+	// last so the snap compares against the untouched overlay
+	if opts.Preserve {
+		blend = preserveBlend(blend)
+	}
+
+	if opts.BlackWhiteTolerance < 0 || opts.BlackWhiteTolerance > 127 {
+		return nil, errors.New("Black and white tolerance must be between 0 and 127")
+	}
+
+	// outermost so it sees the source color before anything else touches it
+	if opts.PreserveBlackWhite {
+		blend = blackWhiteBlend(blend, uint8(opts.BlackWhiteTolerance))
+	}
+
+	interpolate, err := getInterpolationFunc(opts.Interpolation)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.CenterX < 0 || opts.CenterX > 1 || opts.CenterY < 0 || opts.CenterY > 1 {
+		return nil, errors.New("Center must be between 0 and 1")
+	}
+
+	if opts.Width < 0 || opts.Height < 0 {
+		return nil, errors.New("Width and height must be at least 0")
+	}
+
+	if !opts.Crop.Empty() && !opts.Crop.In(canvasBounds(src)) {
+		return nil, errors.New("Crop must be inside the canvas")
+	}
+
+	// only the position can be wrong, where the text ends up is worked out once the frames are done
+	if _, err := textRect(image.Rectangle{}, image.Point{}, opts.TextPosition); err != nil {
+		return nil, err
+	}
+
+	if opts.Mode != "blend" && opts.Mode != "huerotate" {
+		return nil, errors.New("Invalid mode")
+	}
+
+	easing, err := getEasingFunc(opts.Easing)
+	if err != nil {
+		return nil, err
+	}
+
+	spatial, err := getSpatialFunc(opts.Spatial, opts.CenterX, opts.CenterY)
+	if err != nil {
+		return nil, err
+	}
+
+	ramp, err := getRampFunc(opts.IntensityRamp)
+	if err != nil {
+		return nil, err
+	}
+
+	orient, err := getOrientFunc(opts.Orient)
+	if err != nil {
+		return nil, err
+	}
+
+	paletteOrder, err := getPaletteOrderFunc(opts.PaletteOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Coalesce {
+		src, err = coalesce(src, opts.Quantizer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// before decimating so every nth frame counts from the new first frame
+	if opts.ReversePlayback {
+		src, err = reverseFrames(src, opts.Quantizer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// in the source's own coordinates, so before orienting
+	if !opts.Crop.Empty() {
+		src, err = cropFrames(src, opts.Crop, opts.Quantizer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// before anything looks at the canvas, so spatial gradients and masks line up with the turned frames
+	if orient != nil {
+		src = orientFrames(src, orient)
+	}
+
+	// after coalescing so the kept frames are complete by themselves
+	if opts.Every > 1 {
+		src = decimateFrames(src, opts.Every)
+	}
+
+	// after coalescing so the repeats are complete frames as well, a still has nothing to smooth
+	if opts.InterpolateFrames > 0 && !opts.Still {
+		src = interpolateFrames(src, opts.InterpolateFrames)
+	}
+
+	// bouncing turns around at the last color, so it isn't wrapped back to the first
+	gradient := newGradientStops(stops, !opts.Bounce)
+	gradient.cycles = uint(opts.Cycles)
+	gradient.reverse = opts.Reverse
+	gradient.phase = opts.Phase
+	gradient.interpolate = interpolate
+	gradient.easing = easing
+	gradient.bounce = opts.Bounce
+	gradient.wrap = gradient.wrap && opts.Seamless
+	gradient.opacities = opts.Opacities
+
+	switch opts.LoopMode {
+	case "", "continuous":
+	case "per-loop":
+		gradient.loopLength = uint(len(src.Image))
+	default:
+		return nil, errors.New("Invalid loop mode")
+	}
+
+	frames := src.Image
+	// inverted first so desaturating afterwards gives the grays of the negative
+	if opts.Invert {
+		frames = invertFrames(frames)
+	}
+
+	if opts.DesaturateFirst {
+		frames = desaturateFrames(frames)
+	}
+
+	if opts.Mode == "huerotate" && spatial != nil {
+		return nil, errors.New("Spatial gradients only work with the blend mode")
+	}
+
+	if opts.Mode == "huerotate" && opts.RespectAlpha {
+		return nil, errors.New("Respecting alpha only works with the blend mode")
+	}
+
+	if opts.Mode == "huerotate" && (opts.Preserve || opts.PreserveBlackWhite || opts.KeepDominant) {
+		return nil, errors.New("Preserving colors only works with the blend mode")
+	}
+
+	// found in the colors that get blended, so after inverting and desaturating, and outermost like black and white
+	if opts.KeepDominant {
+		if dominant, ok := dominantColor(frames); ok {
+			blend = dominantBlend(blend, dominant)
+		}
+	}
+
+	channels, err := parseChannels(opts.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Mode == "huerotate" && channels != allChannels {
+		return nil, errors.New("Channels only work with the blend mode")
+	}
+
+	if opts.Mask != nil {
+		if opts.Mode == "huerotate" {
+			return nil, errors.New("Masks only work with the blend mode")
+		}
+
+		if err := validateMask(opts.Mask, canvasBounds(src)); err != nil {
+			return nil, err
+		}
+	}
+
+	// a backstop for the check above, divided rather than multiplied so a huge LoopCount can't overflow past it
+	if opts.MaxFrames > 0 && !opts.Still && opts.LoopCount > opts.MaxFrames/len(src.Image) {
+		return nil, fmt.Errorf("%w: %d frames looped %d times is more than the limit of %d", ErrTooManyFrames, len(src.Image), opts.LoopCount, opts.MaxFrames)
+	}
+
+	frameCount := uint(len(src.Image) * opts.LoopCount)
+	if opts.Still {
+		// a still has nothing to ramp over
+		frameCount = 1
+		ramp = nil
+	}
+
+	// the opacity option and the intensity ramp scale the opacity of every frame
+	intensities := frameIntensities(ramp, frameCount, opts.Opacity)
+
+	newDelay := make([]int, frameCount)
+	// overwrite the delay if one is provided, otherwise use default
+	for i := range newDelay {
+		if opts.Delay == 0 && len(src.Delay) > 0 {
+			newDelay[i] = src.Delay[i%len(src.Delay)]
+		} else {
+			newDelay[i] = opts.Delay
+		}
+	}
+
+	delayScale := opts.DelayScale
+	// a second per frame scaled down, so 30 fps gets the delays 3, 3, 4 that add up to a second rather than 3 every time
+	if opts.FPS > 0 {
+		for i := range newDelay {
+			newDelay[i] = 100
+		}
+		delayScale /= opts.FPS
+	}
+
+	if delayScale != 1 {
+		newDelay = scaleDelays(newDelay, delayScale)
+	}
+
+	// the delays are final by now, so the sweep can follow them
+	if opts.SweepSeconds > 0 {
+		gradient.sweepDuration = opts.SweepSeconds * 100
+		gradient.delays = newDelay
+	}
+
+	// the color every output frame is blended with
+	var overlayColors []colorful.Color
+	if opts.Still {
+		overlayColors = []colorful.Color{gradient.at(0.5)}
+	} else {
+		overlayColors = gradient.generate(frameCount)
+	}
+
+	if opts.Overlay != nil {
+		opts.Overlay(append([]colorful.Color{}, overlayColors...))
+	}
+
+	// renders output frames start up to end of the source frames passed in, which start at output frame start
+	var process func(frames []*image.Paletted, start uint, end uint, progress func(done int, total int)) ([]*image.Paletted, error)
+	if opts.Mode == "huerotate" {
+		// the gradient's positions drive the angle, so cycles, phase, easing, and so on still apply
+		var positions []float64
+		if opts.Still {
+			positions = []float64{0.5}
+		} else {
+			positions = gradient.framePositions(frameCount)
+		}
+
+		rotations := make([]float64, len(positions))
+		for i, position := range positions {
+			rotations[i] = position * 360
+		}
+
+		process = func(frames []*image.Paletted, start uint, end uint, progress func(done int, total int)) ([]*image.Paletted, error) {
+			return processFramesHueRotate(ctx, frames, rotations[start:end], intensities[start:end], progress, uint(opts.Threads))
+		}
+	} else if spatial != nil || opts.Mask != nil {
+		// over time the whole pattern shifts along the gradient
+		shifts := gradient.framePositions(frameCount)
+
+		// a mask on its own needs per pixel opacity but not a per pixel gradient
+		if spatial == nil {
+			spatial = spatialFlat
+			if opts.Still {
+				shifts = []float64{0.5}
+			}
+		}
+
+		process = func(frames []*image.Paletted, start uint, end uint, progress func(done int, total int)) ([]*image.Paletted, error) {
+			return processFramesSpatial(ctx, frames, canvasBounds(src), gradient, shifts[start:end], spatial, opts.Mask, blend, intensities[start:end], opts.RespectAlpha, channels, opts.Quantizer, dither, progress, uint(opts.Threads))
+		}
+	} else {
+		var overlayOpacities []float64
+		if opts.Still {
+			overlayOpacities = []float64{gradient.opacityAt(0.5)}
+		} else {
+			overlayOpacities = gradient.generateOpacity(frameCount)
+		}
+
+		for i := range overlayOpacities {
+			overlayOpacities[i] *= intensities[i]
+		}
+
+		process = func(frames []*image.Paletted, start uint, end uint, progress func(done int, total int)) ([]*image.Paletted, error) {
+			return processFrames(ctx, frames, overlayColors[start:end], overlayOpacities[start:end], blend, opts.RespectAlpha, channels, progress, uint(opts.Threads))
+		}
+	}
+
+	// the new canvas size goes into Config below, everything before still works with the source's canvas
+	canvas := canvasBounds(src)
+	size := resizedSize(canvas.Size(), opts.Width, opts.Height)
+	outputCanvas := canvas
+	if size != canvas.Size() {
+		// resized frames start at the origin
+		outputCanvas = image.Rectangle{Max: size}
+	}
+
+	var limited int
+	// progress is reported out of these, a picked frame counts by itself
+	progressFirst, progressTotal := uint(0), frameCount
+	/* the finished output frames start up to end, only ever called one range after the other
+	 * progress counts on across calls, so streaming a range at a time reports the same as doing all of them at once
+	 */
+	render := func(start uint, end uint) ([]*image.Paletted, error) {
+		// output frames loop over the source frames, so they're shifted to line the first one up with start
+		shifted := frames
+		if offset := start % uint(len(frames)); offset != 0 {
+			shifted = append(append([]*image.Paletted{}, frames[offset:]...), frames[:offset]...)
+		}
+
+		var progress func(done int, total int)
+		if opts.Progress != nil {
+			progress = func(done int, total int) {
+				opts.Progress(int(start-progressFirst)+done, int(progressTotal))
+			}
+		}
+
+		newFrames, err := process(shifted, start, end, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		// before resizing so the resized edges are worked out from the opaque colors
+		if opts.Matte != nil {
+			newFrames = matteFrames(newFrames, *opts.Matte)
+		}
+
+		if size != canvas.Size() {
+			newFrames, err = resizeFrames(ctx, newFrames, canvas, size, opts.Quantizer, dither, uint(opts.Threads))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// drawn last so the text keeps its color and stays sharp
+		if len(opts.Text) != 0 {
+			area, _ := textRect(outputCanvas, textSize(opts.Text), opts.TextPosition)
+			newFrames = drawTextFrames(newFrames, outputCanvas, opts.Text, area, opts.TextColor.Clamped())
+		}
+
+		// a GIF can't hold more, and encoding fails on such a palette with nothing to say which frame it was
+		limited += limitPalettes(newFrames)
+
+		return newFrames, nil
+	}
+
+	warnLimited := func() {
+		if limited > 0 && opts.Warn != nil {
+			opts.Warn(fmt.Sprintf("%d frames had more than 256 colors, the ones no pixel can use were dropped", limited))
+		}
+	}
+
+	newDisposal := make([]byte, frameCount)
+	if len(src.Disposal) > 0 {
+		for i := range newDisposal {
+			newDisposal[i] = src.Disposal[i%len(src.Disposal)]
+		}
+	}
+
+	// just the one frame with the overlay and delay it gets in the full output, none of the others are blended
+	if pick != nil {
+		index := pick(int(frameCount))
+		if index < 0 || index >= int(frameCount) {
+			return nil, fmt.Errorf("Picked frame %d is outside of the %d output frames", index, frameCount)
+		}
+
+		progressFirst, progressTotal = uint(index), 1
+		newFrames, err := render(uint(index), uint(index)+1)
+		if err != nil {
+			return nil, err
+		}
+
+		// reduced by itself, the other frames' colors aren't there to share a palette with
+		if opts.MaxColors > 0 {
+			newFrames = quantizeFrames(newFrames, opts.MaxColors, opts.Quality, dither)
+		}
+
+		warnLimited()
+
+		img := *src
+		img.Image = newFrames
+		img.Delay = newDelay[index : index+1]
+		img.Disposal = newDisposal[index : index+1]
+		img.Config.ColorModel = nil
+		img.BackgroundIndex = 0
+		if opts.Width != 0 || opts.Height != 0 {
+			img.Config.Width = size.X
+			img.Config.Height = size.Y
+		}
+
+		return &img, nil
+	}
+
+	if out != nil {
+		width, height := src.Config.Width, src.Config.Height
+		if opts.Width != 0 || opts.Height != 0 {
+			width, height = size.X, size.Y
+		}
+		if width == 0 || height == 0 {
+			width, height = canvas.Max.X, canvas.Max.Y
+		}
+
+		if err := out.Start(width, height, int(frameCount)); err != nil {
+			return nil, err
+		}
+
+		// a few frames per thread at a time keeps every thread busy while only a handful of frames are held
+		batch := uint(opts.Threads) * 4
+		for start := uint(0); start < frameCount; start += batch {
+			end := start + batch
+			if end > frameCount {
+				end = frameCount
+			}
+
+			newFrames, err := render(start, end)
+			if err != nil {
+				return nil, err
+			}
+
+			// every frame has a palette of its own, so they can be sorted a batch at a time
+			if paletteOrder != nil {
+				newFrames = orderPalettes(newFrames, paletteOrder)
+			}
+
+			for i, frame := range newFrames {
+				if err := out.WriteFrame(frame, newDelay[start+uint(i)], newDisposal[start+uint(i)]); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		warnLimited()
+		return nil, nil
+	}
+
+	newFrames, err := render(0, frameCount)
+	if err != nil {
+		return nil, err
+	}
+
+	warnLimited()
+
+	var globalPalette color.Palette
+	if opts.GlobalPalette {
+		paletteSize := 256
+		if opts.MaxColors > 0 {
+			paletteSize = opts.MaxColors
+		}
+
+		newFrames, globalPalette = globalPaletteFrames(newFrames, paletteSize, opts.Quality, dither)
+	} else if opts.MaxColors > 0 {
+		newFrames = quantizeFrames(newFrames, opts.MaxColors, opts.Quality, dither)
+	} else if opts.PaletteFromFirst {
+		newFrames, globalPalette = firstPaletteFrames(newFrames)
+	}
+
+	// last so nothing reshuffles the palettes afterwards, the background index is looked up in the sorted one below
+	if paletteOrder != nil {
+		newFrames = orderPalettes(newFrames, paletteOrder)
+		if globalPalette != nil {
+			globalPalette = newFrames[0].Palette
+		}
+	}
+
+	if opts.Dedupe {
+		newFrames, newDelay, newDisposal = dedupeFrames(newFrames, newDelay, newDisposal)
+	}
+
+	img := *src
+	img.Image = newFrames
+	img.Delay = newDelay
+	img.Disposal = newDisposal
+	img.Config.ColorModel = nil
+	img.BackgroundIndex = 0
+	if opts.Width != 0 || opts.Height != 0 {
+		img.Config.Width = size.X
+		img.Config.Height = size.Y
+	}
+
+	// the background index refers to the global palette, so one is needed to set it
+	if globalPalette == nil && (opts.Background != nil || opts.Transparent) {
+		globalPalette = newFrames[0].Palette
+	}
+
+	if globalPalette != nil {
+		img.Config.ColorModel = globalPalette
+		img.BackgroundIndex = backgroundIndex(globalPalette, opts.Background, opts.Transparent)
+	}
+
+	/* the encoder only sizes the canvas by the first frame when Config is empty
+	 * so later frames that are bigger or further out would fall outside of it
+	 */
+	if img.Config.Width == 0 || img.Config.Height == 0 {
+		bounds := canvasBounds(&img)
+		img.Config.Width = bounds.Max.X
+		img.Config.Height = bounds.Max.Y
+	}
+
+	return &img, nil
+}
+
+/* the palette index viewers should use for the background
+ * transparent picks the first fully transparent color, falling back to 0 when there isn't one
+ */
+func backgroundIndex(palette color.Palette, background *colorful.Color, transparent bool) uint8 {
+	if transparent {
+		for i, c := range palette {
+			if _, _, _, alpha := c.RGBA(); alpha == 0 {
+				return uint8(i)
+			}
+		}
+	}
+
+	if background != nil {
+		return uint8(palette.Index(*background))
+	}
+
+	return 0
+}
+
+/* multiplies every delay by scale, rounding to the nearest 100th of a second but never below 1
+ * what rounding takes off or adds to one delay is carried over to the next, so the total stays within 1 of the scaled total
+ * rather than drifting further with every frame, raising a delay to 1 isn't carried so it doesn't cut into the frames after it
+ */
+func scaleDelays(delays []int, scale float64) []int {
+	scaled := make([]int, len(delays))
+
+	var carry float64
+	for i, delay := range delays {
+		exact := float64(delay)*scale + carry
+		scaled[i] = int(math.Round(exact))
+		carry = exact - float64(scaled[i])
+
+		if scaled[i] < 1 {
+			scaled[i] = 1
+		}
+	}
+
+	return scaled
+}
+
+/* ClampDelays raises every delay below minDelay up to it, in place
+ * browsers play very short delays inconsistently, 2 is a safe minimum
+ * returns how many delays were changed
+ */
+func ClampDelays(delays []int, minDelay int) int {
+	var adjusted int
+
+	for i, delay := range delays {
+		if delay < minDelay {
+			delays[i] = minDelay
+			adjusted++
+		}
+	}
+
+	return adjusted
+}
+
+/* source frames are only ever read - looped output frames come from the same source frame,
+ * so each output frame gets its own copy of the pixels and can be written to freely
+ */
+func copyPixels(dst *image.Paletted, src *image.Paletted) {
+	bounds := src.Bounds()
+	width := bounds.Dx()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		srcOffset := src.PixOffset(bounds.Min.X, y)
+		dstOffset := dst.PixOffset(bounds.Min.X, y)
+		copy(dst.Pix[dstOffset:dstOffset+width], src.Pix[srcOffset:srcOffset+width])
+	}
+}
+
+func prepareFrame(src *image.Paletted, dst *image.Paletted, overlayColor colorful.Color, blend blendFunc, opacity float64, respectAlpha bool, channels channelMask) {
+	copyPixels(dst, src)
+
+	for pixelIndex, pixel := range src.Palette {
+		blended := blendPixel(pixel, overlayColor, blend, alphaOpacity(pixel, opacity, respectAlpha))
+		dst.Palette[pixelIndex] = keepChannels(pixel, blended, channels)
+	}
+}
+
+// scales opacity by the color's alpha when respectAlpha is set
+func alphaOpacity(pixel color.Color, opacity float64, respectAlpha bool) float64 {
+	if !respectAlpha {
+		return opacity
+	}
+
+	_, _, _, alpha := pixel.RGBA()
+	return opacity * float64(alpha) / 0xffff
+}
+
+/* blends a single color with the overlay, transparent colors are returned as is
+ * so are colors at opacity 0, going through HCL and rounding back to 8 bits would shift them slightly
+ */
+func blendPixel(pixel color.Color, overlayColor colorful.Color, blend blendFunc, opacity float64) color.Color {
+	convertedPixel, alpha, ok := straightColor(pixel)
+
+	if !ok || opacity == 0 {
+		return pixel
+	}
+
+	convertedPixel = convertedPixel.Clamped()
+
+	blendedPixel := blendOpacity(blend(overlayColor, convertedPixel), convertedPixel, opacity)
+
+	// only the color is blended, the original alpha is kept as is
+	blendedR, blendedG, blendedB := blendedPixel.RGB255()
+	return color.NRGBA{
+		blendedR,
+		blendedG,
+		blendedB,
+		alpha,
+	}
+}
+
+/* ProcessFrames blends overlay[i] over src[i % len(src)] with the color blend at full opacity
+ * it's the core frame loop of Rainbowify without any options, decoding, or encoding, meant for benchmarks
+ * returns one frame per overlay color, threads below 1 are treated as 1
+ */
+func ProcessFrames(src []*image.Paletted, overlay []colorful.Color, threads int) []*image.Paletted {
+	if len(src) == 0 {
+		return nil
+	}
+
+	if threads < 1 {
+		threads = 1
+	}
+
+	opacities := make([]float64, len(overlay))
+	for i := range opacities {
+		opacities[i] = 1
+	}
+
+	// processFrames only fails once the context is done, which a background context never is
+	frames, _ := processFrames(context.Background(), src, overlay, opacities, blendColor, false, allChannels, nil, uint(threads))
+	return frames
+}
+
+func processFrames(ctx context.Context, frames []*image.Paletted, overlayColors []colorful.Color, opacities []float64, blend blendFunc, respectAlpha bool, channels channelMask, progress func(done int, total int), threads uint) ([]*image.Paletted, error) {
+	frameCount := uint(len(overlayColors))
+	newFrames := make([]*image.Paletted, frameCount)
+	for i := range newFrames {
+		// palettes are only allocated for blends that aren't cached yet
+		newFrames[i] = image.NewPaletted(frames[i%len(frames)].Bounds(), nil)
+	}
+
+	cache := newPaletteCache()
+
+	err := forEachFrame(ctx, frameCount, threads, progress, func(frameIndex uint) error {
+		normalizedFrameIndex := frameIndex % uint(len(frames))
+		src := frames[normalizedFrameIndex]
+		dst := newFrames[frameIndex]
+
+		// looped frames and shared palettes end up with the same blend
+		key := newPaletteKey(src.Palette, overlayColors[frameIndex], opacities[frameIndex])
+		if palette, okay := cache.get(key); okay {
+			copyPixels(dst, src)
+			dst.Palette = palette
+			return nil
+		}
+
+		// do actual work in here
+		dst.Palette = make(color.Palette, len(src.Palette))
+		prepareFrame(
+			src,
+			dst,
+			overlayColors[frameIndex],
+			blend,
+			opacities[frameIndex],
+			respectAlpha,
+			channels,
+		)
+		cache.put(key, dst.Palette)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newFrames, nil
+}
+
+// no more workers than frames, so short animations don't start goroutines that would only sit idle
+func workerCount(threads uint, frameCount uint) uint {
+	if threads > frameCount {
+		threads = frameCount
+	}
+
+	if threads < 1 {
+		return 1
+	}
+
+	return threads
+}
+
+/* runs work for every frame index on threads workers pulling from a shared queue
+ * so a slow frame doesn't hold up the frames that would otherwise be queued behind it
+ * progress, when not nil, is called once per finished frame - never concurrently
+ * returns ctx.Err() once cancelled, otherwise the error of the lowest failing frame
+ */
+func forEachFrame(ctx context.Context, frameCount uint, threads uint, progress func(done int, total int), work func(frameIndex uint) error) error {
+	errs := make([]error, frameCount)
+	var wg sync.WaitGroup
+
+	var progressLock sync.Mutex
+	var done int
+
+	// buffered so workers rarely wait on the queue, without queueing every frame up front
+	jobs := make(chan uint, threads)
+	go func() {
+		defer close(jobs)
+
+		for frameIndex := uint(0); frameIndex < frameCount; frameIndex++ {
+			// stop queueing once cancelled
+			if ctx.Err() != nil {
+				return
+			}
+
+			jobs <- frameIndex
+		}
+	}()
+
+	for i := uint(0); i < workerCount(threads, frameCount); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// workers keep draining the queue so it never blocks, even after a failure
+			for frameIndex := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				if err := work(frameIndex); err != nil {
+					errs[frameIndex] = err
+					continue
+				}
+
+				if progress != nil {
+					progressLock.Lock()
+					done++
+					progress(done, int(frameCount))
+					progressLock.Unlock()
+				}
+			}
+		}()
+	}
+
+	// wait for all threads to finish
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
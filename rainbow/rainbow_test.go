@@ -0,0 +1,1114 @@
+package rainbow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// builds a small animated GIF where every frame has its own palette and pixels
+func newTestGIF(frameCount int, width int, height int) *gif.GIF {
+	img := &gif.GIF{
+		Image:    make([]*image.Paletted, frameCount),
+		Delay:    make([]int, frameCount),
+		Disposal: make([]byte, frameCount),
+	}
+
+	for i := range img.Image {
+		palette := color.Palette{
+			color.RGBA{R: uint8(20 * i), G: 0, B: 0, A: 255},
+			color.RGBA{R: 0, G: uint8(20 * i), B: 128, A: 255},
+			color.RGBA{R: 200, G: 200, B: uint8(20 * i), A: 255},
+			color.RGBA{R: 0, G: 0, B: 0, A: 0},
+		}
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8((i + j) % len(palette))
+		}
+
+		img.Image[i] = frame
+		img.Delay[i] = 10
+	}
+
+	return img
+}
+
+func TestProcessFrames(t *testing.T) {
+	t.Run(
+		"Output is independent of thread count",
+		func(innerT *testing.T) {
+			src := newTestGIF(10, 4, 4)
+			colors, _, err := ParseGradientColors("")
+			if err != nil {
+				innerT.Fatal(err)
+			}
+			gradient := newGradient(colors, true)
+			overlayColors := gradient.generate(uint(len(src.Image)) * 2)
+
+			var expected []byte
+			for _, threads := range []uint{1, 2, 4} {
+				out := *src
+				frames, err := processFrames(
+					context.Background(),
+					src.Image,
+					overlayColors,
+					gradient.generateOpacity(uint(len(overlayColors))),
+					blendColor,
+					false,
+					allChannels,
+					nil,
+					threads,
+				)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+				out.Image = frames
+				out.Delay = make([]int, len(out.Image))
+				out.Disposal = make([]byte, len(out.Image))
+
+				var buf bytes.Buffer
+				if err := gif.EncodeAll(&buf, &out); err != nil {
+					innerT.Fatalf("Error encoding with %d threads: %v", threads, err)
+				}
+
+				if expected == nil {
+					expected = buf.Bytes()
+					continue
+				}
+
+				if !bytes.Equal(expected, buf.Bytes()) {
+					innerT.Errorf("Output with %d threads differs from output with 1 thread", threads)
+				}
+			}
+		},
+	)
+}
+
+func TestPrepareFrame(t *testing.T) {
+	t.Run(
+		"Alpha is preserved",
+		func(innerT *testing.T) {
+			palette := color.Palette{
+				color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				color.NRGBA{R: 0, G: 0, B: 255, A: 128},
+				color.NRGBA{R: 0, G: 0, B: 0, A: 0},
+			}
+			src := image.NewPaletted(image.Rect(0, 0, 3, 1), palette)
+			dst := image.NewPaletted(src.Bounds(), make(color.Palette, len(palette)))
+
+			prepareFrame(src, dst, colorful.Color{R: 0, G: 1, B: 0}, blendOpaque, 1, false, allChannels)
+
+			for i, expected := range []uint8{255, 128, 0} {
+				actual := color.NRGBAModel.Convert(dst.Palette[i]).(color.NRGBA)
+				if actual.A != expected {
+					innerT.Errorf("Palette %d - expected %v but got %v", i, expected, actual.A)
+				}
+			}
+
+			blended := color.NRGBAModel.Convert(dst.Palette[1]).(color.NRGBA)
+			if blended.R != 0 || blended.G != 255 || blended.B != 0 {
+				innerT.Errorf("Expected %v but got %v", color.NRGBA{R: 0, G: 255, B: 0, A: 128}, blended)
+			}
+		},
+	)
+}
+
+func TestPrepareFrameRespectAlpha(t *testing.T) {
+	palette := color.Palette{
+		color.NRGBA{R: 255, G: 0, B: 0, A: 0},
+		color.NRGBA{R: 255, G: 0, B: 0, A: 128},
+		color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+	}
+	src := image.NewPaletted(image.Rect(0, 0, 3, 1), palette)
+	dst := image.NewPaletted(src.Bounds(), make(color.Palette, len(palette)))
+
+	overlay := colorful.Color{R: 0, G: 0, B: 1}
+	prepareFrame(src, dst, overlay, blendOpaque, 1, true, allChannels)
+
+	cases := []struct {
+		name     string
+		index    int
+		expected color.Color
+	}{
+		{name: "Transparent", index: 0, expected: palette[0]},
+		{name: "Half", index: 1, expected: blendPixel(palette[1], overlay, blendOpaque, 128.0/255)},
+		{name: "Opaque", index: 2, expected: color.NRGBA{R: 0, G: 0, B: 255, A: 255}},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				expected := color.NRGBAModel.Convert(c.expected)
+				actual := color.NRGBAModel.Convert(dst.Palette[c.index])
+				if actual != expected {
+					innerT.Errorf("Expected %v but got %v", expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyBlendFunc(t *testing.T) {
+	overlay := colorful.Color{R: 0, G: 0, B: 1}
+
+	opts := DefaultOptions()
+	opts.Colors = []colorful.Color{overlay, overlay}
+	opts.BlendFunc = func(overlay colorful.Color, base colorful.Color) colorful.Color {
+		return overlay
+	}
+
+	out, err := Rainbowify(newTestGIF(3, 2, 2), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for _, frame := range out.Image {
+		for _, c := range frame.Palette {
+			// transparent colors are never blended
+			if _, _, _, alpha := c.RGBA(); alpha == 0 {
+				continue
+			}
+
+			expected := color.NRGBA{R: 0, G: 0, B: 255, A: 255}
+			if actual := color.NRGBAModel.Convert(c); actual != expected {
+				t.Errorf("Expected %v but got %v", expected, actual)
+			}
+		}
+	}
+}
+
+func TestRainbowifyOpacityZero(t *testing.T) {
+	for _, mode := range []string{"blend", "huerotate"} {
+		t.Run(
+			mode,
+			func(innerT *testing.T) {
+				src := newTestGIF(3, 4, 4)
+				// colors that don't survive a trip through HCL and back exactly
+				for _, frame := range src.Image {
+					frame.Palette[0] = color.RGBA{R: 0, G: 78, B: 129, A: 255}
+				}
+
+				opts := DefaultOptions()
+				opts.Mode = mode
+				opts.Opacity = 0
+
+				out, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				for i, frame := range out.Image {
+					bounds := frame.Bounds()
+					for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+						for x := bounds.Min.X; x < bounds.Max.X; x++ {
+							expected := color.NRGBAModel.Convert(src.Image[i].At(x, y))
+							actual := color.NRGBAModel.Convert(frame.At(x, y))
+							if actual != expected {
+								innerT.Errorf("Expected %v but got %v", expected, actual)
+							}
+						}
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestWorkerCount(t *testing.T) {
+	cases := []struct {
+		threads    uint
+		frameCount uint
+		expected   uint
+	}{
+		{threads: 4, frameCount: 10, expected: 4},
+		{threads: 8, frameCount: 3, expected: 3},
+		{threads: 1, frameCount: 1, expected: 1},
+		{threads: 4, frameCount: 0, expected: 1},
+		{threads: 0, frameCount: 5, expected: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			fmt.Sprintf("%d threads for %d frames", c.threads, c.frameCount),
+			func(innerT *testing.T) {
+				actual := workerCount(c.threads, c.frameCount)
+				if actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyLoopMode(t *testing.T) {
+	src := newTestGIF(4, 2, 2)
+
+	cases := []struct {
+		mode string
+		same bool
+	}{
+		{mode: "continuous", same: false},
+		{mode: "per-loop", same: true},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.mode,
+			func(innerT *testing.T) {
+				opts := DefaultOptions()
+				opts.LoopCount = 2
+				opts.LoopMode = c.mode
+
+				out, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				first := out.Image[0].Palette[0]
+				repeated := out.Image[len(src.Image)].Palette[0]
+				if (first == repeated) != c.same {
+					innerT.Errorf("Expected %v and %v to be the same: %v", first, repeated, c.same)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowify(t *testing.T) {
+	t.Run(
+		"Source is untouched",
+		func(innerT *testing.T) {
+			src := newTestGIF(3, 2, 2)
+			palette := make(color.Palette, len(src.Image[0].Palette))
+			copy(palette, src.Image[0].Palette)
+
+			out, err := Rainbowify(src, DefaultOptions())
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(src.Image) != 3 || len(out.Image) != 3 {
+				innerT.Errorf("Expected %v but got %v and %v", 3, len(src.Image), len(out.Image))
+			}
+
+			for i := range palette {
+				if src.Image[0].Palette[i] != palette[i] {
+					innerT.Errorf("Expected %v but got %v", palette[i], src.Image[0].Palette[i])
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Still uses a single frame",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Still = true
+
+			out, err := Rainbowify(newTestGIF(1, 2, 2), opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(out.Image) != 1 {
+				innerT.Errorf("Expected %v but got %v", 1, len(out.Image))
+			}
+		},
+	)
+
+	invalid := []struct {
+		name   string
+		modify func(opts *Options)
+	}{
+		{name: "No colors", modify: func(opts *Options) { opts.Colors = nil }},
+		{name: "Mismatched opacities", modify: func(opts *Options) { opts.Opacities = []float64{1} }},
+		{name: "No threads", modify: func(opts *Options) { opts.Threads = 0 }},
+		{name: "No loops", modify: func(opts *Options) { opts.LoopCount = 0 }},
+		{name: "No cycles", modify: func(opts *Options) { opts.Cycles = 0 }},
+		{name: "Phase of 1", modify: func(opts *Options) { opts.Phase = 1 }},
+		{name: "Keep dominant with hue rotate", modify: func(opts *Options) {
+			opts.KeepDominant = true
+			opts.Mode = "huerotate"
+		}},
+		{name: "Negative sweep seconds", modify: func(opts *Options) { opts.SweepSeconds = -1 }},
+		{name: "Sweep seconds and cycles", modify: func(opts *Options) {
+			opts.SweepSeconds = 1
+			opts.Cycles = 2
+		}},
+		{name: "Opacity above 1", modify: func(opts *Options) { opts.Opacity = 1.5 }},
+		{name: "Unknown blend", modify: func(opts *Options) { opts.Blend = "dodge" }},
+		{name: "Blend factor above 1", modify: func(opts *Options) { opts.BlendFactor = 1.5 }},
+		{name: "Blend factor with multiply", modify: func(opts *Options) { opts.Blend = "multiply"; opts.BlendFactor = 0.5 }},
+		{name: "Background and transparent", modify: func(opts *Options) { opts.Background = &colorful.Color{R: 1, G: 1, B: 1}; opts.Transparent = true }},
+		{name: "Zero gamma", modify: func(opts *Options) { opts.Gamma = 0 }},
+		{name: "One max color", modify: func(opts *Options) { opts.MaxColors = 1 }},
+		{name: "Too many max colors", modify: func(opts *Options) { opts.MaxColors = 257 }},
+		{name: "Palette from first with max colors", modify: func(opts *Options) { opts.MaxColors = 16; opts.PaletteFromFirst = true }},
+		{name: "No quality", modify: func(opts *Options) { opts.Quality = 0 }},
+		{name: "Invalid dither", modify: func(opts *Options) { opts.Dither = "sideways" }},
+		{name: "Invalid orientation", modify: func(opts *Options) { opts.Orient = "45" }},
+		{name: "Invalid palette order", modify: func(opts *Options) { opts.PaletteOrder = "alphabetical" }},
+		{name: "Negative width", modify: func(opts *Options) { opts.Width = -1 }},
+		{name: "Crop outside the canvas", modify: func(opts *Options) { opts.Crop = image.Rect(2, 2, 100, 100) }},
+		{name: "Invalid text position", modify: func(opts *Options) { opts.Text = "hi"; opts.TextPosition = "left" }},
+		{name: "Invalid loop mode", modify: func(opts *Options) { opts.LoopMode = "sometimes" }},
+		{name: "Invalid channels", modify: func(opts *Options) { opts.Channels = "rgba" }},
+		{name: "Channels with huerotate", modify: func(opts *Options) { opts.Mode = "huerotate"; opts.Channels = "r" }},
+		{name: "Too much quality", modify: func(opts *Options) { opts.Quality = 11 }},
+		{name: "Invalid intensity ramp", modify: func(opts *Options) { opts.IntensityRamp = "wobble" }},
+		{name: "Zero every", modify: func(opts *Options) { opts.Every = 0 }},
+		{name: "Respect alpha with huerotate", modify: func(opts *Options) { opts.Mode = "huerotate"; opts.RespectAlpha = true }},
+		{name: "Preserve with huerotate", modify: func(opts *Options) { opts.Mode = "huerotate"; opts.Preserve = true }},
+		{name: "Black and white with huerotate", modify: func(opts *Options) { opts.Mode = "huerotate"; opts.PreserveBlackWhite = true }},
+		{name: "Black and white tolerance too high", modify: func(opts *Options) { opts.BlackWhiteTolerance = 128 }},
+		{name: "Linear color blend", modify: func(opts *Options) { opts.Linear = true }},
+		{name: "Negative saturation", modify: func(opts *Options) { opts.Saturation = -1 }},
+		{name: "Unknown mode", modify: func(opts *Options) { opts.Mode = "invert" }},
+		{name: "Unknown easing", modify: func(opts *Options) { opts.Easing = "bounce" }},
+		{name: "Unknown interpolation", modify: func(opts *Options) { opts.Interpolation = "cmyk" }},
+		{name: "No delay scale", modify: func(opts *Options) { opts.DelayScale = 0 }},
+		{name: "Negative FPS", modify: func(opts *Options) { opts.FPS = -1 }},
+		{name: "FPS and delay", modify: func(opts *Options) { opts.FPS = 30; opts.Delay = 5 }},
+		{name: "Center outside the frame", modify: func(opts *Options) { opts.CenterX = -0.5 }},
+	}
+
+	for _, c := range invalid {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				opts := DefaultOptions()
+				c.modify(&opts)
+
+				if _, err := Rainbowify(newTestGIF(2, 2, 2), opts); err == nil {
+					innerT.Errorf("Expected an error but got %v", err)
+				}
+			},
+		)
+	}
+}
+
+func TestScaleDelays(t *testing.T) {
+	cases := []struct {
+		name     string
+		delays   []int
+		scale    float64
+		expected []int
+	}{
+		{name: "Twice as fast", delays: []int{10, 20}, scale: 0.5, expected: []int{5, 10}},
+		{name: "Half as fast", delays: []int{10, 20}, scale: 2, expected: []int{20, 40}},
+		// 1.5 rounds up to 2, so the 2.5 after it gets the half back and rounds down
+		{name: "Rounded", delays: []int{3, 5}, scale: 0.5, expected: []int{2, 2}},
+		{name: "At least 1", delays: []int{0, 1}, scale: 0.1, expected: []int{1, 1}},
+		{name: "Thirds", delays: []int{10, 10, 10, 10, 10, 10}, scale: 1.0 / 3, expected: []int{3, 4, 3, 3, 4, 3}},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				actual := scaleDelays(c.delays, c.scale)
+				if !reflect.DeepEqual(actual, c.expected) {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestScaleDelaysTotal(t *testing.T) {
+	delays := make([]int, 100)
+	for i := range delays {
+		delays[i] = 7
+	}
+
+	for _, scale := range []float64{0.3, 0.45, 1.0 / 3, 1.7, 2.25} {
+		t.Run(
+			fmt.Sprintf("%v", scale),
+			func(innerT *testing.T) {
+				var total int
+				for _, delay := range scaleDelays(delays, scale) {
+					total += delay
+				}
+
+				if target := 700 * scale; math.Abs(float64(total)-target) > 1 {
+					innerT.Errorf("Expected %v but got %v", target, total)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyFPS(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FPS = 30
+	opts.LoopCount = 25
+
+	out, err := Rainbowify(newTestGIF(4, 2, 2), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// 100 frames at 30 fps take 3.33 seconds
+	var total int
+	for _, delay := range out.Delay {
+		if delay != 3 && delay != 4 {
+			t.Errorf("Expected a delay of 3 or 4 but got %v", delay)
+		}
+		total += delay
+	}
+
+	if math.Abs(float64(total)-1000.0/3) > 1 {
+		t.Errorf("Expected %v but got %v", 1000.0/3, total)
+	}
+}
+
+func TestRainbowifySweepSeconds(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SweepSeconds = 1
+	opts.LoopCount = 2
+
+	// the same frame every time so only the gradient changes between them
+	src := newTestGIF(4, 2, 2)
+	for i := range src.Image {
+		src.Image[i] = src.Image[0]
+	}
+
+	normal, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	opts.DelayScale = 2
+	doubled, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// twice the delay covers twice as much of the sweep per frame
+	for i := 0; i < 4; i++ {
+		expected := framePixels(normal.Image[i*2])
+		actual := framePixels(doubled.Image[i])
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Frame %d - expected %v but got %v", i, expected, actual)
+		}
+	}
+}
+
+func TestClampDelays(t *testing.T) {
+	t.Run(
+		"All zero",
+		func(innerT *testing.T) {
+			delays := []int{0, 0, 0}
+			adjusted := ClampDelays(delays, 2)
+
+			if !reflect.DeepEqual(delays, []int{2, 2, 2}) {
+				innerT.Errorf("Expected %v but got %v", []int{2, 2, 2}, delays)
+			}
+
+			if adjusted != 3 {
+				innerT.Errorf("Expected %v but got %v", 3, adjusted)
+			}
+		},
+	)
+
+	t.Run(
+		"Long delays are untouched",
+		func(innerT *testing.T) {
+			delays := []int{1, 2, 10}
+			adjusted := ClampDelays(delays, 2)
+
+			if !reflect.DeepEqual(delays, []int{2, 2, 10}) {
+				innerT.Errorf("Expected %v but got %v", []int{2, 2, 10}, delays)
+			}
+
+			if adjusted != 1 {
+				innerT.Errorf("Expected %v but got %v", 1, adjusted)
+			}
+		},
+	)
+}
+
+func TestBackgroundIndex(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 0, G: 0, B: 0, A: 255},
+		color.RGBA{R: 250, G: 10, B: 10, A: 255},
+		color.RGBA{R: 0, G: 0, B: 0, A: 0},
+		color.RGBA{R: 10, G: 10, B: 240, A: 255},
+	}
+
+	cases := []struct {
+		name        string
+		background  *colorful.Color
+		transparent bool
+		expected    uint8
+	}{
+		{name: "None", expected: 0},
+		{name: "Exact", background: &colorful.Color{R: 250.0 / 255, G: 10.0 / 255, B: 10.0 / 255}, expected: 1},
+		{name: "Nearest", background: &colorful.Color{R: 0, G: 0, B: 1}, expected: 3},
+		{name: "Transparent", transparent: true, expected: 2},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				actual := backgroundIndex(palette, c.background, c.transparent)
+				if actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Written as the global palette",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Background = &colorful.Color{R: 1, G: 1, B: 1}
+
+			out, err := Rainbowify(newTestGIF(2, 2, 2), opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			palette, ok := out.Config.ColorModel.(color.Palette)
+			if !ok {
+				innerT.Fatalf("Expected a color.Palette but got %v", out.Config.ColorModel)
+			}
+
+			expected := uint8(palette.Index(*opts.Background))
+			if out.BackgroundIndex != expected {
+				innerT.Errorf("Expected %v but got %v", expected, out.BackgroundIndex)
+			}
+		},
+	)
+}
+
+func TestRainbowifyContext(t *testing.T) {
+	// lots of small frames with full palettes so processing takes a while
+	src := &gif.GIF{Image: make([]*image.Paletted, 100)}
+	for i := range src.Image {
+		palette := make(color.Palette, 256)
+		for j := range palette {
+			palette[j] = color.RGBA{R: uint8(j), G: uint8(i), B: 128, A: 255}
+		}
+		src.Image[i] = image.NewPaletted(image.Rect(0, 0, 1, 1), palette)
+	}
+
+	opts := DefaultOptions()
+	opts.Threads = 2
+	opts.LoopCount = 200
+	// 20000 frames so there's time to cancel
+	opts.MaxFrames = 0
+
+	t.Run(
+		"Cancelled mid processing",
+		func(innerT *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			start := time.Now()
+			out, err := RainbowifyContext(ctx, src, opts)
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, context.Canceled) {
+				innerT.Errorf("Expected %v but got %v", context.Canceled, err)
+			}
+
+			if out != nil {
+				innerT.Errorf("Expected %v but got %v", nil, out)
+			}
+
+			if elapsed > 2*time.Second {
+				innerT.Errorf("Expected to return quickly but took %v", elapsed)
+			}
+		},
+	)
+
+	t.Run(
+		"Already cancelled",
+		func(innerT *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			if _, err := RainbowifyContext(ctx, newTestGIF(2, 2, 2), DefaultOptions()); !errors.Is(err, context.Canceled) {
+				innerT.Errorf("Expected %v but got %v", context.Canceled, err)
+			}
+		},
+	)
+}
+
+// a 100 frame GIF looped 5 times, every loop sharing the same overlay colors
+func benchmarkFrames(uniqueOverlays bool) ([]*image.Paletted, []colorful.Color, []float64) {
+	src := &gif.GIF{Image: make([]*image.Paletted, 100)}
+	for i := range src.Image {
+		palette := make(color.Palette, 256)
+		for j := range palette {
+			palette[j] = color.RGBA{R: uint8(j), G: uint8(i), B: 128, A: 255}
+		}
+		src.Image[i] = image.NewPaletted(image.Rect(0, 0, 16, 16), palette)
+	}
+
+	gradient := newGradient([]colorful.Color{{R: 1, G: 0, B: 0}, {R: 0, G: 0, B: 1}}, true)
+	overlayColors := make([]colorful.Color, 500)
+	if uniqueOverlays {
+		overlayColors = gradient.generate(500)
+	} else {
+		loop := gradient.generate(100)
+		for i := range overlayColors {
+			overlayColors[i] = loop[i%len(loop)]
+		}
+	}
+
+	opacities := make([]float64, len(overlayColors))
+	for i := range opacities {
+		opacities[i] = 1
+	}
+
+	return src.Image, overlayColors, opacities
+}
+
+func BenchmarkProcessFramesLooped(b *testing.B) {
+	cases := []struct {
+		name   string
+		unique bool
+	}{
+		{name: "Repeated overlays", unique: false},
+		{name: "Unique overlays", unique: true},
+	}
+
+	for _, c := range cases {
+		frames, overlayColors, opacities := benchmarkFrames(c.unique)
+
+		b.Run(
+			c.name,
+			func(innerB *testing.B) {
+				innerB.ReportAllocs()
+				for i := 0; i < innerB.N; i++ {
+					if _, err := processFrames(context.Background(), frames, overlayColors, opacities, blendColor, false, allChannels, nil, 2); err != nil {
+						innerB.Fatal(err)
+					}
+				}
+			},
+		)
+	}
+}
+
+// every fourth frame has a full palette and the rest only two colors
+func unevenFrames(frameCount int) ([]*image.Paletted, []colorful.Color, []float64) {
+	frames := make([]*image.Paletted, frameCount)
+	overlayColors := make([]colorful.Color, frameCount)
+	opacities := make([]float64, frameCount)
+
+	for i := range frames {
+		paletteSize := 2
+		if i%4 == 0 {
+			paletteSize = 256
+		}
+
+		palette := make(color.Palette, paletteSize)
+		for j := range palette {
+			palette[j] = color.RGBA{R: uint8(j), G: uint8(i), B: uint8(255 - j), A: 255}
+		}
+
+		frames[i] = image.NewPaletted(image.Rect(0, 0, 16, 16), palette)
+		overlayColors[i] = colorful.Hsv(float64(i)*360/float64(frameCount), 1, 1)
+		opacities[i] = 1
+	}
+
+	return frames, overlayColors, opacities
+}
+
+func BenchmarkProcessFramesUneven(b *testing.B) {
+	frames, overlayColors, opacities := unevenFrames(64)
+
+	for _, threads := range []uint{1, 4} {
+		b.Run(
+			fmt.Sprintf("%d threads", threads),
+			func(innerB *testing.B) {
+				for i := 0; i < innerB.N; i++ {
+					if _, err := processFrames(context.Background(), frames, overlayColors, opacities, blendColor, false, allChannels, nil, threads); err != nil {
+						innerB.Fatal(err)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestExportedProcessFrames(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := newTestGIF(3, 4, 4).Image
+	overlay := newGradient(colors, true).generate(6)
+
+	opacities := make([]float64, len(overlay))
+	for i := range opacities {
+		opacities[i] = 1
+	}
+
+	expected, err := processFrames(context.Background(), src, overlay, opacities, blendColor, false, allChannels, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, threads := range []int{0, 1, 4} {
+		t.Run(
+			fmt.Sprintf("%d threads", threads),
+			func(innerT *testing.T) {
+				actual := ProcessFrames(src, overlay, threads)
+				if len(actual) != len(expected) {
+					innerT.Fatalf("Expected %v but got %v", len(expected), len(actual))
+				}
+
+				for i := range actual {
+					if !reflect.DeepEqual(actual[i].Palette, expected[i].Palette) || !bytes.Equal(actual[i].Pix, expected[i].Pix) {
+						innerT.Errorf("Frame %d - expected %v but got %v", i, expected[i].Palette, actual[i].Palette)
+					}
+				}
+			},
+		)
+	}
+}
+
+// 16 frames with a full palette each
+func fullPaletteFrames() ([]*image.Paletted, []colorful.Color) {
+	frames := make([]*image.Paletted, 16)
+	for i := range frames {
+		palette := make(color.Palette, 256)
+		for j := range palette {
+			palette[j] = color.RGBA{R: uint8(j), G: uint8(i * 16), B: uint8(255 - j), A: 255}
+		}
+
+		frames[i] = image.NewPaletted(image.Rect(0, 0, 64, 64), palette)
+		for j := range frames[i].Pix {
+			frames[i].Pix[j] = uint8(j)
+		}
+	}
+
+	overlay := make([]colorful.Color, len(frames))
+	for i := range overlay {
+		overlay[i] = colorful.Hsv(float64(i)*360/float64(len(overlay)), 1, 1)
+	}
+
+	return frames, overlay
+}
+
+func BenchmarkProcessFrames(b *testing.B) {
+	frames, overlay := fullPaletteFrames()
+
+	for _, threads := range []int{1, 2, 4, 8} {
+		b.Run(
+			fmt.Sprintf("%d threads", threads),
+			func(innerB *testing.B) {
+				for i := 0; i < innerB.N; i++ {
+					ProcessFrames(frames, overlay, threads)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyProgress(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(opts *Options)
+	}{
+		{name: "Blend", modify: func(opts *Options) {}},
+		{name: "Huerotate", modify: func(opts *Options) { opts.Mode = "huerotate" }},
+		{name: "Spatial", modify: func(opts *Options) { opts.Spatial = "horizontal" }},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				var calls []int
+				opts := DefaultOptions()
+				opts.Threads = 3
+				opts.LoopCount = 2
+				opts.Progress = func(done int, total int) {
+					if total != 10 {
+						innerT.Errorf("Expected %v but got %v", 10, total)
+					}
+					calls = append(calls, done)
+				}
+				c.modify(&opts)
+
+				if _, err := Rainbowify(newTestGIF(5, 2, 2), opts); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				expected := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+				if !reflect.DeepEqual(calls, expected) {
+					innerT.Errorf("Expected %v but got %v", expected, calls)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyOverlay(t *testing.T) {
+	gray := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 255}
+
+	cases := []struct {
+		name     string
+		modify   func(opts *Options)
+		expected int
+	}{
+		{name: "Every frame", modify: func(opts *Options) {}, expected: 4},
+		{name: "Looped", modify: func(opts *Options) { opts.LoopCount = 2 }, expected: 8},
+		{name: "Every", modify: func(opts *Options) { opts.Every = 2 }, expected: 2},
+		{name: "Interpolate frames", modify: func(opts *Options) { opts.InterpolateFrames = 1 }, expected: 8},
+		{name: "Sweep seconds", modify: func(opts *Options) { opts.SweepSeconds = 0.5 }, expected: 4},
+		{name: "Still", modify: func(opts *Options) { opts.Still = true }, expected: 1},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				var overlays []colorful.Color
+				opts := DefaultOptions()
+				opts.Overlay = func(colors []colorful.Color) { overlays = colors }
+				c.modify(&opts)
+
+				out, err := Rainbowify(newSolidClip(4, 2, gray), opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if len(overlays) != c.expected || len(out.Image) != c.expected {
+					innerT.Fatalf("Expected %v but got %v colors and %v frames", c.expected, len(overlays), len(out.Image))
+				}
+
+				// every output frame is blended with its own color
+				for i, overlay := range overlays {
+					expected := color.RGBAModel.Convert(blendPixel(gray, overlay, blendColor, 1))
+					if actual := color.RGBAModel.Convert(out.Image[i].Palette[0]); actual != expected {
+						innerT.Errorf("Frame %d - expected %v but got %v", i, expected, actual)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyFrame(t *testing.T) {
+	src := newTestGIF(4, 4, 4)
+
+	cases := []struct {
+		name   string
+		modify func(opts *Options)
+		index  int
+	}{
+		{name: "First", modify: func(opts *Options) {}, index: 0},
+		{name: "Looped", modify: func(opts *Options) { opts.LoopCount = 3 }, index: 7},
+		{name: "Interpolated", modify: func(opts *Options) { opts.InterpolateFrames = 2 }, index: 5},
+		{name: "Every", modify: func(opts *Options) { opts.Every = 2; opts.LoopCount = 2 }, index: 3},
+		{name: "Huerotate", modify: func(opts *Options) { opts.Mode = "huerotate"; opts.LoopCount = 2 }, index: 6},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				opts := DefaultOptions()
+				c.modify(&opts)
+
+				full, err := Rainbowify(src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				var count int
+				var progress [][2]int
+				opts.Progress = func(done int, total int) { progress = append(progress, [2]int{done, total}) }
+				out, err := RainbowifyFrame(context.Background(), src, opts, func(frameCount int) int {
+					count = frameCount
+					return c.index
+				})
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if count != len(full.Image) {
+					innerT.Errorf("Expected %v but got %v", len(full.Image), count)
+				}
+
+				if len(out.Image) != 1 {
+					innerT.Fatalf("Expected %v but got %v", 1, len(out.Image))
+				}
+
+				if !reflect.DeepEqual(framePixels(out.Image[0]), framePixels(full.Image[c.index])) {
+					innerT.Errorf("Expected the same pixels as frame %d of the full output", c.index)
+				}
+
+				if out.Delay[0] != full.Delay[c.index] {
+					innerT.Errorf("Expected %v but got %v", full.Delay[c.index], out.Delay[0])
+				}
+
+				if !reflect.DeepEqual(progress, [][2]int{{1, 1}}) {
+					innerT.Errorf("Expected %v but got %v", [][2]int{{1, 1}}, progress)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Other frames aren't blended",
+		func(innerT *testing.T) {
+			var overlays []colorful.Color
+			var blended []colorful.Color
+			var mutex sync.Mutex
+
+			opts := DefaultOptions()
+			opts.LoopCount = 3
+			opts.Overlay = func(colors []colorful.Color) { overlays = colors }
+			opts.BlendFunc = func(overlay colorful.Color, base colorful.Color) colorful.Color {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				blended = append(blended, overlay)
+				return overlay
+			}
+
+			if _, err := RainbowifyFrame(context.Background(), src, opts, func(frameCount int) int { return 5 }); err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(blended) == 0 {
+				innerT.Fatalf("Expected the picked frame to be blended")
+			}
+
+			for _, overlay := range blended {
+				if overlay != overlays[5] {
+					innerT.Fatalf("Expected only %v to be blended but got %v", overlays[5], overlay)
+				}
+			}
+		},
+	)
+
+	invalid := []struct {
+		name string
+		pick func(frameCount int) int
+	}{
+		{name: "No pick", pick: nil},
+		{name: "Past the end", pick: func(frameCount int) int { return frameCount }},
+		{name: "Negative", pick: func(frameCount int) int { return -1 }},
+	}
+
+	for _, c := range invalid {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				if _, err := RainbowifyFrame(context.Background(), src, DefaultOptions(), c.pick); err == nil {
+					innerT.Errorf("Expected an error")
+				}
+			},
+		)
+	}
+}
+
+func TestPaletteCache(t *testing.T) {
+	t.Run(
+		"Looped frames match uncached blends",
+		func(innerT *testing.T) {
+			frames, overlayColors, opacities := benchmarkFrames(false)
+			frames = frames[:10]
+			overlayColors = overlayColors[:10]
+			for i := 0; i < 2; i++ {
+				overlayColors = append(overlayColors, overlayColors[:10]...)
+			}
+			opacities = opacities[:30]
+
+			processed, err := processFrames(context.Background(), frames, overlayColors, opacities, blendColor, false, allChannels, nil, 3)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for i, frame := range processed {
+				src := frames[i%len(frames)]
+				expected := image.NewPaletted(src.Bounds(), make(color.Palette, len(src.Palette)))
+				prepareFrame(src, expected, overlayColors[i], blendColor, opacities[i], false, allChannels)
+
+				for j := range expected.Palette {
+					if frame.Palette[j] != expected.Palette[j] {
+						innerT.Fatalf("Frame %d palette %d - expected %v but got %v", i, j, expected.Palette[j], frame.Palette[j])
+					}
+				}
+			}
+
+			// repeats reuse the cached palette instead of getting a copy
+			for i := len(frames); i < len(processed); i++ {
+				if &processed[i].Palette[0] != &processed[i%len(frames)].Palette[0] {
+					innerT.Errorf("Frame %d - expected the palette of frame %d", i, i%len(frames))
+				}
+			}
+		},
+	)
+}
+
+func TestOutputPixels(t *testing.T) {
+	src := newTestGIF(2, 3, 3)
+	original := make([]uint8, len(src.Image[0].Pix))
+	copy(original, src.Image[0].Pix)
+
+	opts := DefaultOptions()
+	opts.LoopCount = 2
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Looped frames don't share pixels",
+		func(innerT *testing.T) {
+			out.Image[0].Pix[0] = 3
+			out.Image[0].Pix[1] = 3
+
+			if out.Image[2].Pix[0] != original[0] || out.Image[2].Pix[1] != original[1] {
+				innerT.Errorf("Expected %v but got %v", original[:2], out.Image[2].Pix[:2])
+			}
+
+			if src.Image[0].Pix[0] != original[0] || src.Image[0].Pix[1] != original[1] {
+				innerT.Errorf("Expected %v but got %v", original[:2], src.Image[0].Pix[:2])
+			}
+		},
+	)
+
+	t.Run(
+		"Sub images are copied by row",
+		func(innerT *testing.T) {
+			big := newTestGIF(1, 4, 4).Image[0]
+			sub := big.SubImage(image.Rect(1, 1, 3, 3)).(*image.Paletted)
+			dst := image.NewPaletted(sub.Bounds(), sub.Palette)
+
+			copyPixels(dst, sub)
+
+			for y := 1; y < 3; y++ {
+				for x := 1; x < 3; x++ {
+					if dst.ColorIndexAt(x, y) != sub.ColorIndexAt(x, y) {
+						innerT.Errorf("(%d, %d) - expected %v but got %v", x, y, sub.ColorIndexAt(x, y), dst.ColorIndexAt(x, y))
+					}
+				}
+			}
+		},
+	)
+}
@@ -0,0 +1,303 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* maps every frame onto one palette of at most n colors picked with a median cut over all frames
+ * the colors are weighted by how many pixels use them, fully transparent colors share a single entry
+ * quality from 1 to 10 is how much effort goes into refining the median cut, see refinePalette
+ * dither spreads the rounding error to neighbouring pixels and can be nil
+ * every frame gets its own copy of the palette
+ */
+func quantizeFrames(frames []*image.Paletted, n int, quality int, dither ditherFunc) []*image.Paletted {
+	palette := reducedPalette(frames, n, quality)
+
+	reduced := make([]*image.Paletted, len(frames))
+	for i, frame := range frames {
+		framePalette := make(color.Palette, len(palette))
+		copy(framePalette, palette)
+
+		reduced[i] = remapFrame(frame, framePalette, dither)
+	}
+
+	return reduced
+}
+
+/* like quantizeFrames but every frame uses the very same palette, which is returned as well
+ * GIF encoders write a palette shared like that once instead of once per frame
+ */
+func globalPaletteFrames(frames []*image.Paletted, n int, quality int, dither ditherFunc) ([]*image.Paletted, color.Palette) {
+	palette := reducedPalette(frames, n, quality)
+
+	reduced := make([]*image.Paletted, len(frames))
+	for i, frame := range frames {
+		reduced[i] = remapFrame(frame, palette, dither)
+	}
+
+	return reduced, palette
+}
+
+// the combined palette of all frames cut down to at most n colors
+func reducedPalette(frames []*image.Paletted, n int, quality int) color.Palette {
+	counts := make(map[color.NRGBA]int)
+	transparent := false
+
+	for _, frame := range frames {
+		usage := make([]int, len(frame.Palette))
+		bounds := frame.Bounds()
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			offset := frame.PixOffset(bounds.Min.X, y)
+			for _, index := range frame.Pix[offset : offset+bounds.Dx()] {
+				if int(index) < len(usage) {
+					usage[index]++
+				}
+			}
+		}
+
+		for i, c := range frame.Palette {
+			if usage[i] == 0 {
+				continue
+			}
+
+			converted := color.NRGBAModel.Convert(c).(color.NRGBA)
+			if converted.A == 0 {
+				transparent = true
+				continue
+			}
+
+			counts[converted] += usage[i]
+		}
+	}
+
+	opaque := n
+	if transparent {
+		opaque--
+	}
+
+	bucket := make([]weightedColor, 0, len(counts))
+	for c, count := range counts {
+		bucket = append(bucket, weightedColor{color: c, count: count})
+	}
+
+	palette := make(color.Palette, 0, n)
+	if len(bucket) > 0 {
+		boxes := medianCutBoxes(bucket, opaque)
+		centers := make([]color.NRGBA, len(boxes))
+		for i, box := range boxes {
+			centers[i] = averageNRGBA(box)
+		}
+
+		// quality 1 is the plain median cut
+		for _, c := range refinePalette(bucket, centers, quality-1) {
+			palette = append(palette, c)
+		}
+	}
+
+	if transparent {
+		palette = append(palette, color.NRGBA{})
+	}
+
+	return palette
+}
+
+// the pixel weighted average of a box, keeping the average alpha as well
+func averageNRGBA(box []weightedColor) color.NRGBA {
+	var alpha float64
+	var total int
+	for _, c := range box {
+		alpha += float64(c.color.A) * float64(c.count)
+		total += c.count
+	}
+
+	r, g, b := averageColor(box).Clamped().RGB255()
+	return color.NRGBA{R: r, G: g, B: b, A: uint8(alpha/float64(total) + 0.5)}
+}
+
+/* moves every palette color to the weighted average of the colors closest to it, up to iterations times (k-means)
+ * no pass can make the colors further from their closest palette color, so more iterations never make it worse
+ * stops early once no color switches to a different palette color
+ */
+func refinePalette(bucket []weightedColor, palette []color.NRGBA, iterations int) []color.NRGBA {
+	if iterations < 1 || len(palette) < 2 {
+		return palette
+	}
+
+	centers := make([][4]float64, len(palette))
+	for i, c := range palette {
+		centers[i] = [4]float64{float64(c.R), float64(c.G), float64(c.B), float64(c.A)}
+	}
+
+	closest := make([]int, len(bucket))
+	for i := range closest {
+		closest[i] = -1
+	}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		changed := false
+		for i, c := range bucket {
+			nearest := nearestCenter(centers, c.color)
+			if nearest != closest[i] {
+				closest[i] = nearest
+				changed = true
+			}
+		}
+
+		if !changed {
+			break
+		}
+
+		sums := make([][4]float64, len(centers))
+		totals := make([]float64, len(centers))
+		for i, c := range bucket {
+			weight := float64(c.count)
+			sums[closest[i]][0] += float64(c.color.R) * weight
+			sums[closest[i]][1] += float64(c.color.G) * weight
+			sums[closest[i]][2] += float64(c.color.B) * weight
+			sums[closest[i]][3] += float64(c.color.A) * weight
+			totals[closest[i]] += weight
+		}
+
+		// a palette color nothing is closest to stays where it is
+		for i := range centers {
+			if totals[i] == 0 {
+				continue
+			}
+
+			for channel := range centers[i] {
+				centers[i][channel] = sums[i][channel] / totals[i]
+			}
+		}
+	}
+
+	refined := make([]color.NRGBA, len(centers))
+	for i, c := range centers {
+		refined[i] = color.NRGBA{
+			R: uint8(c[0] + 0.5),
+			G: uint8(c[1] + 0.5),
+			B: uint8(c[2] + 0.5),
+			A: uint8(c[3] + 0.5),
+		}
+	}
+
+	return refined
+}
+
+// the index of the center closest to c by squared distance over all four channels
+func nearestCenter(centers [][4]float64, c color.NRGBA) int {
+	nearest := 0
+	nearestDistance := -1.0
+
+	for i, center := range centers {
+		dr := center[0] - float64(c.R)
+		dg := center[1] - float64(c.G)
+		db := center[2] - float64(c.B)
+		da := center[3] - float64(c.A)
+
+		distance := dr*dr + dg*dg + db*db + da*da
+		if nearestDistance < 0 || distance < nearestDistance {
+			nearest = i
+			nearestDistance = distance
+		}
+	}
+
+	return nearest
+}
+
+/* cuts every palette down to the 256 colors a GIF can hold, returning how many frames needed it
+ * pixels are a single byte, so no pixel can use the colors past the first 256 and dropping them changes nothing
+ */
+func limitPalettes(frames []*image.Paletted) int {
+	var limited int
+	for _, frame := range frames {
+		if len(frame.Palette) > 256 {
+			frame.Palette = frame.Palette[:256]
+			limited++
+		}
+	}
+
+	return limited
+}
+
+// redraws frame using palette, picking the closest color for every pixel unless dither is given
+func remapFrame(frame *image.Paletted, palette color.Palette, dither ditherFunc) *image.Paletted {
+	bounds := frame.Bounds()
+	remapped := image.NewPaletted(bounds, palette)
+
+	if dither != nil {
+		dither(remapped, frame)
+	} else {
+		draw.Draw(remapped, bounds, frame, bounds.Min, draw.Src)
+	}
+
+	return remapped
+}
+
+/* like globalPaletteFrames but the shared palette is the first frame's own, the others are mapped onto it color by color
+ * colors it doesn't have go to the closest one it does, which keeps similar frames from flickering between near identical palettes
+ * a transparent color is added when another frame has one and there's room, so partial frames don't turn opaque
+ */
+func firstPaletteFrames(frames []*image.Paletted) ([]*image.Paletted, color.Palette) {
+	palette := frames[0].Palette
+	if !hasTransparent(palette) && len(palette) < 256 {
+		for _, frame := range frames[1:] {
+			if hasTransparent(frame.Palette) {
+				// a copy, frames share palettes and the first one's has to stay as it is
+				palette = append(palette[:len(palette):len(palette)], color.RGBA{})
+				break
+			}
+		}
+	}
+
+	// frames sharing a palette share the mapping as well, keyed like the palette cache so a shorter slice of one gets its own
+	mappings := make(map[paletteKey][]uint8)
+
+	mapped := make([]*image.Paletted, len(frames))
+	for i, frame := range frames {
+		var mapping []uint8
+		if len(frame.Palette) > 0 {
+			var ok bool
+			key := newPaletteKey(frame.Palette, colorful.Color{}, 0)
+			mapping, ok = mappings[key]
+			if !ok {
+				mapping = make([]uint8, len(frame.Palette))
+				for j, c := range frame.Palette {
+					mapping[j] = uint8(palette.Index(c))
+				}
+				mappings[key] = mapping
+			}
+		}
+
+		pix := make([]uint8, len(frame.Pix))
+		for j, index := range frame.Pix {
+			// indices past the palette are left alone, the encoder rejects them either way
+			if int(index) < len(mapping) {
+				index = mapping[index]
+			}
+			pix[j] = index
+		}
+
+		mapped[i] = &image.Paletted{
+			Pix:     pix,
+			Stride:  frame.Stride,
+			Rect:    frame.Rect,
+			Palette: palette,
+		}
+	}
+
+	return mapped, palette
+}
+
+func hasTransparent(palette color.Palette) bool {
+	for _, c := range palette {
+		if _, _, _, alpha := c.RGBA(); alpha == 0 {
+			return true
+		}
+	}
+
+	return false
+}
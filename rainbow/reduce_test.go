@@ -0,0 +1,375 @@
+package rainbow
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// a horizontal ramp of grays, one per column
+func newRampFrame(width int) *image.Paletted {
+	palette := make(color.Palette, width)
+	for i := range palette {
+		gray := uint8(i * 255 / (width - 1))
+		palette[i] = color.RGBA{R: gray, G: gray, B: gray, A: 255}
+	}
+
+	frame := image.NewPaletted(image.Rect(0, 0, width, 4), palette)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < width; x++ {
+			frame.SetColorIndex(x, y, uint8(x))
+		}
+	}
+
+	return frame
+}
+
+func TestQuantizeFrames(t *testing.T) {
+	frames := []*image.Paletted{newRampFrame(64), newTestGIF(1, 64, 4).Image[0]}
+
+	t.Run(
+		"Palettes are at most n colors",
+		func(innerT *testing.T) {
+			for _, n := range []int{2, 5, 16} {
+				for i, frame := range quantizeFrames(frames, n, 1, nil) {
+					if len(frame.Palette) > n {
+						innerT.Errorf("Frame %d - expected at most %v but got %v", i, n, len(frame.Palette))
+					}
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Transparency is kept",
+		func(innerT *testing.T) {
+			reduced := quantizeFrames(frames, 4, 1, nil)[1]
+			_, _, _, alpha := reduced.At(3, 0).RGBA()
+			if alpha != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, alpha)
+			}
+		},
+	)
+
+	t.Run(
+		"Dithering changes the index distribution",
+		func(innerT *testing.T) {
+			plain := quantizeFrames(frames[:1], 4, 1, nil)[0]
+
+			for _, name := range []string{"floyd-steinberg", "ordered"} {
+				dither, err := getDitherFunc(name)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				dithered := quantizeFrames(frames[:1], 4, 1, dither)[0]
+				if reflect.DeepEqual(indexCounts(plain), indexCounts(dithered)) {
+					innerT.Errorf("%s - expected the index counts to differ from %v", name, indexCounts(plain))
+				}
+			}
+		},
+	)
+}
+
+func TestRainbowifyGlobalPalette(t *testing.T) {
+	src := newTestGIF(4, 4, 4)
+
+	opts := DefaultOptions()
+	opts.GlobalPalette = true
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Frames share the palette",
+		func(innerT *testing.T) {
+			palette, ok := out.Config.ColorModel.(color.Palette)
+			if !ok {
+				innerT.Fatalf("Expected a color.Palette but got %v", out.Config.ColorModel)
+			}
+
+			for i, frame := range out.Image {
+				if len(frame.Palette) != len(palette) || &frame.Palette[0] != &palette[0] {
+					innerT.Errorf("Frame %d - expected the global palette", i)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Encodes",
+		func(innerT *testing.T) {
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, out); err != nil {
+				innerT.Fatalf("Error encoding: %v", err)
+			}
+
+			decoded, err := gif.DecodeAll(&buf)
+			if err != nil {
+				innerT.Fatalf("Error decoding: %v", err)
+			}
+
+			if decoded.Config.ColorModel == nil {
+				innerT.Errorf("Expected a global color table but got %v", decoded.Config.ColorModel)
+			}
+		},
+	)
+}
+
+// the average squared distance between every pixel of src and the same pixel of reduced
+func meanColorError(src *image.Paletted, reduced *image.Paletted) float64 {
+	var total float64
+	bounds := src.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			expected := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			actual := color.NRGBAModel.Convert(reduced.At(x, y)).(color.NRGBA)
+
+			dr := float64(expected.R) - float64(actual.R)
+			dg := float64(expected.G) - float64(actual.G)
+			db := float64(expected.B) - float64(actual.B)
+			total += dr*dr + dg*dg + db*db
+		}
+	}
+
+	return total / float64(bounds.Dx()*bounds.Dy())
+}
+
+func TestQuantizeFramesQuality(t *testing.T) {
+	// a hue sweep with the left half brighter so the colors aren't spread evenly
+	palette := make(color.Palette, 200)
+	for i := range palette {
+		lightness := 0.3
+		if i < 100 {
+			lightness = 0.7
+		}
+		r, g, b := colorful.Hsl(float64(i)*360/200, 0.8, lightness).RGB255()
+		palette[i] = color.RGBA{R: r, G: g, B: b, A: 255}
+	}
+	frame := image.NewPaletted(image.Rect(0, 0, 200, 1), palette)
+	for x := range frame.Pix {
+		frame.Pix[x] = uint8(x)
+	}
+
+	previous := -1.0
+	for _, quality := range []int{1, 5, 10} {
+		reduced := quantizeFrames([]*image.Paletted{frame}, 8, quality, nil)[0]
+		actual := meanColorError(frame, reduced)
+
+		if previous >= 0 && actual > previous {
+			t.Errorf("Quality %d - expected at most %v but got %v", quality, previous, actual)
+		}
+		previous = actual
+	}
+}
+
+// how many pixels use each palette index
+func indexCounts(frame *image.Paletted) map[uint8]int {
+	counts := make(map[uint8]int)
+	for _, index := range frame.Pix {
+		counts[index]++
+	}
+
+	return counts
+}
+
+func TestGetDitherFunc(t *testing.T) {
+	if _, err := getDitherFunc("sideways"); err == nil {
+		t.Errorf("Expected an error but got none")
+	}
+
+	dither, err := getDitherFunc("none")
+	if err != nil || dither != nil {
+		t.Errorf("Expected %v but got %v", nil, err)
+	}
+}
+
+func TestRainbowifyLimitsPalettes(t *testing.T) {
+	// more colors than a GIF can hold, only the first 256 are reachable from the pixels
+	palette := make(color.Palette, 300)
+	for i := range palette {
+		palette[i] = color.RGBA{R: uint8(i), G: uint8(i / 2), B: 128, A: 255}
+	}
+	frame := image.NewPaletted(image.Rect(0, 0, 16, 16), palette)
+	for i := range frame.Pix {
+		frame.Pix[i] = uint8(i)
+	}
+	src := &gif.GIF{
+		Image:    []*image.Paletted{frame},
+		Delay:    []int{10},
+		Disposal: []byte{0},
+		Config:   image.Config{Width: 16, Height: 16},
+	}
+
+	var warnings []string
+	opts := DefaultOptions()
+	opts.Blend = "normal"
+	opts.Opacity = 0
+	opts.Warn = func(message string) {
+		warnings = append(warnings, message)
+	}
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Palettes fit in a GIF",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				if len(frame.Palette) > 256 {
+					innerT.Errorf("Frame %d - expected at most %v but got %v", i, 256, len(frame.Palette))
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Pixels are unchanged",
+		func(innerT *testing.T) {
+			for _, point := range []image.Point{{0, 0}, {15, 15}} {
+				expected := frame.At(point.X, point.Y)
+				actual := out.Image[0].At(point.X, point.Y)
+				if !reflect.DeepEqual(color.RGBAModel.Convert(expected), color.RGBAModel.Convert(actual)) {
+					innerT.Errorf("%v - expected %v but got %v", point, expected, actual)
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Warns",
+		func(innerT *testing.T) {
+			if len(warnings) != 1 || !strings.Contains(warnings[0], "256") {
+				innerT.Errorf("Expected a warning about 256 colors but got %v", warnings)
+			}
+		},
+	)
+
+	t.Run(
+		"Encodes",
+		func(innerT *testing.T) {
+			if err := gif.EncodeAll(&bytes.Buffer{}, out); err != nil {
+				innerT.Errorf("Error encoding: %v", err)
+			}
+		},
+	)
+}
+
+func TestRainbowifyPaletteFromFirst(t *testing.T) {
+	src := newTestGIF(4, 4, 4)
+
+	opts := DefaultOptions()
+	opts.PaletteFromFirst = true
+
+	plain, err := Rainbowify(src, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Frames share the first palette",
+		func(innerT *testing.T) {
+			first := out.Image[0].Palette
+			if !reflect.DeepEqual(first, plain.Image[0].Palette) {
+				innerT.Errorf("Expected %v but got %v", plain.Image[0].Palette, first)
+			}
+
+			for i, frame := range out.Image {
+				if len(frame.Palette) != len(first) || &frame.Palette[0] != &first[0] {
+					innerT.Errorf("Frame %d - expected the first frame's palette", i)
+				}
+			}
+
+			if palette, ok := out.Config.ColorModel.(color.Palette); !ok || &palette[0] != &first[0] {
+				innerT.Errorf("Expected the first frame's palette as the global one but got %v", out.Config.ColorModel)
+			}
+		},
+	)
+
+	t.Run(
+		"Pixels get the closest color",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				original := plain.Image[i]
+				for j, index := range original.Pix {
+					expected := frame.Palette[frame.Palette.Index(original.Palette[index])]
+					if actual := frame.Palette[frame.Pix[j]]; actual != expected {
+						innerT.Errorf("Frame %d pixel %d - expected %v but got %v", i, j, expected, actual)
+					}
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Transparency is kept",
+		func(innerT *testing.T) {
+			opaque := newTestGIF(2, 2, 2)
+			// the first frame has no transparent color, the second one uses its own
+			opaque.Image[0].Palette = opaque.Image[0].Palette[:3]
+			for j := range opaque.Image[0].Pix {
+				opaque.Image[0].Pix[j] = uint8(j % 3)
+			}
+
+			out, err := Rainbowify(opaque, opts)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			if len(out.Image[0].Palette) != 4 {
+				innerT.Fatalf("Expected %v but got %v", 4, len(out.Image[0].Palette))
+			}
+
+			second := out.Image[1]
+			for j, index := range opaque.Image[1].Pix {
+				if index != 3 {
+					continue
+				}
+
+				if _, _, _, alpha := second.Palette[second.Pix[j]].RGBA(); alpha != 0 {
+					innerT.Errorf("Pixel %d - expected transparent but got %v", j, second.Palette[second.Pix[j]])
+				}
+			}
+		},
+	)
+}
+
+func TestFirstPaletteFramesShortened(t *testing.T) {
+	palette := color.Palette{
+		color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		color.RGBA{A: 255},
+		color.RGBA{R: 128, G: 128, B: 128, A: 255},
+		color.RGBA{R: 255, A: 255},
+	}
+
+	// the same colors in another order, so every index has to be mapped
+	first := image.NewPaletted(image.Rect(0, 0, 4, 1), color.Palette{palette[3], palette[2], palette[1], palette[0]})
+	// a shorter slice of the last frame's palette, starting at the same entry
+	short := image.NewPaletted(image.Rect(0, 0, 2, 1), palette[:2])
+	short.Pix = []uint8{0, 1}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 1), palette)
+	full.Pix = []uint8{0, 1, 2, 3}
+
+	frames := []*image.Paletted{first, short, full}
+	mapped, _ := firstPaletteFrames(frames)
+	for i, frame := range frames {
+		if !reflect.DeepEqual(framePixels(mapped[i]), framePixels(frame)) {
+			t.Errorf("Frame %d - expected %v but got %v", i, framePixels(frame), framePixels(mapped[i]))
+		}
+	}
+}
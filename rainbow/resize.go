@@ -0,0 +1,136 @@
+package rainbow
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+)
+
+/* the size to resize a canvas of the given size to, a width or height of 0 follows the other one to keep the aspect ratio
+ * both 0 keeps the size as is
+ */
+func resizedSize(size image.Point, width int, height int) image.Point {
+	switch {
+	case width == 0 && height == 0:
+		return size
+	case width == 0:
+		width = int(math.Max(math.Round(float64(height)*float64(size.X)/float64(size.Y)), 1))
+	case height == 0:
+		height = int(math.Max(math.Round(float64(width)*float64(size.Y)/float64(size.X)), 1))
+	}
+
+	return image.Pt(width, height)
+}
+
+// where r of a canvas of size from ends up in a canvas of size to, growing outwards so no pixel is cut off
+func scaleRect(r image.Rectangle, from image.Point, to image.Point) image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+
+	return image.Rect(
+		r.Min.X*to.X/from.X,
+		r.Min.Y*to.Y/from.Y,
+		(r.Max.X*to.X+from.X-1)/from.X,
+		(r.Max.Y*to.Y+from.Y-1)/from.Y,
+	)
+}
+
+/* scales a frame of a canvas of size from to one of size to with bilinear filtering and palettizes it again
+ * samples are taken from within the frame only, so partial frames don't pick up colors from outside of themselves
+ */
+func resizeFrame(src *image.Paletted, canvas image.Rectangle, to image.Point, quantizer string, dither ditherFunc) (*image.Paletted, error) {
+	from := canvas.Size()
+	bounds := src.Bounds().Intersect(canvas)
+	if bounds.Empty() {
+		return image.NewPaletted(image.Rectangle{}, src.Palette), nil
+	}
+
+	resized := image.NewRGBA(scaleRect(bounds.Sub(canvas.Min), from, to))
+
+	// premultiplied so transparent pixels don't bleed their color into their neighbors
+	palette := make([]color.RGBA, len(src.Palette))
+	for i, c := range src.Palette {
+		palette[i] = color.RGBAModel.Convert(c).(color.RGBA)
+	}
+
+	// the canvas pixel that lands on x, y, clamped to the frame
+	sample := func(x int, y int) color.RGBA {
+		x = clampInt(x, bounds.Min.X, bounds.Max.X-1)
+		y = clampInt(y, bounds.Min.Y, bounds.Max.Y-1)
+
+		return palette[src.Pix[src.PixOffset(x, y)]]
+	}
+
+	scaleX := float64(from.X) / float64(to.X)
+	scaleY := float64(from.Y) / float64(to.Y)
+
+	resizedBounds := resized.Bounds()
+	for y := resizedBounds.Min.Y; y < resizedBounds.Max.Y; y++ {
+		// pixel centers line up, so scaling by a whole factor samples between the right pixels
+		srcY := (float64(y)+0.5)*scaleY - 0.5 + float64(canvas.Min.Y)
+		top := int(math.Floor(srcY))
+		fy := srcY - float64(top)
+
+		for x := resizedBounds.Min.X; x < resizedBounds.Max.X; x++ {
+			srcX := (float64(x)+0.5)*scaleX - 0.5 + float64(canvas.Min.X)
+			left := int(math.Floor(srcX))
+			fx := srcX - float64(left)
+
+			resized.SetRGBA(x, y, mixRGBA(
+				mixRGBA(sample(left, top), sample(left+1, top), fx),
+				mixRGBA(sample(left, top+1), sample(left+1, top+1), fx),
+				fy,
+			))
+		}
+	}
+
+	paletted, err := palettize(resized, quantizer)
+	if err != nil || dither == nil {
+		return paletted, err
+	}
+
+	dither(paletted, resized)
+	return paletted, nil
+}
+
+// a and b mixed, t = 0 gives a and t = 1 gives b
+func mixRGBA(a color.RGBA, b color.RGBA, t float64) color.RGBA {
+	mix := func(a uint8, b uint8) uint8 {
+		return uint8(math.Round(float64(a)*(1-t) + float64(b)*t))
+	}
+
+	return color.RGBA{R: mix(a.R, b.R), G: mix(a.G, b.G), B: mix(a.B, b.B), A: mix(a.A, b.A)}
+}
+
+func clampInt(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+
+	if value > max {
+		return max
+	}
+
+	return value
+}
+
+func resizeFrames(ctx context.Context, frames []*image.Paletted, canvas image.Rectangle, to image.Point, quantizer string, dither ditherFunc, threads uint) ([]*image.Paletted, error) {
+	resized := make([]*image.Paletted, len(frames))
+
+	err := forEachFrame(ctx, uint(len(frames)), threads, nil, func(frameIndex uint) error {
+		frame, err := resizeFrame(frames[frameIndex], canvas, to, quantizer, dither)
+		if err != nil {
+			return err
+		}
+
+		resized[frameIndex] = frame
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resized, nil
+}
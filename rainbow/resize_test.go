@@ -0,0 +1,84 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResizedSize(t *testing.T) {
+	cases := []struct {
+		name     string
+		width    int
+		height   int
+		expected image.Point
+	}{
+		{name: "Neither", expected: image.Pt(100, 60)},
+		{name: "Width", width: 50, expected: image.Pt(50, 30)},
+		{name: "Height", height: 120, expected: image.Pt(200, 120)},
+		{name: "Both", width: 10, height: 10, expected: image.Pt(10, 10)},
+		{name: "Never below a pixel", width: 1, expected: image.Pt(1, 1)},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				actual := resizedSize(image.Pt(100, 60), c.width, c.height)
+				if actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyResize(t *testing.T) {
+	src := newTestGIF(3, 100, 60)
+
+	opts := DefaultOptions()
+	opts.Width = 50
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Canvas",
+		func(innerT *testing.T) {
+			if out.Config.Width != 50 || out.Config.Height != 30 {
+				innerT.Errorf("Expected %v but got %vx%v", "50x30", out.Config.Width, out.Config.Height)
+			}
+		},
+	)
+
+	t.Run(
+		"Frames",
+		func(innerT *testing.T) {
+			for i, frame := range out.Image {
+				if frame.Bounds() != image.Rect(0, 0, 50, 30) {
+					innerT.Errorf("Frame %d - expected %v but got %v", i, image.Rect(0, 0, 50, 30), frame.Bounds())
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Flat areas keep their color",
+		func(innerT *testing.T) {
+			palette := color.Palette{color.RGBA{R: 200, G: 40, B: 90, A: 255}}
+			flat := image.NewPaletted(image.Rect(0, 0, 8, 8), palette)
+
+			resized, err := resizeFrame(flat, flat.Bounds(), image.Pt(3, 3), "populosity", nil)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := color.RGBAModel.Convert(palette[0])
+			if actual := color.RGBAModel.Convert(resized.At(1, 1)); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+		},
+	)
+}
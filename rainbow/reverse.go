@@ -0,0 +1,67 @@
+package rainbow
+
+import (
+	"image"
+	"image/gif"
+)
+
+/* plays the frames backwards, along with their delays and disposals
+ * frames that let the ones before them show through would end up over the wrong frames, so such GIFs are coalesced first
+ */
+func reverseFrames(src *gif.GIF, quantizer string) (*gif.GIF, error) {
+	if showsPrevious(src) {
+		var err error
+		src, err = coalesce(src, quantizer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	count := len(src.Image)
+	frames := make([]*image.Paletted, count)
+	var delays []int
+	if len(src.Delay) > 0 {
+		delays = make([]int, count)
+	}
+	var disposals []byte
+	if len(src.Disposal) > 0 {
+		disposals = make([]byte, count)
+	}
+
+	for i, frame := range src.Image {
+		reversedIndex := count - 1 - i
+		frames[reversedIndex] = frame
+		if delays != nil {
+			delays[reversedIndex] = src.Delay[i%len(src.Delay)]
+		}
+		if disposals != nil {
+			disposals[reversedIndex] = src.Disposal[i%len(src.Disposal)]
+		}
+	}
+
+	img := *src
+	img.Image = frames
+	img.Delay = delays
+	img.Disposal = disposals
+
+	return &img, nil
+}
+
+// whether any frame leaves part of the canvas to the frames before it, by not covering it or with transparent colors
+func showsPrevious(src *gif.GIF) bool {
+	canvas := canvasBounds(src)
+
+	for _, frame := range src.Image {
+		if !canvas.In(frame.Bounds()) {
+			return true
+		}
+
+		for _, c := range frame.Palette {
+			if _, _, _, alpha := c.RGBA(); alpha != 0xffff {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"reflect"
+	"testing"
+)
+
+// frames that cover the whole canvas with opaque colors, so nothing shows through
+func newOpaqueGIF(frameCount int) *gif.GIF {
+	img := &gif.GIF{Config: image.Config{Width: 2, Height: 2}}
+	for i := 0; i < frameCount; i++ {
+		palette := color.Palette{color.RGBA{R: uint8(40 * i), G: 10, B: 200, A: 255}}
+		img.Image = append(img.Image, image.NewPaletted(image.Rect(0, 0, 2, 2), palette))
+		img.Delay = append(img.Delay, i+1)
+		img.Disposal = append(img.Disposal, gif.DisposalNone)
+	}
+
+	return img
+}
+
+func TestReverseFrames(t *testing.T) {
+	t.Run(
+		"Order",
+		func(innerT *testing.T) {
+			src := newOpaqueGIF(4)
+
+			img, err := reverseFrames(src, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for i, frame := range img.Image {
+				if frame != src.Image[3-i] {
+					innerT.Errorf("Frame %d - expected source frame %v", i, 3-i)
+				}
+			}
+
+			expected := []int{4, 3, 2, 1}
+			if !reflect.DeepEqual(img.Delay, expected) {
+				innerT.Errorf("Expected %v but got %v", expected, img.Delay)
+			}
+		},
+	)
+
+	t.Run(
+		"Frames that show through are coalesced",
+		func(innerT *testing.T) {
+			src := newOpaqueGIF(2)
+			src.Image[1] = src.Image[1].SubImage(image.Rect(0, 0, 1, 1)).(*image.Paletted)
+
+			img, err := reverseFrames(src, "populosity")
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			// the partial frame was drawn over the first one, reversed it comes first and is complete
+			if img.Image[0].Bounds() != image.Rect(0, 0, 2, 2) {
+				innerT.Errorf("Expected %v but got %v", image.Rect(0, 0, 2, 2), img.Image[0].Bounds())
+			}
+
+			expected := color.RGBAModel.Convert(src.Image[0].Palette[0])
+			if actual := color.RGBAModel.Convert(img.Image[0].At(1, 1)); actual != expected {
+				innerT.Errorf("Expected %v but got %v", expected, actual)
+			}
+		},
+	)
+}
+
+func TestRainbowifyReversePlayback(t *testing.T) {
+	src := newOpaqueGIF(3)
+
+	opts := DefaultOptions()
+	opts.Opacity = 0
+	opts.ReversePlayback = true
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	for i, frame := range out.Image {
+		expected := color.RGBAModel.Convert(src.Image[2-i].Palette[0])
+		if actual := color.RGBAModel.Convert(frame.At(0, 0)); actual != expected {
+			t.Errorf("Frame %d - expected %v but got %v", i, expected, actual)
+		}
+	}
+
+	if !reflect.DeepEqual(out.Delay, []int{3, 2, 1}) {
+		t.Errorf("Expected %v but got %v", []int{3, 2, 1}, out.Delay)
+	}
+}
@@ -0,0 +1,124 @@
+package rainbow
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* maps a point on the canvas to a position on the gradient
+ * x and y are both normalized to [0, 1)
+ */
+type spatialFunc func(x float64, y float64) float64
+
+/* nil means the gradient only varies over time
+ * centerX and centerY are only used by radial, as fractions of the width and height
+ */
+func getSpatialFunc(mode string, centerX float64, centerY float64) (spatialFunc, error) {
+	switch mode {
+	case "", "none":
+		return nil, nil
+	case "horizontal":
+		return spatialHorizontal, nil
+	case "vertical":
+		return spatialVertical, nil
+	case "diagonal":
+		return spatialDiagonal, nil
+	case "radial":
+		return newSpatialRadial(centerX, centerY), nil
+	default:
+		return nil, errors.New("Invalid spatial gradient")
+	}
+}
+
+func spatialHorizontal(x float64, y float64) float64 {
+	return x
+}
+
+func spatialVertical(x float64, y float64) float64 {
+	return y
+}
+
+func spatialDiagonal(x float64, y float64) float64 {
+	return (x + y) / 2
+}
+
+// the distance from the center, reaching 1 at the corner furthest away from it
+func newSpatialRadial(centerX float64, centerY float64) spatialFunc {
+	furthest := math.Hypot(math.Max(centerX, 1-centerX), math.Max(centerY, 1-centerY))
+
+	return func(x float64, y float64) float64 {
+		return math.Hypot(x-centerX, y-centerY) / furthest
+	}
+}
+
+/* blends every pixel with the gradient sampled at its position on the canvas
+ * shift moves the whole pattern along the gradient, which is what animates it across frames
+ * a palette can't hold a color per pixel, so the blended frame gets quantized again
+ * mask scales the opacity per pixel and can be nil, so does the pixel's alpha with respectAlpha
+ * dither redraws the blended frame with the quantizer's palette to hide the banding, nil keeps the quantizer's mapping
+ */
+func prepareFrameSpatial(src *image.Paletted, canvas image.Rectangle, gradient Gradient, shift float64, spatial spatialFunc, mask image.Image, blend blendFunc, opacity float64, respectAlpha bool, channels channelMask, quantizer string, dither ditherFunc) (*image.Paletted, error) {
+	bounds := src.Bounds()
+	blended := image.NewRGBA(bounds)
+
+	width := float64(canvas.Dx())
+	height := float64(canvas.Dy())
+
+	// neighbouring pixels usually land on the same position, sampling the gradient is the slow part
+	overlays := make(map[float64]colorful.Color)
+	opacities := make(map[float64]float64)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// sampled at the pixel's center so the pattern tiles seamlessly
+			normalizedX := (float64(x-canvas.Min.X) + 0.5) / width
+			normalizedY := (float64(y-canvas.Min.Y) + 0.5) / height
+			position := wrapPosition(spatial(normalizedX, normalizedY) + shift)
+
+			overlay, okay := overlays[position]
+			if !okay {
+				overlay = gradient.at(position)
+				overlays[position] = overlay
+				opacities[position] = gradient.opacityAt(position) * opacity
+			}
+
+			pixel := src.At(x, y)
+			pixelOpacity := alphaOpacity(pixel, opacities[position]*maskAt(mask, canvas, x, y), respectAlpha)
+			blended.Set(x, y, keepChannels(pixel, blendPixel(pixel, overlay, blend, pixelOpacity), channels))
+		}
+	}
+
+	paletted, err := palettize(blended, quantizer)
+	if err != nil || dither == nil {
+		return paletted, err
+	}
+
+	dither(paletted, blended)
+	return paletted, nil
+}
+
+func processFramesSpatial(ctx context.Context, frames []*image.Paletted, canvas image.Rectangle, gradient Gradient, shifts []float64, spatial spatialFunc, mask image.Image, blend blendFunc, opacities []float64, respectAlpha bool, channels channelMask, quantizer string, dither ditherFunc, progress func(done int, total int), threads uint) ([]*image.Paletted, error) {
+	frameCount := uint(len(shifts))
+	newFrames := make([]*image.Paletted, frameCount)
+
+	err := forEachFrame(ctx, frameCount, threads, progress, func(frameIndex uint) error {
+		src := frames[frameIndex%uint(len(frames))]
+
+		frame, err := prepareFrameSpatial(src, canvas, gradient, shifts[frameIndex], spatial, mask, blend, opacities[frameIndex], respectAlpha, channels, quantizer, dither)
+		if err != nil {
+			return err
+		}
+
+		newFrames[frameIndex] = frame
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newFrames, nil
+}
@@ -0,0 +1,173 @@
+package rainbow
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+func TestPrepareFrameSpatial(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gradient := newGradient(colors, true)
+
+	src := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.RGBA{R: 128, G: 128, B: 128, A: 255}})
+
+	cases := []struct {
+		name    string
+		spatial string
+		differs bool
+	}{
+		{name: "Horizontal", spatial: "horizontal", differs: true},
+		{name: "Vertical", spatial: "vertical", differs: false},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				spatial, err := getSpatialFunc(c.spatial, 0.5, 0.5)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				frame, err := prepareFrameSpatial(src, src.Bounds(), gradient, 0, spatial, nil, blendColor, 1, false, allChannels, "populosity", nil)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				left := frame.At(0, 4)
+				right := frame.At(7, 4)
+				if (left != right) != c.differs {
+					innerT.Errorf("Expected left %v and right %v to differ: %v", left, right, c.differs)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifySpatial(t *testing.T) {
+	src := newTestGIF(3, 4, 4)
+
+	opts := DefaultOptions()
+	opts.Spatial = "horizontal"
+	opts.Threads = 2
+
+	out, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	t.Run(
+		"Frames keep their bounds",
+		func(innerT *testing.T) {
+			if len(out.Image) != 3 {
+				innerT.Fatalf("Expected %v but got %v", 3, len(out.Image))
+			}
+
+			for _, frame := range out.Image {
+				if frame.Bounds() != src.Image[0].Bounds() {
+					innerT.Errorf("Expected %v but got %v", src.Image[0].Bounds(), frame.Bounds())
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Invalid mode",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Spatial = "spiral"
+
+			if _, err := Rainbowify(src, opts); err == nil {
+				innerT.Errorf("Expected an error but got %v", err)
+			}
+		},
+	)
+
+	t.Run(
+		"Cancelled",
+		func(innerT *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			if _, err := RainbowifyContext(ctx, &gif.GIF{Image: src.Image}, opts); err != context.Canceled {
+				innerT.Errorf("Expected %v but got %v", context.Canceled, err)
+			}
+		},
+	)
+}
+
+func TestSpatialRadial(t *testing.T) {
+	colors, _, err := ParseGradientColors("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gradient := newGradient(colors, true)
+
+	src := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.RGBA{R: 128, G: 128, B: 128, A: 255}})
+
+	cases := []struct {
+		name    string
+		centerX float64
+		centerY float64
+		// pairs of pixels the same distance away from the center
+		pairs [][2]image.Point
+	}{
+		{
+			name:    "Centered",
+			centerX: 0.5,
+			centerY: 0.5,
+			pairs: [][2]image.Point{
+				{{X: 0, Y: 0}, {X: 7, Y: 7}},
+				{{X: 1, Y: 3}, {X: 3, Y: 1}},
+				{{X: 6, Y: 4}, {X: 3, Y: 1}},
+			},
+		},
+		{
+			name:    "Off center",
+			centerX: 0.25,
+			centerY: 0.5,
+			pairs: [][2]image.Point{
+				{{X: 0, Y: 4}, {X: 3, Y: 4}},
+				{{X: 1, Y: 2}, {X: 2, Y: 5}},
+				{{X: 0, Y: 3}, {X: 1, Y: 2}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				spatial, err := getSpatialFunc("radial", c.centerX, c.centerY)
+				if err != nil {
+					innerT.Fatal(err)
+				}
+
+				frame, err := prepareFrameSpatial(src, src.Bounds(), gradient, 0.25, spatial, nil, blendColor, 1, false, allChannels, "populosity", nil)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				for _, pair := range c.pairs {
+					expected := frame.At(pair[0].X, pair[0].Y)
+					actual := frame.At(pair[1].X, pair[1].Y)
+					if expected != actual {
+						innerT.Errorf("%v and %v - expected %v but got %v", pair[0], pair[1], expected, actual)
+					}
+				}
+
+				center := frame.At(int(c.centerX*8), int(c.centerY*8))
+				corner := frame.At(7, 0)
+				if center == corner {
+					innerT.Errorf("Expected the center and corner to differ but got %v", center)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,117 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+)
+
+/* converts a still image into a single frame GIF
+ * paletted images (e.g. 8 bit PNGs) are used as is, everything else gets quantized
+ */
+func staticImageTransform(img image.Image, format string, quantizer string, delay uint) (*gif.GIF, error) {
+	pi, err := palettize(img, quantizer)
+	if err != nil {
+		return nil, err
+	}
+
+	gifImg := gif.GIF{
+		Image:     []*image.Paletted{pi},
+		Delay:     []int{int(delay)},
+		LoopCount: 0,
+	}
+
+	return &gifImg, nil
+}
+
+// reduces any image to at most 256 colors using the given quantizer
+func palettize(img image.Image, quantizer string) (*image.Paletted, error) {
+	if paletted, ok := img.(*image.Paletted); ok && len(paletted.Palette) <= 256 {
+		return paletted, nil
+	}
+
+	transform := img.ColorModel() != color.RGBAModel
+
+	bounds := img.Bounds()
+
+	colors := make([]color.RGBA, (bounds.Max.Y-bounds.Min.Y)*(bounds.Max.X-bounds.Min.X))
+	stride := bounds.Max.X - bounds.Min.X
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.At(x, y)
+			offset := (y-bounds.Min.Y)*stride + (x - bounds.Min.X)
+			if transform {
+				colors[offset] = color.RGBAModel.Convert(c).(color.RGBA)
+			} else {
+				colors[offset] = c.(color.RGBA)
+			}
+		}
+	}
+
+	q := newQuantizer(256)
+	newColorsPtr, indexMap, err := q.quantize(quantizer, colors)
+	if err != nil {
+		return nil, err
+	}
+
+	pix := make([]uint8, len(colors))
+	for i, paletteIndex := range indexMap {
+		pix[i] = uint8(paletteIndex)
+	}
+
+	newColors := make([]color.Color, len(newColorsPtr))
+	for i, c := range newColorsPtr {
+		newColors[i] = *c
+	}
+
+	if isWide(img.ColorModel()) {
+		widenPalette(img, indexMap, newColors)
+	}
+
+	pi := image.NewPaletted(bounds, newColors)
+	pi.Stride = stride
+	pi.Pix = pix
+
+	return pi, nil
+}
+
+// 16 bit PNGs decode into one of these
+func isWide(model color.Model) bool {
+	return model == color.RGBA64Model || model == color.NRGBA64Model || model == color.Gray16Model
+}
+
+/* the quantizers work on 8 bit colors, so for 16 bit images each palette entry is swapped for the average of the pixels mapped to it
+ * blending then starts from the full precision rather than from colors already cut down to 8 bits
+ */
+func widenPalette(img image.Image, indexMap []int, palette []color.Color) {
+	sums := make([][4]uint64, len(palette))
+	counts := make([]uint64, len(palette))
+
+	bounds := img.Bounds()
+	stride := bounds.Max.X - bounds.Min.X
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			index := indexMap[(y-bounds.Min.Y)*stride+(x-bounds.Min.X)]
+			r, g, b, a := img.At(x, y).RGBA()
+			sums[index][0] += uint64(r)
+			sums[index][1] += uint64(g)
+			sums[index][2] += uint64(b)
+			sums[index][3] += uint64(a)
+			counts[index]++
+		}
+	}
+
+	for i, sum := range sums {
+		count := counts[i]
+		if count == 0 {
+			continue
+		}
+
+		palette[i] = color.RGBA64{
+			R: uint16((sum[0] + count/2) / count),
+			G: uint16((sum[1] + count/2) / count),
+			B: uint16((sum[2] + count/2) / count),
+			A: uint16((sum[3] + count/2) / count),
+		}
+	}
+}
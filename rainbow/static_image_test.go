@@ -0,0 +1,142 @@
+package rainbow
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestStaticImageTransform(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.NRGBA{R: 255, G: 0, B: 0, A: 255})
+	src.Set(1, 0, color.NRGBA{R: 0, G: 255, B: 0, A: 255})
+	src.Set(0, 1, color.NRGBA{R: 0, G: 0, B: 255, A: 255})
+	src.Set(1, 1, color.NRGBA{R: 0, G: 0, B: 0, A: 0})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("Error encoding: %v", err)
+	}
+
+	img, static, err := DecodeImage(&buf, "populosity")
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	if !static {
+		t.Errorf("Expected %v but got %v", true, static)
+	}
+
+	if len(img.Image) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(img.Image))
+	}
+
+	t.Run(
+		"Pixels are preserved",
+		func(innerT *testing.T) {
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 2; x++ {
+					expected := color.RGBAModel.Convert(src.At(x, y))
+					actual := color.RGBAModel.Convert(img.Image[0].At(x, y))
+					if expected != actual {
+						innerT.Errorf("(%d, %d) - expected %v but got %v", x, y, expected, actual)
+					}
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Pixels are blended",
+		func(innerT *testing.T) {
+			overlay := colorful.Color{R: 1, G: 1, B: 0}
+			frames, err := processFrames(context.Background(), img.Image, []colorful.Color{overlay}, []float64{1}, blendOpaque, false, allChannels, nil, 1)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			expected := color.NRGBA{R: 255, G: 255, B: 0, A: 255}
+			for _, point := range []image.Point{{0, 0}, {1, 0}, {0, 1}} {
+				actual := frames[0].At(point.X, point.Y)
+				if actual != expected {
+					innerT.Errorf("%v - expected %v but got %v", point, expected, actual)
+				}
+			}
+
+			_, _, _, alpha := frames[0].At(1, 1).RGBA()
+			if alpha != 0 {
+				innerT.Errorf("Expected %v but got %v", 0, alpha)
+			}
+		},
+	)
+}
+
+func TestStaticImageTransformWide(t *testing.T) {
+	values := []uint16{0x0000, 0x1234, 0x80ff, 0xfedc}
+
+	wide := image.NewGray16(image.Rect(0, 0, 2, 2))
+	narrow := image.NewGray(image.Rect(0, 0, 2, 2))
+	for i, v := range values {
+		wide.SetGray16(i%2, i/2, color.Gray16{Y: v})
+		narrow.SetGray(i%2, i/2, color.Gray{Y: uint8((uint32(v) + 128) / 257)})
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, wide); err != nil {
+		t.Fatalf("Error encoding: %v", err)
+	}
+
+	img, _, err := DecodeImage(&buf, "populosity")
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+
+	t.Run(
+		"Palette keeps 16 bits",
+		func(innerT *testing.T) {
+			for i, v := range values {
+				r, g, b, _ := img.Image[0].At(i%2, i/2).RGBA()
+				if r != uint32(v) || g != uint32(v) || b != uint32(v) {
+					innerT.Errorf("Expected %v but got %v", v, []uint32{r, g, b})
+				}
+			}
+		},
+	)
+
+	t.Run(
+		"Blends like 8 bits",
+		func(innerT *testing.T) {
+			narrowImg, err := staticImageTransform(narrow, "png", "populosity", 0)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			overlay := colorful.Color{R: 1, G: 0.5, B: 0}
+			wideFrames, err := processFrames(context.Background(), img.Image, []colorful.Color{overlay}, []float64{1}, blendColor, false, allChannels, nil, 1)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+			narrowFrames, err := processFrames(context.Background(), narrowImg.Image, []colorful.Color{overlay}, []float64{1}, blendColor, false, allChannels, nil, 1)
+			if err != nil {
+				innerT.Fatalf("Unexpected error %v", err)
+			}
+
+			for i := range values {
+				expected := color.RGBAModel.Convert(narrowFrames[0].At(i%2, i/2)).(color.RGBA)
+				actual := color.RGBAModel.Convert(wideFrames[0].At(i%2, i/2)).(color.RGBA)
+				for _, pair := range [][2]uint8{{expected.R, actual.R}, {expected.G, actual.G}, {expected.B, actual.B}} {
+					if math.Abs(float64(pair[0])-float64(pair[1])) > 1 {
+						innerT.Errorf("Expected %v but got %v", expected, actual)
+						break
+					}
+				}
+			}
+		},
+	)
+}
@@ -0,0 +1,106 @@
+package rainbow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/gif"
+	"io"
+)
+
+// FrameWriter takes the output of RainbowifyStream one frame at a time, GIFWriter encodes them into a GIF
+type FrameWriter interface {
+	// called once before any frame with the size of the output's canvas and how many frames will follow
+	Start(width int, height int, frameCount int) error
+	// called for every frame in order, the frame isn't touched again afterwards so it can be let go of
+	WriteFrame(frame *image.Paletted, delay int, disposal byte) error
+}
+
+/* RainbowifyStream is RainbowifyContext but hands the frames to out as they're done instead of returning a GIF
+ * only a few frames per thread are held at a time, rather than all of them along with src
+ * global palettes, max colors, backgrounds, and dedupe look at every frame at once, so they return an error
+ */
+func RainbowifyStream(ctx context.Context, src *gif.GIF, opts Options, out FrameWriter) error {
+	if out == nil {
+		return errors.New("Streaming needs a frame writer")
+	}
+
+	_, err := rainbowify(ctx, src, opts, out, nil)
+	return err
+}
+
+/* GIFWriter encodes a GIF frame by frame, call Start, WriteFrame for every frame, then Close
+ * every frame gets a color table of its own, the same as EncodeTo writes a GIF without a global palette
+ */
+type GIFWriter struct {
+	// as in gif.GIF, 0 loops forever, -1 plays once, and n repeats n times
+	LoopCount int
+	// written into a Comment Extension like EncodeWithComment does, empty writes none
+	Comment string
+
+	w      io.Writer
+	config image.Config
+	buf    bytes.Buffer
+}
+
+func NewGIFWriter(w io.Writer) *GIFWriter {
+	return &GIFWriter{w: w}
+}
+
+// writes the header and logical screen descriptor, along with the comment and loop count
+func (g *GIFWriter) Start(width int, height int, frameCount int) error {
+	if width < 1 || height < 1 || width >= 1<<16 || height >= 1<<16 {
+		return errors.New("Invalid GIF size")
+	}
+
+	g.config = image.Config{Width: width, Height: height}
+
+	// no global color table, background index, or aspect ratio
+	header := []byte("GIF89a")
+	header = append(header, byte(width), byte(width>>8), byte(height), byte(height>>8), 0, 0, 0)
+
+	if len(g.Comment) != 0 {
+		header = append(header, commentExtension(g.Comment)...)
+	}
+
+	// image/gif only writes the loop count for animations as well
+	if frameCount > 1 && g.LoopCount >= 0 {
+		header = append(header, 0x21, 0xff, 0x0b)
+		header = append(header, "NETSCAPE2.0"...)
+		header = append(header, 0x03, 0x01, byte(g.LoopCount), byte(g.LoopCount>>8), 0x00)
+	}
+
+	_, err := g.w.Write(header)
+	return err
+}
+
+/* image/gif can't write a frame by itself, so the frame is encoded as a GIF of its own and its blocks are copied out
+ * without a global palette or a loop count that GIF is the 13 byte header, the frame's blocks, and the 1 byte trailer
+ */
+func (g *GIFWriter) WriteFrame(frame *image.Paletted, delay int, disposal byte) error {
+	if g.config.Width == 0 {
+		return errors.New("GIF writer wasn't started")
+	}
+
+	g.buf.Reset()
+	err := gif.EncodeAll(&g.buf, &gif.GIF{
+		Image:    []*image.Paletted{frame},
+		Delay:    []int{delay},
+		Disposal: []byte{disposal},
+		Config:   g.config,
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded := g.buf.Bytes()
+	_, err = g.w.Write(encoded[13 : len(encoded)-1])
+	return err
+}
+
+// writes the trailer, w is left open
+func (g *GIFWriter) Close() error {
+	_, err := g.w.Write([]byte{0x3b})
+	return err
+}
@@ -0,0 +1,88 @@
+package rainbow
+
+import (
+	"bytes"
+	"context"
+	"image/gif"
+	"testing"
+)
+
+func TestRainbowifyStream(t *testing.T) {
+	cases := []struct {
+		name   string
+		src    *gif.GIF
+		modify func(opts *Options)
+	}{
+		{name: "Default", src: newTestGIF(3, 4, 4), modify: func(opts *Options) {}},
+		{name: "Threads", src: newTestGIF(3, 4, 4), modify: func(opts *Options) { opts.Threads = 2 }},
+		{name: "Partial frames", src: newOffsetGIF(), modify: func(opts *Options) {}},
+		{name: "Hue rotate", src: newTestGIF(3, 4, 4), modify: func(opts *Options) { opts.Mode = "huerotate" }},
+		{name: "Spatial", src: newTestGIF(3, 4, 4), modify: func(opts *Options) { opts.Spatial = "horizontal" }},
+		{name: "Palette order", src: newTestGIF(3, 4, 4), modify: func(opts *Options) { opts.PaletteOrder = "hue" }},
+		{name: "Delays", src: newTestGIF(3, 4, 4), modify: func(opts *Options) { opts.DelayScale = 0.5 }},
+		{name: "Resize and text", src: newOffsetGIF(), modify: func(opts *Options) {
+			opts.Width = 12
+			opts.Text = "hi"
+		}},
+		{name: "Still", src: newTestGIF(3, 4, 4), modify: func(opts *Options) { opts.Still = true }},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.name,
+			func(innerT *testing.T) {
+				opts := DefaultOptions()
+				// a batch holds 4 frames per thread, so later batches start partway through the source
+				opts.LoopCount = 4
+				c.modify(&opts)
+
+				img, err := Rainbowify(c.src, opts)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+				img.LoopCount = 0
+
+				var expected bytes.Buffer
+				if err := EncodeWithComment(&expected, img, "rainbow"); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				var done, total int
+				opts.Progress = func(d int, t int) {
+					done, total = d, t
+				}
+
+				var streamed bytes.Buffer
+				writer := NewGIFWriter(&streamed)
+				writer.Comment = "rainbow"
+				if err := RainbowifyStream(context.Background(), c.src, opts, writer); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+				if err := writer.Close(); err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if !bytes.Equal(streamed.Bytes(), expected.Bytes()) {
+					innerT.Errorf("Expected the streamed GIF to match the encoded one")
+				}
+
+				if done != len(img.Image) || total != len(img.Image) {
+					innerT.Errorf("Expected %v but got %v of %v", len(img.Image), done, total)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"Options that need every frame",
+		func(innerT *testing.T) {
+			opts := DefaultOptions()
+			opts.Dedupe = true
+
+			err := RainbowifyStream(context.Background(), newTestGIF(3, 4, 4), opts, NewGIFWriter(&bytes.Buffer{}))
+			if err == nil {
+				innerT.Errorf("Expected an error")
+			}
+		},
+	)
+}
@@ -0,0 +1,32 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+/* GradientStrip draws colors as a strip of one column per color from left to right, each as tall as height
+ * given what Options.Overlay is called with, that's the overlay color of every output frame
+ */
+func GradientStrip(colors []colorful.Color, height int) (*image.RGBA, error) {
+	if len(colors) == 0 {
+		return nil, errors.New("Gradient strips need at least 1 color")
+	}
+
+	if height < 1 {
+		return nil, errors.New("Gradient strips need a height of at least 1")
+	}
+
+	strip := image.NewRGBA(image.Rect(0, 0, len(colors), height))
+	for x, c := range colors {
+		r, g, b := c.Clamped().RGB255()
+		for y := 0; y < height; y++ {
+			strip.SetRGBA(x, y, color.RGBA{R: r, G: g, B: b, A: 255})
+		}
+	}
+
+	return strip, nil
+}
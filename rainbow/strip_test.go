@@ -0,0 +1,49 @@
+package rainbow
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestGradientStrip(t *testing.T) {
+	colors := newGradient([]colorful.Color{{R: 1, G: 0, B: 0}, {R: 0, G: 0, B: 1}}, true).generate(7)
+	strip, err := GradientStrip(colors, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	bounds := strip.Bounds()
+	if bounds.Dx() != len(colors) || bounds.Dy() != 3 {
+		t.Fatalf("Expected %v by %v but got %v", len(colors), 3, bounds.Size())
+	}
+
+	for x, c := range colors {
+		r, g, b := c.Clamped().RGB255()
+		expected := color.RGBA{R: r, G: g, B: b, A: 255}
+		for y := 0; y < 3; y++ {
+			if actual := strip.RGBAAt(x, y); actual != expected {
+				t.Errorf("(%d, %d) - expected %v but got %v", x, y, expected, actual)
+			}
+		}
+	}
+
+	t.Run(
+		"No colors",
+		func(innerT *testing.T) {
+			if _, err := GradientStrip(nil, 1); err == nil {
+				innerT.Errorf("Expected an error")
+			}
+		},
+	)
+
+	t.Run(
+		"No height",
+		func(innerT *testing.T) {
+			if _, err := GradientStrip(colors, 0); err == nil {
+				innerT.Errorf("Expected an error")
+			}
+		},
+	)
+}
@@ -0,0 +1,123 @@
+package rainbow
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// the gap in pixels between the text and the edge of the canvas
+const textMargin = 2
+
+// where text of the given size goes on canvas: top, bottom, or center, always centered horizontally
+func textRect(canvas image.Rectangle, size image.Point, position string) (image.Rectangle, error) {
+	x := canvas.Min.X + (canvas.Dx()-size.X)/2
+
+	var y int
+	switch position {
+	case "", "bottom":
+		y = canvas.Max.Y - textMargin - size.Y
+	case "top":
+		y = canvas.Min.Y + textMargin
+	case "center":
+		y = canvas.Min.Y + (canvas.Dy()-size.Y)/2
+	default:
+		return image.Rectangle{}, errors.New("Invalid text position")
+	}
+
+	return image.Rect(x, y, x+size.X, y+size.Y), nil
+}
+
+func textSize(text string) image.Point {
+	return image.Pt(len([]rune(text))*glyphWidth, glyphHeight)
+}
+
+// whether the text pixel at x, y of area is set, characters without a glyph are drawn as ?
+func textPixel(runes []rune, area image.Rectangle, x int, y int) bool {
+	x -= area.Min.X
+	y -= area.Min.Y
+
+	glyph, ok := fixedGlyphs[runes[x/glyphWidth]]
+	if !ok {
+		glyph = fixedGlyphs['?']
+	}
+
+	return glyph[y]&(0x40>>uint(x%glyphWidth)) != 0
+}
+
+/* draws text into area of a copy of frame in c, using c itself when the palette has it or has room for it
+ * a frame that doesn't cover the visible part of area is grown with transparent pixels so the text doesn't depend on the frames before it
+ */
+func drawText(frame *image.Paletted, canvas image.Rectangle, text string, area image.Rectangle, c color.Color) *image.Paletted {
+	palette := make(color.Palette, len(frame.Palette), len(frame.Palette)+2)
+	copy(palette, frame.Palette)
+
+	// adds c to the palette if it's missing and there's room, otherwise falls back to the closest color
+	indexOf := func(c color.Color, exact func(color.Color) bool) int {
+		for i, existing := range palette {
+			if exact(existing) {
+				return i
+			}
+		}
+
+		if len(palette) < 256 {
+			palette = append(palette, c)
+			return len(palette) - 1
+		}
+
+		return palette.Index(c)
+	}
+
+	textIndex := indexOf(c, func(existing color.Color) bool {
+		return color.RGBAModel.Convert(existing) == color.RGBAModel.Convert(c)
+	})
+
+	visible := area.Intersect(canvas)
+	bounds := frame.Bounds()
+	var fill uint8
+	if !visible.In(bounds) {
+		transparentIndex := indexOf(color.RGBA{}, func(existing color.Color) bool {
+			_, _, _, alpha := existing.RGBA()
+			return alpha == 0
+		})
+
+		// the closest color to transparent could be anything when the palette is full, so only grow with a real one
+		if _, _, _, alpha := palette[transparentIndex].RGBA(); alpha == 0 {
+			bounds = bounds.Union(visible)
+			fill = uint8(transparentIndex)
+		}
+	}
+
+	drawn := image.NewPaletted(bounds, palette)
+	for i := range drawn.Pix {
+		drawn.Pix[i] = fill
+	}
+
+	frameBounds := frame.Bounds()
+	for y := frameBounds.Min.Y; y < frameBounds.Max.Y; y++ {
+		start := frame.PixOffset(frameBounds.Min.X, y)
+		copy(drawn.Pix[drawn.PixOffset(frameBounds.Min.X, y):], frame.Pix[start:start+frameBounds.Dx()])
+	}
+
+	runes := []rune(text)
+	clipped := visible.Intersect(bounds)
+	for y := clipped.Min.Y; y < clipped.Max.Y; y++ {
+		for x := clipped.Min.X; x < clipped.Max.X; x++ {
+			if textPixel(runes, area, x, y) {
+				drawn.Pix[drawn.PixOffset(x, y)] = uint8(textIndex)
+			}
+		}
+	}
+
+	return drawn
+}
+
+// draws text onto every frame, see drawText
+func drawTextFrames(frames []*image.Paletted, canvas image.Rectangle, text string, area image.Rectangle, c color.Color) []*image.Paletted {
+	drawn := make([]*image.Paletted, len(frames))
+	for i, frame := range frames {
+		drawn[i] = drawText(frame, canvas, text, area, c)
+	}
+
+	return drawn
+}
@@ -0,0 +1,107 @@
+package rainbow
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+func TestTextRect(t *testing.T) {
+	canvas := image.Rect(0, 0, 100, 50)
+	size := image.Pt(20, 13)
+
+	cases := []struct {
+		position string
+		expected image.Rectangle
+	}{
+		{position: "top", expected: image.Rect(40, 2, 60, 15)},
+		{position: "bottom", expected: image.Rect(40, 35, 60, 48)},
+		{position: "center", expected: image.Rect(40, 18, 60, 31)},
+	}
+
+	for _, c := range cases {
+		t.Run(
+			c.position,
+			func(innerT *testing.T) {
+				actual, err := textRect(canvas, size, c.position)
+				if err != nil {
+					innerT.Fatalf("Unexpected error %v", err)
+				}
+
+				if actual != c.expected {
+					innerT.Errorf("Expected %v but got %v", c.expected, actual)
+				}
+			},
+		)
+	}
+}
+
+func TestRainbowifyText(t *testing.T) {
+	src := newTestGIF(2, 60, 20)
+
+	opts := DefaultOptions()
+	plain, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	opts.Text = "HI"
+	opts.TextPosition = "center"
+	opts.TextColor = colorful.Color{R: 1, G: 1, B: 1}
+	captioned, err := Rainbowify(src, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	area, _ := textRect(image.Rect(0, 0, 60, 20), textSize("HI"), "center")
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	for i := range plain.Image {
+		var changed int
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 60; x++ {
+				expected := color.RGBAModel.Convert(plain.Image[i].At(x, y))
+				actual := color.RGBAModel.Convert(captioned.Image[i].At(x, y))
+
+				if !image.Pt(x, y).In(area) {
+					if actual != expected {
+						t.Errorf("Frame %d - (%d, %d) is outside the text, expected %v but got %v", i, x, y, expected, actual)
+					}
+					continue
+				}
+
+				if actual != expected {
+					changed++
+					if actual != white {
+						t.Errorf("Frame %d - (%d, %d) expected %v but got %v", i, x, y, white, actual)
+					}
+				}
+			}
+		}
+
+		if changed == 0 {
+			t.Errorf("Frame %d - expected the text to change some pixels", i)
+		}
+	}
+}
+
+func TestDrawTextGrowsPartialFrames(t *testing.T) {
+	palette := color.Palette{color.RGBA{R: 255, A: 255}}
+	frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	canvas := image.Rect(0, 0, 30, 20)
+	area, _ := textRect(canvas, textSize("A"), "bottom")
+
+	drawn := drawText(frame, canvas, "A", area, color.White)
+
+	expected := image.Rect(0, 0, 4, 4).Union(area)
+	if drawn.Bounds() != expected {
+		t.Errorf("Expected %v but got %v", expected, drawn.Bounds())
+	}
+
+	// the new area is transparent apart from the text
+	if _, _, _, alpha := drawn.At(area.Min.X, area.Min.Y).RGBA(); alpha != 0 {
+		t.Errorf("Expected %v but got %v", 0, alpha)
+	}
+}
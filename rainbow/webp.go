@@ -0,0 +1,202 @@
+//go:build webp
+// +build webp
+
+package rainbow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"io/ioutil"
+
+	"github.com/HugoSmits86/nativewebp"
+)
+
+// WebPSupported is whether this build can encode and decode WebP
+const WebPSupported = true
+
+/* EncodeWebP writes img to w as an animated lossless WebP
+ * like EncodeAPNG every frame is composited onto the full canvas first
+ */
+func EncodeWebP(w io.Writer, img *gif.GIF) error {
+	if len(img.Image) == 0 {
+		return ErrNoFrames
+	}
+
+	frames := composite(img)
+	animation := nativewebp.Animation{
+		Images:    make([]image.Image, len(frames)),
+		Durations: make([]uint, len(frames)),
+		Disposals: make([]uint, len(frames)),
+		LoopCount: uint16(playCount(img.LoopCount)),
+	}
+
+	for i, frame := range frames {
+		animation.Images[i] = frame
+
+		// GIF delays are in 100ths of a second, WebP durations in milliseconds
+		if i < len(img.Delay) && img.Delay[i] > 0 {
+			animation.Durations[i] = uint(img.Delay[i]) * 10
+		}
+
+		// clear to the background so partially transparent frames don't pile up
+		animation.Disposals[i] = 1
+	}
+
+	return nativewebp.EncodeAll(w, &animation, nil)
+}
+
+/* decodes a WebP into the same structure a GIF decodes to
+ * animations are composited frame by frame onto the canvas and each composited frame is quantized,
+ * so they all cover the full canvas and get DisposalBackground like coalesced frames
+ * WebPs without an animation are decoded as stills
+ */
+func decodeWebP(r io.Reader, quantizer string) (*gif.GIF, bool, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// the animation flag lives in the VP8X chunk, simple WebPs don't have one
+	if len(data) < 30 || string(data[12:16]) != "VP8X" || data[20]&0x02 == 0 {
+		stillImg, err := nativewebp.DecodeIgnoreAlphaFlag(bytes.NewReader(data))
+		if err != nil {
+			return nil, true, err
+		}
+
+		img, err := staticImageTransform(stillImg, "webp", quantizer, 0)
+		return img, true, err
+	}
+
+	bounds := image.Rect(0, 0, webpUint24(data[24:27])+1, webpUint24(data[27:30])+1)
+	canvas := image.NewRGBA(bounds)
+	img := &gif.GIF{
+		Config: image.Config{Width: bounds.Dx(), Height: bounds.Dy()},
+	}
+
+	for offset := 12; offset+8 <= len(data); {
+		name := string(data[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		if offset+8+length > len(data) {
+			return nil, false, errors.New("WebP chunk is truncated")
+		}
+		chunk := data[offset+8 : offset+8+length]
+
+		switch name {
+		case "ANIM":
+			if len(chunk) < 6 {
+				return nil, false, errors.New("WebP ANIM chunk is truncated")
+			}
+			img.LoopCount = gifLoopCount(binary.LittleEndian.Uint16(chunk[4:6]))
+
+		case "ANMF":
+			if len(chunk) < 16 {
+				return nil, false, errors.New("WebP ANMF chunk is truncated")
+			}
+
+			x := webpUint24(chunk[0:3]) * 2
+			y := webpUint24(chunk[3:6]) * 2
+			width := webpUint24(chunk[6:9]) + 1
+			height := webpUint24(chunk[9:12]) + 1
+			duration := webpUint24(chunk[12:15])
+			flags := chunk[15]
+
+			frame, err := nativewebp.Decode(bytes.NewReader(webpFrameFile(chunk[16:], width, height)))
+			if err != nil {
+				return nil, false, err
+			}
+
+			// bit 1 asks for the frame to replace the canvas instead of being blended onto it
+			op := draw.Over
+			if flags&0x02 != 0 {
+				op = draw.Src
+			}
+			rect := image.Rect(x, y, x+width, y+height)
+			draw.Draw(canvas, rect, frame, frame.Bounds().Min, op)
+
+			paletted, err := palettize(canvas, quantizer)
+			if err != nil {
+				return nil, false, err
+			}
+
+			img.Image = append(img.Image, paletted)
+			// WebP durations are in milliseconds, GIF delays in 100ths of a second
+			img.Delay = append(img.Delay, (duration+5)/10)
+			img.Disposal = append(img.Disposal, gif.DisposalBackground)
+
+			// bit 0 disposes the frame's area to the background before the next frame
+			if flags&0x01 != 0 {
+				draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+
+		// chunks are padded to an even length
+		offset += 8 + length + length%2
+	}
+
+	if len(img.Image) == 0 {
+		return nil, false, ErrNoFrames
+	}
+
+	return img, false, nil
+}
+
+// reads a 24 bit little endian value
+func webpUint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// the inverse of playCount, WebP stores how often to play with 0 meaning forever
+func gifLoopCount(plays uint16) int {
+	switch plays {
+	case 0:
+		return 0
+	case 1:
+		return -1
+	default:
+		return int(plays) - 1
+	}
+}
+
+/* wraps the image chunks of an animation frame into a WebP file of their own so the still decoder can read them
+ * lossy frames with an ALPH chunk need a VP8X chunk announcing the alpha
+ */
+func webpFrameFile(frameData []byte, width int, height int) []byte {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+
+	var hasAlpha bool
+	for offset := 0; offset+8 <= len(frameData); {
+		length := int(binary.LittleEndian.Uint32(frameData[offset+4 : offset+8]))
+		if string(frameData[offset:offset+4]) == "ALPH" {
+			hasAlpha = true
+		}
+		offset += 8 + length + length%2
+	}
+
+	if hasAlpha {
+		vp8x := make([]byte, 18)
+		copy(vp8x, "VP8X")
+		binary.LittleEndian.PutUint32(vp8x[4:8], 10)
+		vp8x[8] = 0x10
+		putWebPUint24(vp8x[12:15], width-1)
+		putWebPUint24(vp8x[15:18], height-1)
+		body.Write(vp8x)
+	}
+	body.Write(frameData)
+
+	file := make([]byte, 8, 8+body.Len())
+	copy(file, "RIFF")
+	binary.LittleEndian.PutUint32(file[4:8], uint32(body.Len()))
+	return append(file, body.Bytes()...)
+}
+
+func putWebPUint24(b []byte, v int) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
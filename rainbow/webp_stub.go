@@ -0,0 +1,23 @@
+//go:build !webp
+// +build !webp
+
+package rainbow
+
+import (
+	"fmt"
+	"image/gif"
+	"io"
+)
+
+// WebPSupported is whether this build can encode and decode WebP
+const WebPSupported = false
+
+// EncodeWebP needs the WebP encoder, which is only included when building with -tags webp
+func EncodeWebP(w io.Writer, img *gif.GIF) error {
+	return fmt.Errorf("%w: WebP output needs a build with -tags webp", ErrUnsupportedFormat)
+}
+
+// decoding WebP needs the WebP decoder, which is only included when building with -tags webp
+func decodeWebP(r io.Reader, quantizer string) (*gif.GIF, bool, error) {
+	return nil, false, fmt.Errorf("%w: WebP input needs a build with -tags webp", ErrUnsupportedFormat)
+}
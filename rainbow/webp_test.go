@@ -0,0 +1,124 @@
+//go:build webp
+// +build webp
+
+package rainbow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// reads a 24 bit little endian value stored as width or height minus one
+func webpDimension(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16 + 1
+}
+
+func TestEncodeWebP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, newTestGIF(3, 4, 2)); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	b := buf.Bytes()
+	if string(b[0:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		t.Fatalf("Expected a WebP header but got %q", b[:12])
+	}
+
+	var frames int
+	var width int
+	var height int
+	for offset := 12; offset+8 <= len(b); {
+		name := string(b[offset : offset+4])
+		length := int(binary.LittleEndian.Uint32(b[offset+4 : offset+8]))
+		data := b[offset+8 : offset+8+length]
+
+		if name == "ANMF" {
+			// the first frame's size follows its x and y offsets
+			if frames == 0 {
+				width = webpDimension(data[6:9])
+				height = webpDimension(data[9:12])
+			}
+			frames++
+		}
+
+		// chunks are padded to an even length
+		offset += 8 + length + length%2
+	}
+
+	if frames != 3 {
+		t.Errorf("Expected %v but got %v", 3, frames)
+	}
+
+	if width != 4 || height != 2 {
+		t.Errorf("Expected %v but got %v", "4x2", []int{width, height})
+	}
+}
+
+func TestDecodeWebP(t *testing.T) {
+	src := newTestGIF(3, 4, 2)
+	src.LoopCount = 2
+
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, src); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	img, static, err := DecodeImage(&buf, "populosity")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if static {
+		t.Errorf("Expected an animation but got a still")
+	}
+
+	if len(img.Image) != 3 {
+		t.Fatalf("Expected %v but got %v", 3, len(img.Image))
+	}
+
+	composited := composite(src)
+	for i, frame := range img.Image {
+		if frame.Bounds() != image.Rect(0, 0, 4, 2) {
+			t.Errorf("Expected %v but got %v", image.Rect(0, 0, 4, 2), frame.Bounds())
+		}
+
+		if img.Delay[i] != 10 {
+			t.Errorf("Expected %v but got %v", 10, img.Delay[i])
+		}
+
+		// the lossless round trip keeps every pixel of the composited frames that were encoded
+		for j := range frame.Pix {
+			expected := color.RGBAModel.Convert(composited[i].At(j%4, j/4))
+			if actual := color.RGBAModel.Convert(frame.At(j%4, j/4)); actual != expected {
+				t.Errorf("Expected %v but got %v", expected, actual)
+			}
+		}
+	}
+
+	if img.LoopCount != 2 {
+		t.Errorf("Expected %v but got %v", 2, img.LoopCount)
+	}
+
+	out, err := Rainbowify(img, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	var encoded bytes.Buffer
+	if err := gif.EncodeAll(&encoded, out); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	decoded, err := gif.DecodeAll(&encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if len(decoded.Image) != 3 {
+		t.Errorf("Expected %v but got %v", 3, len(decoded.Image))
+	}
+}